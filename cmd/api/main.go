@@ -12,6 +12,7 @@ import (
 	"webPageAnalyzerGO/internal/api"
 	"webPageAnalyzerGO/internal/config"
 	"webPageAnalyzerGO/internal/repository"
+	"webPageAnalyzerGO/internal/retention"
 )
 
 func main() {
@@ -37,16 +38,26 @@ func main() {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
-	// Initialize MongoDB connection
-	mongoRepo, err := repository.NewMongoRepository(ctx, cfg.MongoDB)
+	// Initialize the storage backend
+	repo, err := repository.New(ctx, cfg)
 	if err != nil {
-		logger.Error("Failed to create MongoDB repository", "error", err)
+		logger.Error("Failed to create repository", "backend", cfg.Storage.Backend, "error", err)
 		os.Exit(1)
 	}
-	defer mongoRepo.Close(ctx)
+	defer repo.Close(ctx)
+
+	// Start the data retention purger
+	purger := retention.NewPurger(repo, cfg.Retention, logger)
+	if purger.Enabled() {
+		go purger.Run(ctx)
+	}
 
 	// Initialize and start the API server
-	server := api.NewServer(cfg, mongoRepo, logger)
+	server, err := api.NewServer(cfg, repo, logger)
+	if err != nil {
+		logger.Error("Failed to create server", "error", err)
+		os.Exit(1)
+	}
 	go func() {
 		if err := server.Start(); err != nil {
 			logger.Error("Server failed to start", "error", err)
@@ -3,12 +3,12 @@ package analyzer
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 	_ "golang.org/x/sync/errgroup"
@@ -20,39 +20,69 @@ import (
 // Analyzer handles URL analysis
 type Analyzer struct {
 	client *http.Client
-	config config.AnalyzerConfig
-	logger *slog.Logger
+	// deepClient accepts certificates that fail verification so that deep
+	// analyses can report them
+	deepClient   *http.Client
+	trackers     []*trackerSignature
+	technologies []*technologyRule
+	rulesVersion string
+	config       config.AnalyzerConfig
+	logger       *slog.Logger
 }
 
-// New creates a new Analyzer
-func New(cfg config.AnalyzerConfig, logger *slog.Logger) *Analyzer {
+// New creates a new Analyzer. It fails if the configured tracker signature
+// file cannot be loaded.
+func New(cfg config.AnalyzerConfig, logger *slog.Logger) (*Analyzer, error) {
+	trackers, digest, err := loadTrackerSignatures(cfg.TrackerSignatures)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracker signatures: %w", err)
+	}
+	// Results depend on the user's signatures as much as on the bundled rules
+	rulesVersion := RulesVersion
+	if digest != "" {
+		rulesVersion += "+" + digest[:12]
+	}
+	technologies, err := loadTechnologyRules()
+	if err != nil {
+		return nil, fmt.Errorf("invalid technology fingerprints: %w", err)
+	}
+
 	return &Analyzer{
 		client: &http.Client{
 			Timeout: cfg.RequestTimeout,
 		},
-		config: cfg,
-		logger: logger,
-	}
+		deepClient: &http.Client{
+			Timeout:   cfg.RequestTimeout,
+			Transport: newInspectingTransport(),
+		},
+		trackers:     trackers,
+		technologies: technologies,
+		rulesVersion: rulesVersion,
+		config:       cfg,
+		logger:       logger,
+	}, nil
+}
+
+// RulesVersion returns the version of the detection rules in use: the
+// bundled RulesVersion, followed by a digest of the user's tracker
+// signatures if any are loaded
+func (a *Analyzer) RulesVersion() string {
+	return a.rulesVersion
 }
 
 // AnalyzeURL analyzes a webpage and returns the analysis results
 func (a *Analyzer) AnalyzeURL(ctx context.Context, urlStr string) (*models.AnalysisResult, error) {
-	// Parse URL
-	parsedURL, err := url.Parse(urlStr)
+	// Parse URL, defaulting to https
+	parsedURL, err := parsePageURL(urlStr)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
-	}
-
-	// Ensure scheme is set
-	if parsedURL.Scheme == "" {
-		parsedURL.Scheme = "https"
-		urlStr = parsedURL.String()
+		return nil, err
 	}
+	urlStr = parsedURL.String()
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
 	}
 
 	// Set User-Agent
@@ -62,19 +92,19 @@ func (a *Analyzer) AnalyzeURL(ctx context.Context, urlStr string) (*models.Analy
 	a.logger.Info("Sending request", "url", urlStr)
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, classifyFetchError(err)
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	// Only successful HTML responses are analyzed
+	if err := checkResponse(resp, a.config.MaxPageSize); err != nil {
+		return nil, err
 	}
 
 	// Read body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, a.config.MaxPageSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	// Parse HTML
@@ -106,6 +136,9 @@ func (a *Analyzer) analyzeDocument(ctx context.Context, n *html.Node, baseURL *u
 	internalInaccessible := 0
 	externalInaccessible := 0
 
+	// Collect anchor text for search indexing
+	var anchorText []string
+
 	// Use WaitGroup to wait for all link checks
 	var wg sync.WaitGroup
 
@@ -134,7 +167,24 @@ func (a *Analyzer) analyzeDocument(ctx context.Context, n *html.Node, baseURL *u
 				case "h6":
 					analysis.Headings.H6++
 				}
+			case "meta":
+				var name, content string
+				for _, attr := range n.Attr {
+					switch attr.Key {
+					case "name":
+						name = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+				if name == "description" {
+					analysis.Description = strings.TrimSpace(content)
+				}
 			case "a":
+				if text := extractVisibleText(n, maxIndexedTextLength); text != "" {
+					anchorText = append(anchorText, text)
+				}
+
 				// Process links
 				for _, attr := range n.Attr {
 					if attr.Key == "href" {
@@ -201,6 +251,10 @@ func (a *Analyzer) analyzeDocument(ctx context.Context, n *html.Node, baseURL *u
 	// Wait for all link checks to complete
 	wg.Wait()
 
+	// Store searchable text
+	analysis.AnchorText = truncateText(strings.Join(anchorText, " "), maxIndexedTextLength)
+	analysis.TextContent = extractVisibleText(n, maxIndexedTextLength)
+
 	// Set link counts in the analysis result
 	analysis.InternalLinks = models.LinkStatus{
 		Count:        len(internalLinks),
@@ -212,6 +266,51 @@ func (a *Analyzer) analyzeDocument(ctx context.Context, n *html.Node, baseURL *u
 	}
 }
 
+// maxIndexedTextLength caps the amount of page text stored for search
+const maxIndexedTextLength = 64 * 1024
+
+// extractVisibleText returns the whitespace-normalised text of a node,
+// skipping scripts, styles and other non-rendered elements
+func extractVisibleText(n *html.Node, limit int) string {
+	var b strings.Builder
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if b.Len() >= limit {
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript", "template", "head":
+				return
+			}
+		}
+		if n.Type == html.TextNode {
+			for _, word := range strings.Fields(n.Data) {
+				if b.Len() > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(word)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+	extract(n)
+	return truncateText(b.String(), limit)
+}
+
+// truncateText cuts text to at most limit bytes without splitting a UTF-8 sequence
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
 // detectHTMLVersion determines the HTML version from the document
 func (a *Analyzer) detectHTMLVersion(n *html.Node) string {
 	// Look for doctype declaration
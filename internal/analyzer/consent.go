@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// tcfLocator names the frame TCF stubs add so that nested frames can find
+// the consent API
+const tcfLocator = "__tcfapiLocator"
+
+// hasTCFStub reports whether a page defines the IAB Transparency and Consent
+// Framework __tcfapi function or its locator frame
+func hasTCFStub(doc *html.Node) bool {
+	found := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if found {
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "script" && jsTypes[strings.ToLower(strings.TrimSpace(attrValue(n, "type")))]:
+				found = strings.Contains(nodeText(n), "__tcfapi")
+			case attrValue(n, "name") == tcfLocator, attrValue(n, "id") == tcfLocator:
+				found = true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return found
+}
+
+// checkConsent reports the consent management platforms of a page and flags
+// trackers that load, run or set cookies before visitors interact with them.
+// Without JavaScript, everything the initial response and markup load counts
+// as happening before consent.
+func checkConsent(platforms []models.TrackerMatch, tcf bool, trackers []models.TrackerMatch, exempt map[string]bool) models.ConsentAnalysis {
+	consent := models.ConsentAnalysis{
+		Platforms:             []models.TrackerMatch{},
+		TCF:                   tcf,
+		TrackersBeforeConsent: []string{},
+		CookiesBeforeConsent:  []string{},
+		Findings:              []models.SecurityCheck{},
+	}
+	consent.Platforms = append(consent.Platforms, platforms...)
+	managed := len(platforms) > 0 || tcf
+
+	for _, tracker := range trackers {
+		if exempt[tracker.Name] {
+			continue
+		}
+		i := slices.IndexFunc(tracker.Evidence, func(e models.TrackerEvidence) bool { return e.BeforeConsent })
+		if i < 0 {
+			continue
+		}
+
+		consent.TrackersBeforeConsent = append(consent.TrackersBeforeConsent, tracker.Name)
+		explanation := tracker.Name + " loads before any consent interaction; a potential GDPR/ePrivacy violation"
+		if !managed {
+			explanation = tracker.Name + " loads and the page has no consent management platform; a potential GDPR/ePrivacy violation"
+		}
+		consent.Findings = append(consent.Findings, newCheck(tracker.Name, tracker.Evidence[i].Value, models.VerdictFail, explanation))
+
+		for _, evidence := range tracker.Evidence {
+			if evidence.Type != models.EvidenceCookie || !evidence.BeforeConsent ||
+				slices.Contains(consent.CookiesBeforeConsent, evidence.Value) {
+				continue
+			}
+			consent.CookiesBeforeConsent = append(consent.CookiesBeforeConsent, evidence.Value)
+			consent.Findings = append(consent.Findings, newCheck(tracker.Name, evidence.Value, models.VerdictFail,
+				"The "+evidence.Value+" cookie is set before any consent interaction; a potential GDPR/ePrivacy violation"))
+		}
+	}
+
+	return consent
+}
@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Browser limits on cookies
+const (
+	maxCookieSize     = 4096
+	maxCookieLifetime = 400 * 24 * time.Hour
+)
+
+// sessionCookiePattern matches names of cookies that likely hold a session
+// or credentials
+var sessionCookiePattern = regexp.MustCompile(`(?i)(sess|sid$|^sid|auth|token|jwt|login|remember|csrf|xsrf)`)
+
+// sameSiteNames maps SameSite modes to their attribute values. Missing or
+// unrecognized values leave the browser default.
+var sameSiteNames = map[http.SameSite]string{
+	http.SameSiteLaxMode:    "Lax",
+	http.SameSiteStrictMode: "Strict",
+	http.SameSiteNoneMode:   "None",
+}
+
+// auditCookies describes each cookie the page response sets and flags
+// risky or invalid attributes. Cookies are third-party when their Domain
+// belongs to another registrable domain than the page.
+func auditCookies(cookies []*http.Cookie, pageURL *url.URL, now time.Time) []models.CookieRecord {
+	records := make([]models.CookieRecord, 0, len(cookies))
+	host := strings.ToLower(pageURL.Hostname())
+	https := pageURL.Scheme == "https"
+
+	for _, cookie := range cookies {
+		domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		record := models.CookieRecord{
+			Name:        cookie.Name,
+			Domain:      domain,
+			Path:        cookie.Path,
+			Secure:      cookie.Secure,
+			HttpOnly:    cookie.HttpOnly,
+			SameSite:    sameSiteNames[cookie.SameSite],
+			Partitioned: cookie.Partitioned,
+			Session:     true,
+			Size:        len(cookie.Name) + len(cookie.Value),
+			ThirdParty:  domain != "" && registrableDomain(domain) != registrableDomain(host),
+			Findings:    []models.SecurityCheck{},
+		}
+
+		// Max-Age takes precedence over Expires
+		switch {
+		case cookie.MaxAge > 0:
+			expires := now.Add(time.Duration(cookie.MaxAge) * time.Second)
+			record.Expires, record.Session = &expires, false
+		case cookie.MaxAge < 0:
+			record.Expires, record.Session = &now, false
+		case !cookie.Expires.IsZero():
+			expires := cookie.Expires
+			record.Expires, record.Session = &expires, false
+		}
+
+		switch {
+		case strings.HasPrefix(cookie.Name, "__Host-"):
+			record.Prefix = "__Host-"
+		case strings.HasPrefix(cookie.Name, "__Secure-"):
+			record.Prefix = "__Secure-"
+		}
+
+		record.PrefixValid = validCookiePrefix(record, https)
+		record.Findings = cookieFindings(record, host, https, now)
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// cookieFindings flags the risky or invalid attributes of a cookie
+func cookieFindings(record models.CookieRecord, host string, https bool, now time.Time) []models.SecurityCheck {
+	findings := []models.SecurityCheck{}
+	flag := func(attribute, verdict, explanation string) {
+		findings = append(findings, newCheck(attribute, "", verdict, explanation))
+	}
+
+	session := sessionCookiePattern.MatchString(record.Name)
+	if session && !record.HttpOnly {
+		flag("HttpOnly", models.VerdictFail, "Session-looking cookie without HttpOnly; scripts can read and steal it")
+	}
+	switch {
+	case session && https && !record.Secure:
+		flag("Secure", models.VerdictFail, "Session-looking cookie without Secure; it is also sent over plain HTTP")
+	case https && !record.Secure:
+		flag("Secure", models.VerdictWarn, "Cookie without Secure on an HTTPS page; it is also sent over plain HTTP")
+	}
+	if session && record.SameSite == "" {
+		flag("SameSite", models.VerdictWarn, "Session-looking cookie without SameSite; set Lax or Strict explicitly")
+	}
+	if record.SameSite == "None" && !record.Secure {
+		flag("SameSite", models.VerdictFail, "SameSite=None without Secure; browsers reject the cookie")
+	}
+	if record.Partitioned && !record.Secure {
+		flag("Partitioned", models.VerdictFail, "Partitioned without Secure; browsers reject the cookie")
+	}
+
+	if !record.PrefixValid {
+		flag("Prefix", models.VerdictFail, record.Prefix+" cookies must be Secure and set over HTTPS, "+
+			"__Host- ones also without Domain and with Path=/; browsers reject the cookie")
+	}
+
+	if record.Domain != "" {
+		if suffix, _ := publicsuffix.PublicSuffix(record.Domain); suffix == record.Domain {
+			flag("Domain", models.VerdictFail, "Domain is a public suffix; browsers reject the cookie")
+		} else if host != record.Domain && !strings.HasSuffix(host, "."+record.Domain) {
+			flag("Domain", models.VerdictFail, "Domain does not match the page host; browsers reject the cookie")
+		}
+	}
+
+	if record.Size > maxCookieSize {
+		flag("Size", models.VerdictFail, "Name and value exceed 4096 bytes; browsers drop the cookie")
+	}
+	if record.Expires != nil && record.Expires.Sub(now) > maxCookieLifetime {
+		flag("Expires", models.VerdictWarn, "Lifetime exceeds 400 days; browsers cap it")
+	}
+
+	return findings
+}
+
+// validCookiePrefix reports whether a cookie meets the requirements of its
+// __Secure- or __Host- name prefix, if any
+func validCookiePrefix(record models.CookieRecord, https bool) bool {
+	switch record.Prefix {
+	case "__Secure-":
+		return record.Secure && https
+	case "__Host-":
+		return record.Secure && https && record.Domain == "" && record.Path == "/"
+	}
+	return true
+}
+
+// registrableDomain returns the registrable domain (eTLD+1) of a host, or
+// the host itself for IP addresses and hosts directly under a public suffix
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
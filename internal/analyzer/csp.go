@@ -0,0 +1,471 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// CSP policy delivery
+const (
+	cspSourceHeader = "header"
+	cspSourceMeta   = "meta"
+)
+
+// cspMetaIgnored lists directives browsers ignore in a <meta> policy
+var cspMetaIgnored = []string{"frame-ancestors", "report-uri", "sandbox"}
+
+// cspScriptDirectives lists the directives governing script elements, most
+// specific first
+var cspScriptDirectives = []string{"script-src-elem", "script-src", "default-src"}
+
+// jsTypes lists the script type attributes browsers execute as JavaScript
+var jsTypes = map[string]bool{
+	"": true, "module": true, "text/javascript": true, "application/javascript": true,
+	"text/ecmascript": true, "application/ecmascript": true, "application/x-javascript": true,
+	"text/x-javascript": true, "text/jscript": true, "text/livescript": true,
+}
+
+// cspHashes maps the hash source prefixes to their algorithms
+var cspHashes = map[string]func() hash.Hash{
+	"sha256-": sha256.New,
+	"sha384-": sha512.New384,
+	"sha512-": sha512.New,
+}
+
+// pageScript is a script element of the analyzed page
+type pageScript struct {
+	src     *url.URL // nil for inline scripts
+	nonce   string
+	content string
+}
+
+// analyzeCSP parses the content security policies delivered in the response
+// headers and <meta> tags of a page, flags their weaknesses and checks
+// whether each script of the page would be allowed by them
+func analyzeCSP(header http.Header, doc *html.Node, pageURL *url.URL) models.CSPAnalysis {
+	analysis := models.CSPAnalysis{
+		Policies: []models.CSPPolicy{},
+		Scripts:  []models.CSPScript{},
+	}
+
+	// A header may carry several comma-separated policies, all of which apply
+	for _, name := range []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+		for _, value := range header.Values(name) {
+			for _, raw := range strings.Split(value, ",") {
+				if strings.TrimSpace(raw) != "" {
+					analysis.Policies = append(analysis.Policies,
+						parseCSP(raw, cspSourceHeader, name != "Content-Security-Policy"))
+				}
+			}
+		}
+	}
+	for _, raw := range metaPolicies(doc) {
+		analysis.Policies = append(analysis.Policies, parseCSP(raw, cspSourceMeta, false))
+	}
+
+	for i := range analysis.Policies {
+		analysis.Policies[i].Weaknesses = evaluateCSP(analysis.Policies[i])
+	}
+
+	for _, script := range collectScripts(doc, pageURL) {
+		result := models.CSPScript{Inline: script.src == nil, Allowed: true}
+		if script.src != nil {
+			result.URL = script.src.String()
+		}
+		for _, policy := range analysis.Policies {
+			if ok, reason := allowsScript(policy, script, pageURL); !ok {
+				result.Allowed = false
+				result.Reason = reason
+				if policy.ReportOnly {
+					result.Reason += " (report-only policy)"
+				}
+				break
+			}
+		}
+		analysis.Scripts = append(analysis.Scripts, result)
+	}
+
+	return analysis
+}
+
+// parseCSP parses a serialized policy into its directives. Directive names
+// are case-insensitive and only the first occurrence of a directive counts.
+func parseCSP(raw, source string, reportOnly bool) models.CSPPolicy {
+	policy := models.CSPPolicy{
+		Source:     source,
+		ReportOnly: reportOnly,
+		Raw:        strings.TrimSpace(raw),
+		Directives: map[string][]string{},
+	}
+
+	for _, directive := range strings.Split(raw, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, seen := policy.Directives[name]; seen {
+			continue
+		}
+		policy.Directives[name] = fields[1:]
+	}
+
+	return policy
+}
+
+// evaluateCSP flags the weaknesses of a policy
+func evaluateCSP(policy models.CSPPolicy) []models.SecurityCheck {
+	weaknesses := []models.SecurityCheck{}
+	flag := func(directive, value, verdict, explanation string) {
+		weaknesses = append(weaknesses, newCheck(directive, value, verdict, explanation))
+	}
+
+	if policy.ReportOnly {
+		flag("policy", "", models.VerdictWarn, "Report-only; violations are reported but nothing is blocked")
+	}
+
+	// Scripts
+	directive, sources, ok := scriptSources(policy)
+	if !ok {
+		flag("script-src", "", models.VerdictFail, "Neither script-src nor default-src is set, so any script may run")
+	} else {
+		list := parseSourceList(sources)
+		if list.unsafeInline && !list.nonceOrHash && !list.strictDynamic {
+			flag(directive, "'unsafe-inline'", models.VerdictFail,
+				"Inline scripts are allowed without a nonce or hash, so injected scripts run")
+		}
+		if list.unsafeEval {
+			flag(directive, "'unsafe-eval'", models.VerdictWarn, "eval() and similar can turn injected strings into code")
+		}
+		// 'strict-dynamic' makes browsers ignore host and scheme sources
+		if !list.strictDynamic {
+			for _, source := range sources {
+				switch strings.ToLower(source) {
+				case "*", "http:", "https:", "data:", "blob:":
+					flag(directive, source, models.VerdictFail, "Scripts may be loaded from any host")
+				}
+			}
+		}
+	}
+
+	// Plugins should be disabled entirely
+	objectSources, ok := policy.Directives["object-src"]
+	if !ok {
+		objectSources, ok = policy.Directives["default-src"]
+	}
+	if !ok {
+		flag("object-src", "", models.VerdictWarn, "Missing; plugins may load from anywhere, set object-src 'none'")
+	} else if len(objectSources) != 1 || !strings.EqualFold(objectSources[0], "'none'") {
+		flag("object-src", strings.Join(objectSources, " "), models.VerdictWarn,
+			"Plugins are allowed; set object-src 'none'")
+	}
+
+	// default-src does not cover base-uri
+	if _, ok := policy.Directives["base-uri"]; !ok {
+		flag("base-uri", "", models.VerdictWarn,
+			"Missing; injected <base> tags can redirect relative script URLs, set base-uri 'none' or 'self'")
+	}
+
+	// Plain HTTP sources can be tampered with in transit
+	for _, name := range slices.Sorted(maps.Keys(policy.Directives)) {
+		if name == "report-uri" || name == "report-to" {
+			continue
+		}
+		for _, source := range policy.Directives[name] {
+			lower := strings.ToLower(source)
+			if lower == "http:" || strings.HasPrefix(lower, "http://") {
+				flag(name, source, models.VerdictWarn, "Content may be loaded over plain HTTP")
+			}
+		}
+	}
+
+	if policy.Source == cspSourceMeta {
+		for _, name := range cspMetaIgnored {
+			if _, ok := policy.Directives[name]; ok {
+				flag(name, "", models.VerdictWarn, "Ignored in a <meta> policy; send it as a header")
+			}
+		}
+	}
+
+	return weaknesses
+}
+
+// scriptSources returns the directive governing script elements and its
+// source list, or false if the policy does not restrict scripts
+func scriptSources(policy models.CSPPolicy) (string, []string, bool) {
+	for _, name := range cspScriptDirectives {
+		if sources, ok := policy.Directives[name]; ok {
+			return name, sources, true
+		}
+	}
+	return "", nil, false
+}
+
+// sourceList summarizes the keywords of a source list
+type sourceList struct {
+	none          bool
+	unsafeInline  bool
+	unsafeEval    bool
+	strictDynamic bool
+	nonceOrHash   bool
+	nonces        []string
+	hashes        []string
+}
+
+// parseSourceList reads the keywords, nonces and hashes of a source list
+func parseSourceList(sources []string) sourceList {
+	var list sourceList
+	for _, source := range sources {
+		lower := strings.ToLower(source)
+		switch {
+		case lower == "'none'":
+			list.none = len(sources) == 1
+		case lower == "'unsafe-inline'":
+			list.unsafeInline = true
+		case lower == "'unsafe-eval'":
+			list.unsafeEval = true
+		case lower == "'strict-dynamic'":
+			list.strictDynamic = true
+		case strings.HasPrefix(lower, "'nonce-") && strings.HasSuffix(lower, "'"):
+			list.nonceOrHash = true
+			list.nonces = append(list.nonces, source[len("'nonce-"):len(source)-1])
+		case strings.HasPrefix(lower, "'sha") && strings.HasSuffix(lower, "'"):
+			list.nonceOrHash = true
+			list.hashes = append(list.hashes, source[1:len(source)-1])
+		}
+	}
+	return list
+}
+
+// allowsScript reports whether a policy allows a script element to run,
+// and if not, why
+func allowsScript(policy models.CSPPolicy, script pageScript, pageURL *url.URL) (bool, string) {
+	directive, sources, ok := scriptSources(policy)
+	if !ok {
+		return true, ""
+	}
+	list := parseSourceList(sources)
+	if list.none {
+		return false, directive + " is 'none'"
+	}
+
+	if script.nonce != "" {
+		for _, nonce := range list.nonces {
+			if nonce == script.nonce {
+				return true, ""
+			}
+		}
+	}
+
+	if script.src == nil {
+		for _, h := range list.hashes {
+			if matchesHash(h, script.content) {
+				return true, ""
+			}
+		}
+		if list.unsafeInline && !list.nonceOrHash && !list.strictDynamic {
+			return true, ""
+		}
+		return false, "Inline script without a matching nonce or hash in " + directive
+	}
+
+	if list.strictDynamic {
+		return false, "'strict-dynamic' in " + directive + " only trusts scripts with a matching nonce"
+	}
+	for _, source := range sources {
+		if matchesSource(source, script.src, pageURL) {
+			return true, ""
+		}
+	}
+	return false, script.src.Host + " is not an allowed source in " + directive
+}
+
+// matchesHash reports whether a hash source such as sha256-<base64>
+// matches the content of an inline script
+func matchesHash(source, content string) bool {
+	for prefix, newHash := range cspHashes {
+		if strings.HasPrefix(strings.ToLower(source), prefix) {
+			h := newHash()
+			h.Write([]byte(content))
+			return base64.StdEncoding.EncodeToString(h.Sum(nil)) == source[len(prefix):]
+		}
+	}
+	return false
+}
+
+// matchesSource reports whether a scheme, host or keyword source expression
+// matches the URL of a resource loaded by the page
+func matchesSource(source string, u, pageURL *url.URL) bool {
+	lower := strings.ToLower(source)
+	switch {
+	case lower == "*":
+		// Wildcards match network schemes and the page's own scheme
+		switch u.Scheme {
+		case "http", "https", "ws", "wss", pageURL.Scheme:
+			return true
+		}
+		return false
+	case lower == "'self'":
+		if !strings.EqualFold(u.Hostname(), pageURL.Hostname()) || !schemeMatches(pageURL.Scheme, u.Scheme) {
+			return false
+		}
+		// Secure upgrades of the page's origin may use the default port
+		return effectivePort(u) == effectivePort(pageURL) || u.Scheme != pageURL.Scheme && u.Port() == ""
+	case strings.HasPrefix(lower, "'"):
+		// Other keywords, nonces and hashes never match a URL
+		return false
+	case strings.HasSuffix(lower, ":") && !strings.Contains(lower, "/"):
+		return schemeMatches(strings.TrimSuffix(lower, ":"), u.Scheme)
+	}
+
+	// Host source: [scheme://]host[:port][/path]
+	scheme, rest, hasScheme := strings.Cut(lower, "://")
+	if !hasScheme {
+		scheme, rest = pageURL.Scheme, lower
+	}
+	if !schemeMatches(scheme, u.Scheme) {
+		return false
+	}
+
+	hostPort, path := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		hostPort, path = rest[:i], rest[i:]
+	}
+	host, port, hasPort := strings.Cut(hostPort, ":")
+
+	hostname := strings.ToLower(u.Hostname())
+	switch {
+	case host == "*":
+	case strings.HasPrefix(host, "*."):
+		if !strings.HasSuffix(hostname, host[1:]) {
+			return false
+		}
+	case hostname != host:
+		return false
+	}
+
+	switch {
+	case hasPort && port == "*":
+	case hasPort:
+		if effectivePort(u) != port {
+			return false
+		}
+	case u.Port() != "" && u.Port() != defaultPort(u.Scheme):
+		return false
+	}
+
+	if path != "" {
+		if strings.HasSuffix(path, "/") {
+			return strings.HasPrefix(u.Path, path)
+		}
+		return u.Path == path
+	}
+	return true
+}
+
+// schemeMatches reports whether a source scheme allows a resource scheme;
+// sources for insecure schemes also allow their secure upgrades
+func schemeMatches(source, scheme string) bool {
+	return source == scheme ||
+		source == "http" && scheme == "https" ||
+		source == "ws" && scheme == "wss"
+}
+
+// effectivePort returns the port of a URL, defaulting to that of its scheme
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	return defaultPort(u.Scheme)
+}
+
+// defaultPort returns the default port of a scheme
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	}
+	return ""
+}
+
+// metaPolicies returns the policies delivered in <meta http-equiv> tags.
+// Browsers ignore report-only policies in <meta> tags.
+func metaPolicies(n *html.Node) []string {
+	var policies []string
+	if n.Type == html.ElementNode && n.Data == "meta" {
+		var httpEquiv, content string
+		for _, attr := range n.Attr {
+			switch attr.Key {
+			case "http-equiv":
+				httpEquiv = attr.Val
+			case "content":
+				content = attr.Val
+			}
+		}
+		if strings.EqualFold(strings.TrimSpace(httpEquiv), "Content-Security-Policy") && strings.TrimSpace(content) != "" {
+			policies = append(policies, content)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		policies = append(policies, metaPolicies(c)...)
+	}
+	return policies
+}
+
+// collectScripts returns the JavaScript script elements of a document, with
+// external script URLs resolved against the document base
+func collectScripts(doc *html.Node, pageURL *url.URL) []pageScript {
+	base := documentBase(doc, pageURL)
+	var scripts []pageScript
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "script" {
+			var script pageScript
+			var src, scriptType string
+			hasSrc := false
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "src":
+					src, hasSrc = attr.Val, true
+				case "type":
+					scriptType = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "nonce":
+					script.nonce = attr.Val
+				}
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.TextNode {
+					script.content += c.Data
+				}
+			}
+
+			if jsTypes[scriptType] {
+				if hasSrc {
+					if u, err := base.Parse(strings.TrimSpace(src)); err == nil {
+						script.src = u
+						scripts = append(scripts, script)
+					}
+				} else if strings.TrimSpace(script.content) != "" {
+					scripts = append(scripts, script)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return scripts
+}
@@ -3,17 +3,27 @@ package analyzer
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"golang.org/x/net/html"
 	_ "log/slog"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Version identifies the analyzer build that produced a deep analysis, and
+// RulesVersion the bundled detection rules it used; Analyzer.RulesVersion
+// adds the user's tracker signatures. Bump them when results change so
+// stored deep analysis versions can be told apart.
+const (
+	Version      = "1.10.0"
+	RulesVersion = "11"
 )
 
 // PageData contains detailed information about a webpage for deep analysis
@@ -34,6 +44,7 @@ type PageData struct {
 	Schema        SchemaData
 	Cookies       CookiesData
 	Links         LinksData
+	Privacy       models.PrivacyAnalysis
 }
 
 // ResourceData represents a resource on a webpage
@@ -77,6 +88,14 @@ type ContentData struct {
 type SecurityData struct {
 	CSPHeaders    bool
 	XSSProtection bool
+	Headers       []models.SecurityCheck
+	CSP           models.CSPAnalysis
+	TLS           models.TLSAnalysis
+	MixedContent  models.MixedContentAnalysis
+	Forms         models.FormAnalysis
+	Dependencies  models.DependencyAnalysis
+	Score         int
+	Grade         string
 }
 
 // MobileData contains mobile-friendliness information
@@ -95,10 +114,11 @@ type SocialData struct {
 
 // TechnologyData contains technology stack information
 type TechnologyData struct {
-	Server      string
-	CMS         string
-	Frameworks  []string
-	Advertising []string
+	Server       string
+	CMS          string
+	Frameworks   []string
+	Advertising  []string
+	Technologies []models.Technology
 }
 
 // MediaData contains media usage information
@@ -123,6 +143,7 @@ type CookiesData struct {
 	ThirdParty int
 	HasConsent bool
 	MaxAgeDays int
+	Records    []models.CookieRecord
 }
 
 // LinksData contains link analysis information
@@ -137,22 +158,17 @@ type LinksData struct {
 func (a *Analyzer) FetchPage(ctx context.Context, urlStr string) (*PageData, error) {
 	startTime := time.Now()
 
-	// Parse URL
-	parsedURL, err := url.Parse(urlStr)
+	// Parse URL, defaulting to https
+	parsedURL, err := parsePageURL(urlStr)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
-	}
-
-	// Ensure scheme is set
-	if parsedURL.Scheme == "" {
-		parsedURL.Scheme = "https"
-		urlStr = parsedURL.String()
+		return nil, err
 	}
+	urlStr = parsedURL.String()
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
 	}
 
 	// Set User-Agent
@@ -170,24 +186,24 @@ func (a *Analyzer) FetchPage(ctx context.Context, urlStr string) (*PageData, err
 	}
 	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
 
-	resp, err := a.client.Do(req)
+	resp, err := a.deepClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, classifyFetchError(err)
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	// Only successful HTML responses are analyzed
+	if err := checkResponse(resp, a.config.MaxPageSize); err != nil {
+		return nil, err
 	}
 
 	// Calculate load time
 	loadTime := time.Since(startTime)
 
 	// Read body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, a.config.MaxPageSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	// Get page size
@@ -209,8 +225,9 @@ func (a *Analyzer) FetchPage(ctx context.Context, urlStr string) (*PageData, err
 			KeywordDensity: make(map[string]float64),
 		},
 		Technology: TechnologyData{
-			Frameworks:  []string{},
-			Advertising: []string{},
+			Frameworks:   []string{},
+			Advertising:  []string{},
+			Technologies: []models.Technology{},
 		},
 		Schema: SchemaData{
 			SchemaTypes: []string{},
@@ -225,21 +242,21 @@ func (a *Analyzer) FetchPage(ctx context.Context, urlStr string) (*PageData, err
 	pageData.Security.CSPHeaders = resp.Header.Get("Content-Security-Policy") != ""
 	pageData.Security.XSSProtection = resp.Header.Get("X-XSS-Protection") != ""
 
-	// Extract cookies
-	cookies := resp.Cookies()
-	pageData.Cookies.TotalCount = len(cookies)
+	// Audit the cookies the page sets
+	now := time.Now()
+	pageData.Cookies.Records = auditCookies(resp.Cookies(), resp.Request.URL, now)
+	pageData.Cookies.TotalCount = len(pageData.Cookies.Records)
 
-	// Process cookies
-	for _, cookie := range cookies {
-		if strings.Contains(cookie.Domain, parsedURL.Host) || cookie.Domain == "" {
-			pageData.Cookies.FirstParty++
-		} else {
+	for _, cookie := range pageData.Cookies.Records {
+		if cookie.ThirdParty {
 			pageData.Cookies.ThirdParty++
+		} else {
+			pageData.Cookies.FirstParty++
 		}
 
-		// Check cookie max age
-		if cookie.MaxAge > 0 {
-			maxAgeDays := cookie.MaxAge / (60 * 60 * 24)
+		// Track the longest cookie lifetime
+		if cookie.Expires != nil {
+			maxAgeDays := int(cookie.Expires.Sub(now).Hours() / 24)
 			if maxAgeDays > pageData.Cookies.MaxAgeDays {
 				pageData.Cookies.MaxAgeDays = maxAgeDays
 			}
@@ -249,6 +266,47 @@ func (a *Analyzer) FetchPage(ctx context.Context, urlStr string) (*PageData, err
 	// Process the document
 	a.processDocument(doc, parsedURL, pageData)
 
+	// Fingerprint the technologies the page is built with
+	pageData.Technology.Technologies = a.fingerprint(resp.Header, resp.Cookies(), body, doc, resp.Request.URL)
+	pageData.Technology.CMS = "Unknown"
+	for _, tech := range pageData.Technology.Technologies {
+		if pageData.Technology.CMS == "Unknown" && slices.Contains(tech.Categories, categoryCMS) {
+			pageData.Technology.CMS = tech.Name
+		}
+		if slices.ContainsFunc(tech.Categories, func(c string) bool { return slices.Contains(frameworkCategories, c) }) {
+			pageData.Technology.Frameworks = append(pageData.Technology.Frameworks, tech.Name)
+		}
+	}
+
+	// Match trackers, analytics vendors and consent managers against the
+	// signature database
+	pageData.Privacy = a.detectTrackers(doc, resp.Request.URL, pageData.Cookies.Records)
+	pageData.Cookies.HasConsent = len(pageData.Privacy.Consent.Platforms) > 0 || pageData.Privacy.Consent.TCF
+	for _, tracker := range pageData.Privacy.Trackers {
+		if tracker.Category == models.TrackerAdvertising {
+			pageData.Technology.Advertising = append(pageData.Technology.Advertising, tracker.Name)
+		}
+	}
+
+	// Evaluate the content security policies and audit the security headers
+	pageData.Security.CSP = analyzeCSP(resp.Header, doc, resp.Request.URL)
+	pageData.Security.Headers = auditSecurityHeaders(resp, doc, pageData.Security.CSP)
+
+	// Inspect the TLS connection and certificate chain
+	pageData.Security.TLS = analyzeTLS(resp.TLS, resp.Request.URL.Hostname(), now)
+
+	// Find subresources loaded over plain HTTP
+	pageData.Security.MixedContent = detectMixedContent(doc, resp.Request.URL)
+
+	// Audit how forms submit what users enter
+	pageData.Security.Forms = a.auditForms(doc, resp.Request.URL)
+
+	// Inventory the scripts and stylesheets the page depends on
+	pageData.Security.Dependencies = a.inventoryDependencies(ctx, doc, resp.Request.URL)
+
+	pageData.Security.Score, pageData.Security.Grade = gradeSecurity(slices.Concat(
+		pageData.Security.Headers, pageData.Security.TLS.Checks, pageData.Security.MixedContent.Checks))
+
 	// Perform readability analysis
 	pageData.Content.ReadabilityScore = calculateReadabilityScore(string(body))
 
@@ -359,13 +417,10 @@ func (a *Analyzer) processDocument(n *html.Node, baseURL *url.URL, data *PageDat
 				audioCount++
 
 			case "script":
-				var type_, src, innerHTML string
+				var type_, innerHTML string
 				for _, attr := range n.Attr {
-					switch attr.Key {
-					case "type":
+					if attr.Key == "type" {
 						type_ = attr.Val
-					case "src":
-						src = attr.Val
 					}
 				}
 
@@ -392,27 +447,6 @@ func (a *Analyzer) processDocument(n *html.Node, baseURL *url.URL, data *PageDat
 					}
 				}
 
-				// Detect frameworks and libraries
-				for _, framework := range []string{"react", "angular", "vue", "jquery", "bootstrap"} {
-					if strings.Contains(src, framework) || strings.Contains(innerHTML, framework) {
-						data.Technology.Frameworks = append(data.Technology.Frameworks, framework)
-					}
-				}
-
-				// Check for advertising
-				for _, adNetwork := range []string{"adsense", "doubleclick", "adroll", "taboola", "outbrain"} {
-					if strings.Contains(src, adNetwork) || strings.Contains(innerHTML, adNetwork) {
-						data.Technology.Advertising = append(data.Technology.Advertising, adNetwork)
-					}
-				}
-
-				// Check for cookie consent
-				if strings.Contains(innerHTML, "cookie") &&
-					(strings.Contains(innerHTML, "consent") || strings.Contains(innerHTML, "gdpr")) {
-					_ = true
-					data.Cookies.HasConsent = true
-				}
-
 			case "a":
 				var href, rel, text string
 				for _, attr := range n.Attr {
@@ -502,17 +536,6 @@ func (a *Analyzer) processDocument(n *html.Node, baseURL *url.URL, data *PageDat
 
 	// Set keyboard navigation support (placeholder for a more accurate check)
 	data.Accessibility.KeyboardNavigation = ariaCount > 0
-
-	// Detect CMS (very basic detection)
-	if strings.Contains(strings.ToLower(fmt.Sprintf("%s", n.Data)), "wordpress") {
-		data.Technology.CMS = "WordPress"
-	} else if strings.Contains(strings.ToLower(fmt.Sprintf("%s", n.Data)), "joomla") {
-		data.Technology.CMS = "Joomla"
-	} else if strings.Contains(strings.ToLower(fmt.Sprintf("%s", n.Data)), "drupal") {
-		data.Technology.CMS = "Drupal"
-	} else {
-		data.Technology.CMS = "Unknown"
-	}
 }
 
 // Helper functions
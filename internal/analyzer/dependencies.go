@@ -0,0 +1,234 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/sync/errgroup"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// maxIntegrityChecks bounds how many dependencies a deep analysis downloads
+// to verify their integrity hashes
+const maxIntegrityChecks = 20
+
+// integrityWorkers bounds how many dependencies are downloaded at once
+const integrityWorkers = 4
+
+// sriStrength lists the Subresource Integrity algorithms from strongest to
+// weakest. Browsers only compare the hashes of the strongest one present.
+var sriStrength = []string{"sha512-", "sha384-", "sha256-"}
+
+// inventoryDependencies lists the external scripts and stylesheets of a page
+// with their Subresource Integrity protection. When configured, it downloads
+// the ones with an integrity attribute to verify their hashes.
+func (a *Analyzer) inventoryDependencies(ctx context.Context, doc *html.Node, pageURL *url.URL) models.DependencyAnalysis {
+	analysis := models.DependencyAnalysis{
+		Items:           []models.Dependency{},
+		ThirdPartyHosts: []string{},
+	}
+	base := documentBase(doc, pageURL)
+	seen := map[string]bool{}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if kind, ref := dependencyRef(n); kind != "" {
+				u, err := base.Parse(strings.TrimSpace(ref))
+				if err == nil && (u.Scheme == "http" || u.Scheme == "https") && !seen[kind+" "+u.String()] {
+					seen[kind+" "+u.String()] = true
+					analysis.Items = append(analysis.Items, newDependency(n, kind, u, pageURL))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if a.config.VerifyIntegrity {
+		a.verifyDependencies(ctx, analysis.Items)
+	}
+
+	for i := range analysis.Items {
+		dep := &analysis.Items[i]
+		if dep.ThirdParty {
+			analysis.ThirdParty++
+			if !slices.Contains(analysis.ThirdPartyHosts, dep.Host) {
+				analysis.ThirdPartyHosts = append(analysis.ThirdPartyHosts, dep.Host)
+			}
+		} else {
+			analysis.FirstParty++
+		}
+		if !dep.IntegrityValid {
+			analysis.WithoutIntegrity++
+		}
+	}
+	slices.Sort(analysis.ThirdPartyHosts)
+
+	return analysis
+}
+
+// verifyDependencies verifies the integrity hashes of up to
+// maxIntegrityChecks dependencies, a few at a time. All downloads share a
+// single request timeout; those still running when it expires are reported
+// as unavailable.
+func (a *Analyzer) verifyDependencies(ctx context.Context, deps []models.Dependency) {
+	ctx, cancel := context.WithTimeout(ctx, a.config.RequestTimeout)
+	defer cancel()
+
+	var g errgroup.Group
+	g.SetLimit(integrityWorkers)
+	checked := 0
+	for i := range deps {
+		dep := &deps[i]
+		if !dep.IntegrityValid || checked == maxIntegrityChecks {
+			continue
+		}
+		checked++
+		g.Go(func() error {
+			dep.Verification = models.IntegrityUnavailable
+			if ctx.Err() == nil {
+				dep.Verification = a.verifyIntegrity(ctx, dep)
+			}
+			if dep.Verification == models.IntegrityMismatch {
+				dep.Findings = append(dep.Findings, newCheck("integrity", dep.Integrity, models.VerdictFail,
+					"The downloaded resource does not match its integrity hash; browsers block it"))
+			}
+			return nil
+		})
+	}
+	g.Wait()
+}
+
+// dependencyRef returns the kind and URL of the script or stylesheet an
+// element loads, or an empty kind
+func dependencyRef(n *html.Node) (kind, ref string) {
+	switch n.Data {
+	case "script":
+		src, ok := attr(n, "src")
+		if ok && jsTypes[strings.ToLower(strings.TrimSpace(attrValue(n, "type")))] {
+			return models.DependencyScript, src
+		}
+	case "link":
+		href, ok := attr(n, "href")
+		if !ok {
+			return "", ""
+		}
+		for _, rel := range strings.Fields(strings.ToLower(attrValue(n, "rel"))) {
+			switch {
+			case rel == "stylesheet", rel == "preload" && strings.EqualFold(attrValue(n, "as"), "style"):
+				return models.DependencyStylesheet, href
+			case rel == "modulepreload", rel == "preload" && strings.EqualFold(attrValue(n, "as"), "script"):
+				return models.DependencyScript, href
+			}
+		}
+	}
+	return "", ""
+}
+
+// newDependency describes a dependency and flags gaps in its protection
+func newDependency(n *html.Node, kind string, u, pageURL *url.URL) models.Dependency {
+	dep := models.Dependency{
+		URL:       u.String(),
+		Host:      strings.ToLower(u.Hostname()),
+		Type:      kind,
+		Integrity: strings.TrimSpace(attrValue(n, "integrity")),
+		Findings:  []models.SecurityCheck{},
+	}
+	dep.ThirdParty = registrableDomain(dep.Host) != registrableDomain(strings.ToLower(pageURL.Hostname()))
+	_, digests := integrityHashes(dep.Integrity)
+	dep.IntegrityValid = len(digests) > 0
+	if crossOrigin, ok := attr(n, "crossorigin"); ok {
+		// An empty or unknown value means anonymous
+		dep.CrossOrigin = "anonymous"
+		if strings.EqualFold(crossOrigin, "use-credentials") {
+			dep.CrossOrigin = "use-credentials"
+		}
+	}
+
+	flag := func(verdict, explanation string) {
+		dep.Findings = append(dep.Findings, newCheck("integrity", dep.Integrity, verdict, explanation))
+	}
+	switch {
+	case dep.Integrity != "" && !dep.IntegrityValid:
+		flag(models.VerdictFail, "The integrity attribute has no valid sha256, sha384 or sha512 hash; browsers do not enforce it")
+	case dep.IntegrityValid && dep.CrossOrigin == "" && !sameOrigin(u, pageURL):
+		flag(models.VerdictFail, "Cross-origin integrity checks need the crossorigin attribute; browsers block the resource")
+	case !dep.IntegrityValid && dep.ThirdParty && kind == models.DependencyScript:
+		flag(models.VerdictWarn, "Third-party script without integrity; whoever controls "+dep.Host+" can run code on the page")
+	case !dep.IntegrityValid && dep.ThirdParty:
+		flag(models.VerdictWarn, "Third-party stylesheet without integrity; whoever controls "+dep.Host+" can alter the page")
+	}
+
+	return dep
+}
+
+// integrityHashes returns the strongest algorithm of an integrity attribute
+// with its decoded digests, ignoring malformed and unsupported entries
+func integrityHashes(integrity string) (algorithm string, digests [][]byte) {
+	byAlgorithm := map[string][][]byte{}
+	for _, token := range strings.Fields(integrity) {
+		// Options follow a question mark and are not used yet
+		token, _, _ = strings.Cut(token, "?")
+		for prefix, newHash := range cspHashes {
+			if !strings.HasPrefix(strings.ToLower(token), prefix) {
+				continue
+			}
+			encoded := token[len(prefix):]
+			digest, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				digest, err = base64.URLEncoding.DecodeString(encoded)
+			}
+			if err == nil && len(digest) == newHash().Size() {
+				byAlgorithm[prefix] = append(byAlgorithm[prefix], digest)
+			}
+		}
+	}
+	for _, prefix := range sriStrength {
+		if len(byAlgorithm[prefix]) > 0 {
+			return prefix, byAlgorithm[prefix]
+		}
+	}
+	return "", nil
+}
+
+// verifyIntegrity downloads a dependency and compares it with the hashes of
+// its integrity attribute
+func (a *Analyzer) verifyIntegrity(ctx context.Context, dep *models.Dependency) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dep.URL, nil)
+	if err != nil {
+		return models.IntegrityUnavailable
+	}
+	req.Header.Set("User-Agent", a.config.UserAgent)
+
+	resp, err := a.deepClient.Do(req)
+	if err != nil {
+		a.logger.Debug("Failed to download dependency", "url", dep.URL, "error", err)
+		return models.IntegrityUnavailable
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return models.IntegrityUnavailable
+	}
+	body, err := readBody(resp, a.config.MaxPageSize)
+	if err != nil {
+		return models.IntegrityUnavailable
+	}
+
+	algorithm, digests := integrityHashes(dep.Integrity)
+	h := cspHashes[algorithm]()
+	h.Write(body)
+	sum := h.Sum(nil)
+	if slices.ContainsFunc(digests, func(digest []byte) bool { return bytes.Equal(digest, sum) }) {
+		return models.IntegrityMatch
+	}
+	return models.IntegrityMismatch
+}
@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// Errors returned when a page cannot be fetched or analyzed. Returned errors
+// wrap one of these together with the underlying cause; test for them with
+// errors.Is.
+var (
+	ErrInvalidURL        = errors.New("invalid URL")
+	ErrDNS               = errors.New("host could not be resolved")
+	ErrConnectionRefused = errors.New("connection refused")
+	ErrTLS               = errors.New("TLS handshake failed")
+	ErrTimeout           = errors.New("request timed out")
+	ErrNotHTML           = errors.New("response is not HTML")
+	ErrTooLarge          = errors.New("response is too large")
+	ErrUpstreamStatus    = errors.New("unexpected upstream status")
+)
+
+// StatusError reports a non-200 response from the analyzed site. It matches
+// ErrUpstreamStatus.
+type StatusError struct {
+	StatusCode int
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is reports whether target is ErrUpstreamStatus
+func (e *StatusError) Is(target error) bool {
+	return target == ErrUpstreamStatus
+}
+
+// parsePageURL parses the URL of a page to analyze, defaulting to https
+// when no scheme is given
+func parsePageURL(urlStr string) (*url.URL, error) {
+	parsedURL, err := url.Parse(urlStr)
+	if err == nil && parsedURL.Scheme == "" {
+		parsedURL, err = url.Parse("https://" + urlStr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidURL, parsedURL.Scheme)
+	}
+	if parsedURL.Host == "" {
+		return nil, fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+	return parsedURL, nil
+}
+
+// classifyFetchError wraps an error of sending a request in the matching
+// sentinel error
+func classifyFetchError(err error) error {
+	var (
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		certErr      *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("%w: %w", ErrDNS, err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("%w: %w", ErrConnectionRefused, err)
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return fmt.Errorf("%w: %w", ErrTLS, err)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	default:
+		return fmt.Errorf("failed to fetch URL: %w", err)
+	}
+}
+
+// checkResponse rejects responses that are not a successful HTML page
+func checkResponse(resp *http.Response, maxSize int64) error {
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	// Pages without a content type are parsed anyway
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+			return fmt.Errorf("%w: content type %s", ErrNotHTML, contentType)
+		}
+	}
+
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrTooLarge, resp.ContentLength, maxSize)
+	}
+	return nil
+}
+
+// readBody reads a response body of at most maxSize bytes; zero means no limit
+func readBody(resp *http.Response, maxSize int64) ([]byte, error) {
+	reader := io.Reader(resp.Body)
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, classifyFetchError(err)
+	}
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("%w: body exceeds the limit of %d bytes", ErrTooLarge, maxSize)
+	}
+	return body, nil
+}
@@ -0,0 +1,155 @@
+package analyzer
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// csrfFieldPattern matches names of hidden fields that carry an anti-CSRF token
+var csrfFieldPattern = regexp.MustCompile(`(?i)(csrf|xsrf|authenticity_token|requestverificationtoken|^_token$|nonce)`)
+
+// passwordAutocomplete lists the autocomplete values password managers need
+var passwordAutocomplete = map[string]bool{"current-password": true, "new-password": true}
+
+// auditForms reports every form of a page with the security problems of how
+// it submits, plus password fields that are not part of any form
+func (a *Analyzer) auditForms(doc *html.Node, pageURL *url.URL) models.FormAnalysis {
+	analysis := models.FormAnalysis{
+		Forms:    []models.FormRecord{},
+		Findings: []models.SecurityCheck{},
+	}
+	base := documentBase(doc, pageURL)
+
+	var walk func(n *html.Node, inForm bool)
+	walk = func(n *html.Node, inForm bool) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "form" && !inForm:
+				analysis.Forms = append(analysis.Forms, a.auditForm(n, base, pageURL))
+				inForm = true
+			case n.Data == "input" && !inForm && isPasswordInput(n) && attrValue(n, "form") == "":
+				analysis.PasswordFieldsOutsideForms++
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inForm)
+		}
+	}
+	walk(doc, false)
+
+	if analysis.PasswordFieldsOutsideForms > 0 {
+		analysis.Findings = append(analysis.Findings, newCheck("password", "", models.VerdictWarn,
+			"Password fields outside a form are submitted by scripts, bypassing the browser's form handling and password managers"))
+	}
+
+	return analysis
+}
+
+// auditForm describes a form and flags how it could leak what users submit
+func (a *Analyzer) auditForm(form *html.Node, base, pageURL *url.URL) models.FormRecord {
+	record := models.FormRecord{
+		ID:                   attrValue(form, "id"),
+		Name:                 attrValue(form, "name"),
+		Method:               strings.ToUpper(strings.TrimSpace(attrValue(form, "method"))),
+		Login:                a.detectLoginForm(form),
+		PasswordAutocomplete: []string{},
+		Findings:             []models.SecurityCheck{},
+	}
+	if record.Method != "POST" && record.Method != "DIALOG" {
+		record.Method = "GET"
+	}
+
+	// An empty action submits to the document itself
+	action := pageURL
+	if raw := strings.TrimSpace(attrValue(form, "action")); raw != "" {
+		if u, err := base.Parse(raw); err == nil {
+			action = u
+		}
+	}
+	record.Action = action.String()
+	record.InsecureAction = action.Scheme == "http"
+	record.CrossOrigin = !sameOrigin(action, pageURL)
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "input" {
+			switch {
+			case isPasswordInput(n):
+				record.PasswordFields++
+				record.PasswordAutocomplete = append(record.PasswordAutocomplete,
+					strings.ToLower(strings.TrimSpace(attrValue(n, "autocomplete"))))
+			case strings.EqualFold(attrValue(n, "type"), "hidden") && csrfFieldPattern.MatchString(attrValue(n, "name")):
+				record.CSRFToken = true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(form)
+
+	flag := func(name, value, verdict, explanation string) {
+		record.Findings = append(record.Findings, newCheck(name, value, verdict, explanation))
+	}
+
+	if record.Method == "DIALOG" {
+		// Dialog forms close a dialog and never submit
+		return record
+	}
+
+	credentials := record.PasswordFields > 0
+	switch {
+	case credentials && record.InsecureAction:
+		flag("action", record.Action, models.VerdictFail, "Credentials are submitted over plain HTTP")
+	case record.InsecureAction && pageURL.Scheme == "https":
+		flag("action", record.Action, models.VerdictWarn, "The HTTPS page submits this form over plain HTTP")
+	}
+	if credentials && record.CrossOrigin {
+		flag("action", record.Action, models.VerdictWarn, "Credentials are submitted to another origin")
+	}
+	if credentials && record.Method == "GET" {
+		flag("method", record.Method, models.VerdictFail,
+			"Credentials are submitted with GET and end up in URLs, server logs and browser history")
+	}
+	if record.Method == "POST" && !record.CSRFToken {
+		flag("csrf", "", models.VerdictWarn, "No hidden anti-CSRF token field; make sure the form is protected another way")
+	}
+	for _, autocomplete := range record.PasswordAutocomplete {
+		if !passwordAutocomplete[autocomplete] {
+			flag("autocomplete", autocomplete, models.VerdictWarn,
+				"Password field without autocomplete=current-password or new-password; password managers may not fill it")
+		}
+	}
+
+	return record
+}
+
+// isPasswordInput reports whether an element is a password field
+func isPasswordInput(n *html.Node) bool {
+	return n.Type == html.ElementNode && n.Data == "input" && strings.EqualFold(attrValue(n, "type"), "password")
+}
+
+// attr returns the value of an attribute of an element and whether it is set
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// attrValue returns the value of an attribute of an element, or ""
+func attrValue(n *html.Node, key string) string {
+	value, _ := attr(n, key)
+	return value
+}
+
+// sameOrigin reports whether two URLs share scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Hostname(), b.Hostname()) && effectivePort(a) == effectivePort(b)
+}
@@ -0,0 +1,189 @@
+package analyzer
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// checkMixedContent names the mixed content check
+const checkMixedContent = "Mixed content"
+
+// cssURLPattern matches url() references in CSS
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+
+// cssImportPattern matches @import rules with a quoted URL
+var cssImportPattern = regexp.MustCompile(`@import\s+['"]([^'"]+)['"]`)
+
+// subresourceAttrs maps elements to the attributes loading subresources and
+// whether browsers treat them as active content that can change the page
+var subresourceAttrs = map[string][]struct {
+	attr   string
+	active bool
+}{
+	"script": {{"src", true}},
+	"iframe": {{"src", true}},
+	"frame":  {{"src", true}},
+	"object": {{"data", true}},
+	"embed":  {{"src", true}},
+	"form":   {{"action", true}},
+	"img":    {{"src", false}, {"srcset", false}},
+	"source": {{"src", false}, {"srcset", false}},
+	"video":  {{"src", false}, {"poster", false}},
+	"audio":  {{"src", false}},
+	"track":  {{"src", false}},
+	"input":  {{"src", false}},
+}
+
+// detectMixedContent lists the subresources an HTTPS page loads over plain
+// HTTP, resolving relative URLs against the page or its <base> element
+func detectMixedContent(doc *html.Node, pageURL *url.URL) models.MixedContentAnalysis {
+	analysis := models.MixedContentAnalysis{Items: []models.MixedContentItem{}}
+	if pageURL.Scheme != "https" {
+		return analysis
+	}
+
+	base := documentBase(doc, pageURL)
+	add := func(element, attribute, ref string, active bool) {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || u.Scheme != "http" {
+			return
+		}
+		item := models.MixedContentItem{URL: u.String(), Element: element, Attribute: attribute}
+		if active {
+			item.Type = models.MixedContentActive
+			analysis.Active++
+		} else {
+			item.Type = models.MixedContentPassive
+			analysis.Passive++
+		}
+		analysis.Items = append(analysis.Items, item)
+	}
+	addCSS := func(element, attribute, css string) {
+		for _, m := range cssImportPattern.FindAllStringSubmatch(css, -1) {
+			add(element, "@import", m[1], true)
+		}
+		for _, loc := range cssURLPattern.FindAllStringSubmatchIndex(css, -1) {
+			// Imported stylesheets are active content, other CSS resources passive
+			imported := strings.HasSuffix(strings.TrimSpace(css[:loc[0]]), "@import")
+			add(element, attribute, css[loc[2]:loc[3]], imported)
+		}
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attrs := map[string]string{}
+			for _, attr := range n.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+
+			for _, sub := range subresourceAttrs[n.Data] {
+				value, ok := attrs[sub.attr]
+				if !ok || (n.Data == "input" && !strings.EqualFold(attrs["type"], "image")) {
+					continue
+				}
+				if sub.attr == "srcset" {
+					for _, candidate := range strings.Split(value, ",") {
+						if fields := strings.Fields(candidate); len(fields) > 0 {
+							add(n.Data, sub.attr, fields[0], sub.active)
+						}
+					}
+					continue
+				}
+				add(n.Data, sub.attr, value, sub.active)
+			}
+
+			if n.Data == "link" {
+				if href, ok := attrs["href"]; ok {
+					if active, loaded := linkLoads(attrs["rel"], attrs["as"]); loaded {
+						add("link", "href", href, active)
+					}
+				}
+			}
+
+			if style, ok := attrs["style"]; ok {
+				addCSS(n.Data, "style", style)
+			}
+			if n.Data == "style" {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.TextNode {
+						addCSS("style", "url()", c.Data)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	switch {
+	case analysis.Active > 0:
+		analysis.Checks = []models.SecurityCheck{newCheck(checkMixedContent, "", models.VerdictFail,
+			"Scripts, frames, stylesheets or forms use plain HTTP; browsers block them and attackers can alter the page")}
+	case analysis.Passive > 0:
+		analysis.Checks = []models.SecurityCheck{newCheck(checkMixedContent, "", models.VerdictWarn,
+			"Images or media are loaded over plain HTTP and can be observed or replaced")}
+	default:
+		analysis.Checks = []models.SecurityCheck{newCheck(checkMixedContent, "", models.VerdictPass,
+			"All subresources are loaded over HTTPS")}
+	}
+
+	return analysis
+}
+
+// linkLoads reports whether a <link> element loads a subresource, and if so
+// whether it is active content
+func linkLoads(rel, as string) (active, loaded bool) {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet", "modulepreload":
+			return true, true
+		case "preload", "prefetch":
+			switch strings.ToLower(as) {
+			case "image", "audio", "video", "track":
+				return false, true
+			}
+			return true, true
+		case "icon", "apple-touch-icon":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// documentBase returns the URL relative references of a document resolve
+// against: the first <base href>, or the page URL
+func documentBase(doc *html.Node, pageURL *url.URL) *url.URL {
+	var base *url.URL
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if base != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "base" {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" {
+					if u, err := pageURL.Parse(strings.TrimSpace(attr.Val)); err == nil {
+						base = u
+						return
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+
+	if base == nil {
+		return pageURL
+	}
+	return base
+}
@@ -2,12 +2,12 @@ package analyzer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"golang.org/x/net/html"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 	"golang.org/x/time/rate"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -148,7 +148,7 @@ func (a *MultipleUrlAnalyzer) AnalyzeURLs(ctx context.Context, urls []string) ([
 				errorMsg += fmt.Sprintf("\n- ... and %d more errors", len(errs)-5)
 			}
 		}
-		return results, fmt.Errorf(errorMsg)
+		return results, errors.New(errorMsg)
 	}
 
 	return results, nil
@@ -156,22 +156,17 @@ func (a *MultipleUrlAnalyzer) AnalyzeURLs(ctx context.Context, urls []string) ([
 
 // analyzeURL analyzes a single webpage and returns the analysis result
 func (a *MultipleUrlAnalyzer) analyzeURLMulti(ctx context.Context, urlStr string) (*models.AnalysisResult, error) {
-	// Parse URL
-	parsedURL, err := url.Parse(urlStr)
+	// Parse URL, defaulting to https
+	parsedURL, err := parsePageURL(urlStr)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
-	}
-
-	// Ensure scheme is set
-	if parsedURL.Scheme == "" {
-		parsedURL.Scheme = "https"
-		urlStr = parsedURL.String()
+		return nil, err
 	}
+	urlStr = parsedURL.String()
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
 	}
 
 	// Set User-Agent
@@ -181,13 +176,13 @@ func (a *MultipleUrlAnalyzer) analyzeURLMulti(ctx context.Context, urlStr string
 	a.logger.Debug("Sending request", "url", urlStr)
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, classifyFetchError(err)
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	// Only successful HTML responses are analyzed
+	if err := checkResponse(resp, a.config.MaxPageSize); err != nil {
+		return nil, err
 	}
 
 	// Estimate memory needed for this page
@@ -205,9 +200,9 @@ func (a *MultipleUrlAnalyzer) analyzeURLMulti(ctx context.Context, urlStr string
 	defer a.semaphore.Release(estimatedMemory)
 
 	// Read body
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, a.config.MaxPageSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	// Parse HTML
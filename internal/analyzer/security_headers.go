@@ -0,0 +1,372 @@
+package analyzer
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// HSTS max-age thresholds in seconds: browsers require a year for preloading
+// and half a year is the commonly recommended minimum
+const (
+	hstsMinMaxAge     = 180 * 24 * 60 * 60
+	hstsPreloadMaxAge = 365 * 24 * 60 * 60
+)
+
+// Names of the security checks, used to weigh them when grading
+const (
+	checkHSTS               = "Strict-Transport-Security"
+	checkCSP                = "Content-Security-Policy"
+	checkFrameOptions       = "X-Frame-Options"
+	checkContentTypeOptions = "X-Content-Type-Options"
+	checkReferrerPolicy     = "Referrer-Policy"
+	checkPermissionsPolicy  = "Permissions-Policy"
+	checkOpenerPolicy       = "Cross-Origin-Opener-Policy"
+	checkEmbedderPolicy     = "Cross-Origin-Embedder-Policy"
+	checkResourcePolicy     = "Cross-Origin-Resource-Policy"
+	checkCacheControl       = "Cache-Control"
+	checkXSSProtection      = "X-XSS-Protection"
+)
+
+// checkWeights sets how much each check counts towards the security score.
+// Checks without a weight are reported but not graded.
+var checkWeights = map[string]int{
+	checkHSTS:               20,
+	checkCSP:                20,
+	checkFrameOptions:       15,
+	checkContentTypeOptions: 10,
+	checkReferrerPolicy:     10,
+	checkCacheControl:       10,
+	checkPermissionsPolicy:  5,
+	checkOpenerPolicy:       5,
+	checkEmbedderPolicy:     5,
+	checkResourcePolicy:     5,
+	checkXSSProtection:      5,
+	checkTLSProtocol:        10,
+	checkTLSCertificate:     20,
+	checkTLSHostname:        20,
+	checkTLSExpiry:          10,
+	checkMixedContent:       20,
+}
+
+// gradeThresholds maps minimum scores to letter grades, best first
+var gradeThresholds = []struct {
+	score int
+	grade string
+}{
+	{95, "A+"}, {85, "A"}, {70, "B"}, {55, "C"}, {40, "D"}, {0, "F"},
+}
+
+// auditSecurityHeaders checks the security headers of a page response,
+// taking its parsed content security policies into account. Pages with a
+// password field or setting cookies are sensitive and must not be cached.
+func auditSecurityHeaders(resp *http.Response, doc *html.Node, csp models.CSPAnalysis) []models.SecurityCheck {
+	header := resp.Header
+	https := resp.Request != nil && resp.Request.URL.Scheme == "https"
+	sensitive := len(resp.Cookies()) > 0 || hasPasswordInput(doc)
+
+	return []models.SecurityCheck{
+		checkStrictTransportSecurity(header.Get(checkHSTS), https),
+		checkContentSecurityPolicy(csp),
+		checkFrameProtection(header.Get(checkFrameOptions), csp),
+		checkContentTypeOptionsHeader(header.Get(checkContentTypeOptions)),
+		checkReferrerPolicyHeader(header.Get(checkReferrerPolicy)),
+		checkPermissionsPolicyHeader(header.Get(checkPermissionsPolicy), header.Get("Feature-Policy")),
+		checkCrossOriginHeader(checkOpenerPolicy, header.Get(checkOpenerPolicy),
+			[]string{"same-origin"}, []string{"same-origin-allow-popups"},
+			"isolates the browsing context from cross-origin windows"),
+		checkCrossOriginHeader(checkEmbedderPolicy, header.Get(checkEmbedderPolicy),
+			[]string{"require-corp", "credentialless"}, nil,
+			"only allows embedding resources that opt in"),
+		checkCrossOriginHeader(checkResourcePolicy, header.Get(checkResourcePolicy),
+			[]string{"same-origin", "same-site"}, []string{"cross-origin"},
+			"restricts which sites may load this resource"),
+		checkCacheControlHeader(header.Get(checkCacheControl), sensitive),
+		checkXSSProtectionHeader(header.Get(checkXSSProtection)),
+	}
+}
+
+// gradeSecurity scores checks from 0 to 100, counting a warning as half a
+// pass, and returns the score together with its letter grade
+func gradeSecurity(checks []models.SecurityCheck) (int, string) {
+	var earned, total int
+	for _, check := range checks {
+		weight := checkWeights[check.Name]
+		total += weight
+		switch check.Verdict {
+		case models.VerdictPass:
+			earned += 2 * weight
+		case models.VerdictWarn:
+			earned += weight
+		}
+	}
+	if total == 0 {
+		return 0, "F"
+	}
+
+	score := earned * 100 / (2 * total)
+	for _, t := range gradeThresholds {
+		if score >= t.score {
+			return score, t.grade
+		}
+	}
+	return score, "F"
+}
+
+// newCheck creates a check result
+func newCheck(name, value, verdict, explanation string) models.SecurityCheck {
+	return models.SecurityCheck{Name: name, Value: value, Verdict: verdict, Explanation: explanation}
+}
+
+// checkStrictTransportSecurity checks that HTTPS is enforced for long
+// enough, including subdomains
+func checkStrictTransportSecurity(value string, https bool) models.SecurityCheck {
+	if !https {
+		return newCheck(checkHSTS, value, models.VerdictFail,
+			"The page is served over plain HTTP, so HSTS cannot protect it")
+	}
+	if value == "" {
+		return newCheck(checkHSTS, value, models.VerdictFail,
+			"Missing; browsers may be downgraded to plain HTTP")
+	}
+
+	maxAge := -1
+	var includeSubDomains, preload bool
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			if n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(arg), `"`)); err == nil && n >= 0 {
+				maxAge = n
+			}
+		case "includesubdomains":
+			includeSubDomains = true
+		case "preload":
+			preload = true
+		}
+	}
+
+	switch {
+	case maxAge < 0:
+		return newCheck(checkHSTS, value, models.VerdictFail, "Missing or invalid max-age; the header is ignored")
+	case maxAge == 0:
+		return newCheck(checkHSTS, value, models.VerdictFail, "max-age=0 disables HSTS")
+	case maxAge < hstsMinMaxAge:
+		return newCheck(checkHSTS, value, models.VerdictWarn, "max-age is shorter than six months")
+	case !includeSubDomains:
+		return newCheck(checkHSTS, value, models.VerdictWarn, "Subdomains are not covered; add includeSubDomains")
+	case preload && maxAge < hstsPreloadMaxAge:
+		return newCheck(checkHSTS, value, models.VerdictWarn, "Preloading requires a max-age of at least one year")
+	case preload:
+		return newCheck(checkHSTS, value, models.VerdictPass, "HTTPS is enforced for the domain and its subdomains, ready for preloading")
+	}
+	return newCheck(checkHSTS, value, models.VerdictPass, "HTTPS is enforced for the domain and its subdomains")
+}
+
+// checkContentSecurityPolicy checks that an enforced content security
+// policy without serious weaknesses is set, in a header or <meta> tag
+func checkContentSecurityPolicy(csp models.CSPAnalysis) models.SecurityCheck {
+	var raw []string
+	enforced, weak := false, false
+	for _, policy := range csp.Policies {
+		raw = append(raw, policy.Raw)
+		if policy.ReportOnly {
+			continue
+		}
+		enforced = true
+		for _, weakness := range policy.Weaknesses {
+			weak = weak || weakness.Verdict == models.VerdictFail
+		}
+	}
+	value := strings.Join(raw, ", ")
+
+	switch {
+	case len(csp.Policies) == 0:
+		return newCheck(checkCSP, value, models.VerdictFail,
+			"Missing; injected scripts and content are not restricted")
+	case !enforced:
+		return newCheck(checkCSP, value, models.VerdictWarn,
+			"Only report-only policies are set; nothing is blocked")
+	case weak:
+		return newCheck(checkCSP, value, models.VerdictWarn,
+			"The policy has serious weaknesses and does not reliably stop injected scripts")
+	}
+	return newCheck(checkCSP, value, models.VerdictPass, "An enforced content security policy restricts scripts")
+}
+
+// checkFrameProtection checks that the page cannot be framed by other
+// sites, through either X-Frame-Options or the frame-ancestors directive of
+// an enforced header policy
+func checkFrameProtection(value string, csp models.CSPAnalysis) models.SecurityCheck {
+	if ancestors, ok := frameAncestors(csp); ok {
+		if ancestors == "*" {
+			return newCheck(checkFrameOptions, "frame-ancestors "+ancestors, models.VerdictFail,
+				"CSP frame-ancestors allows any site to frame the page")
+		}
+		return newCheck(checkFrameOptions, "frame-ancestors "+ancestors, models.VerdictPass,
+			"CSP frame-ancestors restricts who may frame the page")
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "":
+		return newCheck(checkFrameOptions, value, models.VerdictFail,
+			"Missing, and CSP sets no frame-ancestors; the page can be framed for clickjacking")
+	case "DENY", "SAMEORIGIN":
+		return newCheck(checkFrameOptions, value, models.VerdictPass, "Other sites cannot frame the page")
+	}
+	if strings.HasPrefix(strings.ToUpper(value), "ALLOW-FROM") {
+		return newCheck(checkFrameOptions, value, models.VerdictWarn,
+			"ALLOW-FROM is not supported by modern browsers; use CSP frame-ancestors")
+	}
+	return newCheck(checkFrameOptions, value, models.VerdictFail, "Invalid value; the header is ignored")
+}
+
+// checkContentTypeOptionsHeader checks that MIME type sniffing is disabled
+func checkContentTypeOptionsHeader(value string) models.SecurityCheck {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "nosniff":
+		return newCheck(checkContentTypeOptions, value, models.VerdictPass, "MIME type sniffing is disabled")
+	case "":
+		return newCheck(checkContentTypeOptions, value, models.VerdictFail,
+			"Missing; browsers may sniff responses into executable content types")
+	}
+	return newCheck(checkContentTypeOptions, value, models.VerdictFail, "Invalid value; only nosniff is recognized")
+}
+
+// checkReferrerPolicyHeader checks that full URLs do not leak to other
+// sites. Browsers use the last policy they recognize.
+func checkReferrerPolicyHeader(value string) models.SecurityCheck {
+	if strings.TrimSpace(value) == "" {
+		return newCheck(checkReferrerPolicy, value, models.VerdictWarn,
+			"Missing; browsers default to strict-origin-when-cross-origin, older ones leak full URLs")
+	}
+
+	policy := ""
+	for _, token := range strings.Split(value, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		switch token {
+		case "no-referrer", "same-origin", "strict-origin", "strict-origin-when-cross-origin",
+			"origin", "origin-when-cross-origin", "no-referrer-when-downgrade", "unsafe-url":
+			policy = token
+		}
+	}
+
+	switch policy {
+	case "no-referrer", "same-origin", "strict-origin", "strict-origin-when-cross-origin":
+		return newCheck(checkReferrerPolicy, value, models.VerdictPass, "Full URLs are not sent to other sites")
+	case "origin", "origin-when-cross-origin", "no-referrer-when-downgrade":
+		return newCheck(checkReferrerPolicy, value, models.VerdictWarn,
+			"The referrer is sent to other sites, or over HTTP")
+	case "unsafe-url":
+		return newCheck(checkReferrerPolicy, value, models.VerdictFail,
+			"Full URLs are sent to every site, even over plain HTTP")
+	}
+	return newCheck(checkReferrerPolicy, value, models.VerdictFail, "No recognized policy; the header is ignored")
+}
+
+// checkPermissionsPolicyHeader checks that powerful browser features are
+// restricted
+func checkPermissionsPolicyHeader(value, featurePolicy string) models.SecurityCheck {
+	switch {
+	case strings.TrimSpace(value) != "":
+		return newCheck(checkPermissionsPolicy, value, models.VerdictPass, "Browser features are restricted")
+	case featurePolicy != "":
+		return newCheck(checkPermissionsPolicy, featurePolicy, models.VerdictWarn,
+			"Only the deprecated Feature-Policy header is set; use Permissions-Policy")
+	}
+	return newCheck(checkPermissionsPolicy, value, models.VerdictWarn,
+		"Missing; embedded content may request camera, location and other features")
+}
+
+// checkCrossOriginHeader checks one of the cross-origin isolation headers:
+// secure values pass, weaker ones warn and anything else warns as missing
+func checkCrossOriginHeader(name, value string, secure, weak []string, purpose string) models.SecurityCheck {
+	v := strings.ToLower(strings.TrimSpace(value))
+	// Reporting endpoints may follow the value, e.g. require-corp; report-to="x"
+	v, _, _ = strings.Cut(v, ";")
+	v = strings.TrimSpace(v)
+
+	for _, s := range secure {
+		if v == s {
+			return newCheck(name, value, models.VerdictPass, "Set to "+v+"; "+purpose)
+		}
+	}
+	for _, w := range weak {
+		if v == w {
+			return newCheck(name, value, models.VerdictWarn, "Set to the weaker "+v)
+		}
+	}
+	if v == "" {
+		return newCheck(name, value, models.VerdictWarn, "Missing; setting it "+purpose)
+	}
+	return newCheck(name, value, models.VerdictWarn, "Set to "+v+", which gives no protection")
+}
+
+// checkCacheControlHeader checks that sensitive pages are not stored by
+// browsers and shared caches
+func checkCacheControlHeader(value string, sensitive bool) models.SecurityCheck {
+	if !sensitive {
+		return newCheck(checkCacheControl, value, models.VerdictPass,
+			"The page has no password field and sets no cookies, so caching it is safe")
+	}
+
+	directives := map[string]bool{}
+	for _, d := range strings.Split(value, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+		directives[strings.ToLower(name)] = true
+	}
+
+	switch {
+	case directives["no-store"]:
+		return newCheck(checkCacheControl, value, models.VerdictPass, "The sensitive page is never stored by caches")
+	case directives["private"] || directives["no-cache"]:
+		return newCheck(checkCacheControl, value, models.VerdictWarn,
+			"Shared caches are bypassed, but the sensitive page may still be stored; add no-store")
+	}
+	return newCheck(checkCacheControl, value, models.VerdictFail,
+		"The page has a password field or sets cookies but may be cached; use no-store")
+}
+
+// checkXSSProtectionHeader checks the deprecated XSS auditor header, whose
+// filter is gone from modern browsers and could be abused in older ones
+func checkXSSProtectionHeader(value string) models.SecurityCheck {
+	v := strings.TrimSpace(value)
+	switch {
+	case v == "":
+		return newCheck(checkXSSProtection, value, models.VerdictPass, "Not set; the XSS auditor is obsolete")
+	case v == "0":
+		return newCheck(checkXSSProtection, value, models.VerdictPass, "The obsolete XSS auditor is disabled")
+	}
+	return newCheck(checkXSSProtection, value, models.VerdictWarn,
+		"The XSS auditor is obsolete and could introduce vulnerabilities; set 0 or remove the header")
+}
+
+// frameAncestors returns the frame-ancestors sources of the first enforced
+// header policy setting them; browsers ignore the directive elsewhere
+func frameAncestors(csp models.CSPAnalysis) (string, bool) {
+	for _, policy := range csp.Policies {
+		if policy.ReportOnly || policy.Source != cspSourceHeader {
+			continue
+		}
+		if sources, ok := policy.Directives["frame-ancestors"]; ok {
+			return strings.Join(sources, " "), true
+		}
+	}
+	return "", false
+}
+
+// hasPasswordInput reports whether the document contains a password field
+func hasPasswordInput(n *html.Node) bool {
+	if isPasswordInput(n) {
+		return true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if hasPasswordInput(c) {
+			return true
+		}
+	}
+	return false
+}
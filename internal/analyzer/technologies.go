@@ -0,0 +1,356 @@
+package analyzer
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// bundledTechnologies is the technology fingerprint database shipped with
+// the analyzer
+//
+//go:embed technologies.json
+var bundledTechnologies []byte
+
+// maxConfidence is the confidence of a certain match
+const maxConfidence = 100
+
+// categoryCMS is the category of content management systems
+const categoryCMS = "cms"
+
+// frameworkCategories lists the categories reported as frameworks
+var frameworkCategories = []string{
+	"javascript-framework",
+	"javascript-library",
+	"ui-framework",
+	"web-framework",
+}
+
+// fingerprintPattern is a case-insensitive regular expression optionally
+// followed by \;version:<template> and \;confidence:<0-100> tags, in the
+// Wappalyzer format. Version templates refer to capture groups as \1, \2...
+type fingerprintPattern struct {
+	re         *regexp.Regexp
+	version    string
+	confidence int
+}
+
+// parseFingerprintPattern compiles a pattern and its tags
+func parseFingerprintPattern(expr string) (*fingerprintPattern, error) {
+	parts := strings.Split(expr, `\;`)
+	re, err := regexp.Compile("(?i)" + parts[0])
+	if err != nil {
+		return nil, err
+	}
+	pattern := &fingerprintPattern{re: re, confidence: maxConfidence}
+	for _, tag := range parts[1:] {
+		key, value, _ := strings.Cut(tag, ":")
+		switch key {
+		case "version":
+			pattern.version = value
+		case "confidence":
+			confidence, err := strconv.Atoi(value)
+			if err != nil || confidence < 0 || confidence > maxConfidence {
+				return nil, fmt.Errorf("invalid confidence %q", value)
+			}
+			pattern.confidence = confidence
+		default:
+			return nil, fmt.Errorf("unknown pattern tag %q", key)
+		}
+	}
+	return pattern, nil
+}
+
+// match applies the pattern, returning whether it matched and the version
+// it extracted
+func (p *fingerprintPattern) match(s string) (bool, string) {
+	m := p.re.FindStringSubmatch(s)
+	if m == nil {
+		return false, ""
+	}
+	version := p.version
+	// Replace higher groups first so that \1 does not clobber \10
+	for i := len(m) - 1; i >= 1; i-- {
+		version = strings.ReplaceAll(version, `\`+strconv.Itoa(i), m[i])
+	}
+	return true, strings.TrimSpace(version)
+}
+
+// technologyRule fingerprints a technology. Headers, meta tags (by name) and
+// cookies (by name) map to patterns matching their values, an empty pattern
+// only requiring their presence. Scripts patterns match script URLs, HTML
+// ones the raw markup, and JS entries name global variables defined or
+// referenced through window by inline scripts. Implies names technologies a
+// match entails, optionally with a \;confidence tag.
+type technologyRule struct {
+	Name       string            `json:"name"`
+	Categories []string          `json:"categories"`
+	Headers    map[string]string `json:"headers"`
+	Meta       map[string]string `json:"meta"`
+	Cookies    map[string]string `json:"cookies"`
+	Scripts    []string          `json:"scripts"`
+	HTML       []string          `json:"html"`
+	JS         []string          `json:"js"`
+	Implies    []string          `json:"implies"`
+
+	headers map[string]*fingerprintPattern
+	meta    map[string]*fingerprintPattern
+	cookies map[string]*fingerprintPattern
+	scripts []*fingerprintPattern
+	html    []*fingerprintPattern
+	js      []*fingerprintPattern
+	implies []impliedTechnology
+}
+
+// impliedTechnology is a technology a rule implies
+type impliedTechnology struct {
+	rule       *technologyRule
+	confidence int
+}
+
+// loadTechnologyRules parses the bundled technology fingerprints
+func loadTechnologyRules() ([]*technologyRule, error) {
+	var db struct {
+		Technologies []*technologyRule `json:"technologies"`
+	}
+	if err := json.Unmarshal(bundledTechnologies, &db); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*technologyRule, len(db.Technologies))
+	for _, rule := range db.Technologies {
+		if rule.Name == "" {
+			return nil, errors.New("technology rule without a name")
+		}
+		if byName[rule.Name] != nil {
+			return nil, fmt.Errorf("duplicate technology rule %q", rule.Name)
+		}
+		byName[rule.Name] = rule
+	}
+
+	for _, rule := range db.Technologies {
+		if err := rule.compile(byName); err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+	}
+	return db.Technologies, nil
+}
+
+// compile parses the patterns of a rule and resolves the technologies it
+// implies
+func (r *technologyRule) compile(byName map[string]*technologyRule) error {
+	compileMap := func(exprs map[string]string, canonical func(string) string) (map[string]*fingerprintPattern, error) {
+		compiled := make(map[string]*fingerprintPattern, len(exprs))
+		for key, expr := range exprs {
+			pattern, err := parseFingerprintPattern(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			compiled[canonical(key)] = pattern
+		}
+		return compiled, nil
+	}
+	compileList := func(exprs []string) ([]*fingerprintPattern, error) {
+		compiled := make([]*fingerprintPattern, 0, len(exprs))
+		for _, expr := range exprs {
+			pattern, err := parseFingerprintPattern(expr)
+			if err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, pattern)
+		}
+		return compiled, nil
+	}
+	identity := func(key string) string { return key }
+
+	var err error
+	if r.headers, err = compileMap(r.Headers, http.CanonicalHeaderKey); err != nil {
+		return err
+	}
+	if r.meta, err = compileMap(r.Meta, strings.ToLower); err != nil {
+		return err
+	}
+	if r.cookies, err = compileMap(r.Cookies, identity); err != nil {
+		return err
+	}
+	if r.scripts, err = compileList(r.Scripts); err != nil {
+		return err
+	}
+	if r.html, err = compileList(r.HTML); err != nil {
+		return err
+	}
+
+	// Globals count when declared, assigned or accessed through window
+	globals := make([]string, 0, len(r.JS))
+	for _, entry := range r.JS {
+		name, tags, _ := strings.Cut(entry, `\;`)
+		name = regexp.QuoteMeta(name)
+		expr := `(?:window\.` + name + `\b|\b(?:var|let|const)\s+` + name + `\b|(?:^|[^.\w$])` + name + `\s*=[^=])`
+		if tags != "" {
+			expr += `\;` + tags
+		}
+		globals = append(globals, expr)
+	}
+	if r.js, err = compileList(globals); err != nil {
+		return err
+	}
+
+	for _, entry := range r.Implies {
+		name, tags, _ := strings.Cut(entry, `\;`)
+		implied := impliedTechnology{rule: byName[name], confidence: maxConfidence}
+		if implied.rule == nil {
+			return fmt.Errorf("implies unknown technology %q", name)
+		}
+		if tags != "" {
+			pattern, err := parseFingerprintPattern(`\;` + tags)
+			if err != nil {
+				return err
+			}
+			implied.confidence = pattern.confidence
+		}
+		r.implies = append(r.implies, implied)
+	}
+	return nil
+}
+
+// fingerprint detects the technologies behind a page from its response
+// headers and cookies, meta tags, script URLs, markup and inline scripts.
+// Each matching pattern adds its confidence, up to 100, and the longest
+// version extracted wins.
+func (a *Analyzer) fingerprint(header http.Header, cookies []*http.Cookie, body []byte, doc *html.Node, pageURL *url.URL) []models.Technology {
+	type detection struct {
+		version    string
+		confidence int
+		impliedBy  []string
+	}
+	detected := map[*technologyRule]*detection{}
+	var found []*technologyRule
+	hit := func(rule *technologyRule, pattern *fingerprintPattern, value string) bool {
+		ok, version := pattern.match(value)
+		if !ok {
+			return false
+		}
+		d := detected[rule]
+		if d == nil {
+			d = &detection{}
+			detected[rule] = d
+			found = append(found, rule)
+		}
+		d.confidence = min(d.confidence+pattern.confidence, maxConfidence)
+		if len(version) > len(d.version) {
+			d.version = version
+		}
+		return true
+	}
+
+	// Collect what the rules match against
+	meta := map[string][]string{}
+	var collectMeta func(*html.Node)
+	collectMeta = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			if name, ok := attr(n, "name"); ok {
+				name = strings.ToLower(strings.TrimSpace(name))
+				meta[name] = append(meta[name], attrValue(n, "content"))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collectMeta(c)
+		}
+	}
+	collectMeta(doc)
+	var sources, inline []string
+	for _, script := range collectScripts(doc, pageURL) {
+		if script.src != nil {
+			sources = append(sources, script.src.String())
+		} else {
+			inline = append(inline, script.content)
+		}
+	}
+	markup := string(body)
+
+	for _, rule := range a.technologies {
+		for name, pattern := range rule.headers {
+			for _, value := range header.Values(name) {
+				if hit(rule, pattern, value) {
+					break
+				}
+			}
+		}
+		for name, pattern := range rule.meta {
+			for _, content := range meta[name] {
+				if hit(rule, pattern, content) {
+					break
+				}
+			}
+		}
+		for name, pattern := range rule.cookies {
+			for _, cookie := range cookies {
+				if cookie.Name == name && hit(rule, pattern, cookie.Value) {
+					break
+				}
+			}
+		}
+		for _, pattern := range rule.scripts {
+			for _, src := range sources {
+				if hit(rule, pattern, src) {
+					break
+				}
+			}
+		}
+		for _, pattern := range rule.html {
+			hit(rule, pattern, markup)
+		}
+		for _, pattern := range rule.js {
+			for _, code := range inline {
+				if hit(rule, pattern, code) {
+					break
+				}
+			}
+		}
+	}
+
+	// Add implied technologies, including those implied in turn, no more
+	// confident than what implies them
+	for i := 0; i < len(found); i++ {
+		rule := found[i]
+		for _, implied := range rule.implies {
+			confidence := min(detected[rule].confidence, implied.confidence)
+			d := detected[implied.rule]
+			switch {
+			case d == nil:
+				detected[implied.rule] = &detection{confidence: confidence, impliedBy: []string{rule.Name}}
+				found = append(found, implied.rule)
+			case len(d.impliedBy) > 0 && !slices.Contains(d.impliedBy, rule.Name):
+				d.impliedBy = append(d.impliedBy, rule.Name)
+				d.confidence = max(d.confidence, confidence)
+			}
+		}
+	}
+
+	technologies := make([]models.Technology, 0, len(found))
+	for _, rule := range found {
+		d := detected[rule]
+		technologies = append(technologies, models.Technology{
+			Name:       rule.Name,
+			Version:    d.version,
+			Categories: slices.Clone(rule.Categories),
+			Confidence: d.confidence,
+			ImpliedBy:  d.impliedBy,
+		})
+	}
+	slices.SortStableFunc(technologies, func(x, y models.Technology) int {
+		return cmp.Or(cmp.Compare(y.Confidence, x.Confidence), cmp.Compare(x.Name, y.Name))
+	})
+	return technologies
+}
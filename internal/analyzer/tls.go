@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// certExpiryWarningDays is how many days before expiry a certificate is
+// reported as expiring soon
+const certExpiryWarningDays = 30
+
+// Names of the TLS checks
+const (
+	checkTLSProtocol    = "TLS protocol"
+	checkTLSCertificate = "TLS certificate"
+	checkTLSHostname    = "TLS hostname"
+	checkTLSExpiry      = "TLS certificate expiry"
+)
+
+// newInspectingTransport returns a transport that completes TLS handshakes
+// with certificates that fail verification, so deep analyses can report the
+// problem instead of failing. analyzeTLS verifies the chain itself.
+func newInspectingTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return transport
+}
+
+// analyzeTLS describes the TLS connection a page was served over and
+// verifies its certificate chain for the host. It returns a zero analysis
+// for plain HTTP.
+func analyzeTLS(state *tls.ConnectionState, host string, now time.Time) models.TLSAnalysis {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return models.TLSAnalysis{}
+	}
+
+	leaf := state.PeerCertificates[0]
+	analysis := models.TLSAnalysis{
+		Enabled:          true,
+		Version:          tls.VersionName(state.Version),
+		CipherSuite:      tls.CipherSuiteName(state.CipherSuite),
+		ALPN:             state.NegotiatedProtocol,
+		SelfSigned:       bytes.Equal(leaf.RawIssuer, leaf.RawSubject) && leaf.CheckSignatureFrom(leaf) == nil,
+		HostnameMismatch: leaf.VerifyHostname(host) != nil,
+		Expired:          now.After(leaf.NotAfter) || now.Before(leaf.NotBefore),
+		DaysUntilExpiry:  int(leaf.NotAfter.Sub(now).Hours() / 24),
+		Chain:            make([]models.CertificateInfo, 0, len(state.PeerCertificates)),
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates {
+		analysis.Chain = append(analysis.Chain, certificateInfo(cert))
+		if cert != leaf {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	// Trust is checked apart from the hostname, which is reported on its own
+	_, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: now})
+	analysis.Trusted = err == nil
+	if err != nil {
+		analysis.VerificationError = err.Error()
+	}
+
+	analysis.Checks = tlsChecks(analysis, state.Version, host)
+	return analysis
+}
+
+// tlsChecks rates the protocol and certificate of a TLS connection
+func tlsChecks(analysis models.TLSAnalysis, version uint16, host string) []models.SecurityCheck {
+	checks := make([]models.SecurityCheck, 0, 4)
+
+	if version < tls.VersionTLS12 {
+		checks = append(checks, newCheck(checkTLSProtocol, analysis.Version, models.VerdictFail,
+			"TLS versions before 1.2 are deprecated and vulnerable"))
+	} else {
+		checks = append(checks, newCheck(checkTLSProtocol, analysis.Version, models.VerdictPass,
+			"A current TLS version was negotiated"))
+	}
+
+	switch {
+	case analysis.SelfSigned:
+		checks = append(checks, newCheck(checkTLSCertificate, analysis.Chain[0].Issuer, models.VerdictFail,
+			"The certificate is self-signed; browsers will not trust it"))
+	case !analysis.Trusted:
+		checks = append(checks, newCheck(checkTLSCertificate, analysis.Chain[0].Issuer, models.VerdictFail,
+			"The certificate chain is not trusted: "+analysis.VerificationError))
+	default:
+		checks = append(checks, newCheck(checkTLSCertificate, analysis.Chain[0].Issuer, models.VerdictPass,
+			"The certificate chains to a trusted root"))
+	}
+
+	if analysis.HostnameMismatch {
+		checks = append(checks, newCheck(checkTLSHostname, host, models.VerdictFail,
+			"The certificate is not valid for "+host))
+	} else {
+		checks = append(checks, newCheck(checkTLSHostname, host, models.VerdictPass,
+			"The certificate is valid for "+host))
+	}
+
+	expiry := analysis.Chain[0].NotAfter.UTC().Format(time.RFC3339)
+	switch {
+	case analysis.Expired:
+		checks = append(checks, newCheck(checkTLSExpiry, expiry, models.VerdictFail,
+			"The certificate is expired or not yet valid"))
+	case analysis.DaysUntilExpiry < certExpiryWarningDays:
+		checks = append(checks, newCheck(checkTLSExpiry, expiry, models.VerdictWarn,
+			fmt.Sprintf("The certificate expires in %d days", analysis.DaysUntilExpiry)))
+	default:
+		checks = append(checks, newCheck(checkTLSExpiry, expiry, models.VerdictPass,
+			fmt.Sprintf("The certificate is valid for %d more days", analysis.DaysUntilExpiry)))
+	}
+
+	return checks
+}
+
+// certificateInfo describes a certificate of the chain
+func certificateInfo(cert *x509.Certificate) models.CertificateInfo {
+	info := models.CertificateInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		SANs:      []string{},
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		IsCA:      cert.IsCA,
+	}
+
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType, info.KeySize = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType, info.KeySize = "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		info.KeyType, info.KeySize = "Ed25519", 256
+	default:
+		info.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+
+	return info
+}
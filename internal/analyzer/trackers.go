@@ -0,0 +1,298 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// bundledTrackers is the tracker signature database shipped with the analyzer
+//
+//go:embed trackers.json
+var bundledTrackers []byte
+
+// maxEvidenceLength bounds the matched inline code recorded as evidence
+const maxEvidenceLength = 100
+
+// trackerCategories lists the categories signatures may use, in report order
+var trackerCategories = []string{
+	models.TrackerAnalytics,
+	models.TrackerAdvertising,
+	models.TrackerSocial,
+	models.TrackerSessionReplay,
+}
+
+// trackerSignature identifies a tracker, analytics vendor or consent
+// management platform. Domains match the hosts of loaded resources and their
+// subdomains, optionally followed by a path prefix such as facebook.com/tr.
+// Patterns are regular expressions matching resource URLs and inline
+// scripts, cookies ones matching cookie names. Markers are element IDs
+// (#id) or classes (.class). Consent-exempt trackers, such as cookieless
+// analytics, may run before consent.
+type trackerSignature struct {
+	Name          string   `json:"name"`
+	Vendor        string   `json:"vendor"`
+	Category      string   `json:"category"`
+	Domains       []string `json:"domains"`
+	Patterns      []string `json:"patterns"`
+	Cookies       []string `json:"cookies"`
+	Markers       []string `json:"markers"`
+	ConsentExempt bool     `json:"consentExempt"`
+
+	patterns []*regexp.Regexp
+	cookies  []*regexp.Regexp
+}
+
+// loadTrackerSignatures parses the bundled signatures and, if path is set,
+// the user's signatures from it. User signatures replace bundled ones of the
+// same name and add the others. The digest identifies the user's file and
+// is empty without one.
+func loadTrackerSignatures(path string) (signatures []*trackerSignature, digest string, err error) {
+	signatures, err = parseTrackerSignatures(bundledTrackers)
+	if err != nil {
+		return nil, "", fmt.Errorf("bundled tracker signatures: %w", err)
+	}
+	if path == "" {
+		return signatures, "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	custom, err := parseTrackerSignatures(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	for _, signature := range custom {
+		i := slices.IndexFunc(signatures, func(s *trackerSignature) bool { return s.Name == signature.Name })
+		if i >= 0 {
+			signatures[i] = signature
+		} else {
+			signatures = append(signatures, signature)
+		}
+	}
+	sum := sha256.Sum256(data)
+	return signatures, hex.EncodeToString(sum[:]), nil
+}
+
+// parseTrackerSignatures decodes a signature database and compiles its
+// regular expressions
+func parseTrackerSignatures(data []byte) ([]*trackerSignature, error) {
+	var db struct {
+		Trackers []*trackerSignature `json:"trackers"`
+	}
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, err
+	}
+
+	compile := func(name string, exprs []string) ([]*regexp.Regexp, error) {
+		compiled := make([]*regexp.Regexp, 0, len(exprs))
+		for _, expr := range exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			compiled = append(compiled, re)
+		}
+		return compiled, nil
+	}
+
+	for _, signature := range db.Trackers {
+		if signature.Name == "" {
+			return nil, errors.New("tracker signature without a name")
+		}
+		if !slices.Contains(trackerCategories, signature.Category) && signature.Category != models.TrackerConsent {
+			return nil, fmt.Errorf("%s: unknown category %q", signature.Name, signature.Category)
+		}
+		for i, entry := range signature.Domains {
+			// Hosts are case-insensitive, paths are not
+			domain, path, hasPath := strings.Cut(entry, "/")
+			signature.Domains[i] = strings.ToLower(domain)
+			if hasPath {
+				signature.Domains[i] += "/" + path
+			}
+		}
+
+		var err error
+		if signature.patterns, err = compile(signature.Name, signature.Patterns); err != nil {
+			return nil, err
+		}
+		if signature.cookies, err = compile(signature.Name, signature.Cookies); err != nil {
+			return nil, err
+		}
+	}
+	return db.Trackers, nil
+}
+
+// matchesURL reports whether a resource URL belongs to the tracker
+func (s *trackerSignature) matchesURL(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, entry := range s.Domains {
+		domain, path, hasPath := strings.Cut(entry, "/")
+		if (host == domain || strings.HasSuffix(host, "."+domain)) &&
+			(!hasPath || strings.HasPrefix(u.RequestURI(), "/"+path)) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(s.patterns, func(re *regexp.Regexp) bool { return re.MatchString(u.String()) })
+}
+
+// matchesMarker returns the marker of the signature an element carries, or ""
+func (s *trackerSignature) matchesMarker(n *html.Node) string {
+	for _, marker := range s.Markers {
+		switch {
+		case strings.HasPrefix(marker, "#") && attrValue(n, "id") == marker[1:]:
+			return marker
+		case strings.HasPrefix(marker, ".") && slices.Contains(strings.Fields(attrValue(n, "class")), marker[1:]):
+			return marker
+		}
+	}
+	return ""
+}
+
+// detectTrackers reports the known trackers whose scripts, pixels or frames
+// a page loads, whose code it inlines or whose cookies it sets, and checks
+// which of them do so before any consent interaction
+func (a *Analyzer) detectTrackers(doc *html.Node, pageURL *url.URL, cookies []models.CookieRecord) models.PrivacyAnalysis {
+	matches := map[*trackerSignature]*models.TrackerMatch{}
+	var found []*trackerSignature
+	record := func(signature *trackerSignature, evidence models.TrackerEvidence) {
+		match := matches[signature]
+		if match == nil {
+			match = &models.TrackerMatch{
+				Name:     signature.Name,
+				Vendor:   signature.Vendor,
+				Category: signature.Category,
+				Evidence: []models.TrackerEvidence{},
+			}
+			matches[signature] = match
+			found = append(found, signature)
+		}
+		if !slices.Contains(match.Evidence, evidence) {
+			match.Evidence = append(match.Evidence, evidence)
+		}
+	}
+
+	base := documentBase(doc, pageURL)
+	resource := func(kind, ref string, active bool) {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		for _, signature := range a.trackers {
+			if signature.matchesURL(u) {
+				record(signature, models.TrackerEvidence{Type: kind, Value: u.String(), BeforeConsent: active})
+			}
+		}
+	}
+	inline := func(code string, active bool) {
+		for _, signature := range a.trackers {
+			for _, re := range signature.patterns {
+				if m := re.FindString(code); m != "" {
+					if len(m) > maxEvidenceLength {
+						m = m[:maxEvidenceLength]
+					}
+					record(signature, models.TrackerEvidence{Type: models.EvidenceInline, Value: m, BeforeConsent: active})
+					break
+				}
+			}
+		}
+	}
+
+	// Resources inside <noscript> only load when scripting is disabled
+	var walk func(n *html.Node, noscript bool)
+	walk = func(n *html.Node, noscript bool) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				// Consent managers block scripts by giving them a non-script type
+				executes := jsTypes[strings.ToLower(strings.TrimSpace(attrValue(n, "type")))]
+				if src, ok := attr(n, "src"); ok {
+					resource(models.EvidenceScript, src, executes)
+				} else {
+					inline(nodeText(n), executes)
+				}
+			case "img":
+				if src, ok := attr(n, "src"); ok {
+					resource(models.EvidencePixel, src, !noscript)
+				}
+			case "iframe":
+				if src, ok := attr(n, "src"); ok {
+					resource(models.EvidenceIframe, src, !noscript)
+				}
+			case "noscript":
+				// Fallback pixels are only parsed as markup without scripting
+				context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+				if nodes, err := html.ParseFragment(strings.NewReader(nodeText(n)), context); err == nil {
+					for _, node := range nodes {
+						walk(node, true)
+					}
+				}
+			}
+			for _, signature := range a.trackers {
+				if marker := signature.matchesMarker(n); marker != "" {
+					record(signature, models.TrackerEvidence{Type: models.EvidenceMarker, Value: marker})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, noscript)
+		}
+	}
+	walk(doc, false)
+
+	// Cookies set by the page response precede any interaction
+	for _, cookie := range cookies {
+		for _, signature := range a.trackers {
+			if slices.ContainsFunc(signature.cookies, func(re *regexp.Regexp) bool { return re.MatchString(cookie.Name) }) {
+				record(signature, models.TrackerEvidence{Type: models.EvidenceCookie, Value: cookie.Name, BeforeConsent: true})
+			}
+		}
+	}
+
+	analysis := models.PrivacyAnalysis{
+		Trackers:   make([]models.TrackerMatch, 0, len(found)),
+		Categories: make(map[string]int, len(trackerCategories)),
+	}
+	for _, category := range trackerCategories {
+		analysis.Categories[category] = 0
+	}
+	var platforms []models.TrackerMatch
+	exempt := map[string]bool{}
+	for _, signature := range found {
+		if signature.Category == models.TrackerConsent {
+			platforms = append(platforms, *matches[signature])
+			continue
+		}
+		analysis.Trackers = append(analysis.Trackers, *matches[signature])
+		analysis.Categories[signature.Category]++
+		exempt[signature.Name] = signature.ConsentExempt
+	}
+	analysis.Consent = checkConsent(platforms, hasTCFStub(doc), analysis.Trackers, exempt)
+
+	return analysis
+}
+
+// nodeText concatenates the text children of an element
+func nodeText(n *html.Node) string {
+	var text strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			text.WriteString(c.Data)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/middleware"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// createAPIKeyHandler handles requests to create a personal API key
+func (s *Server) createAPIKeyHandler(c *gin.Context) {
+	var req models.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid request",
+				Error:      "unknown scope: " + scope,
+			})
+			return
+		}
+	}
+
+	// Only admins may hand out admin access
+	if slices.Contains(req.Scopes, models.ScopeAdmin) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Message:    "Only admins can create keys with the admin scope",
+		})
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      "expires_at must be in the future",
+		})
+		return
+	}
+
+	// Admin keys expire within MaxAdminAPIKeyLifetime, by default at its end
+	if slices.Contains(req.Scopes, models.ScopeAdmin) {
+		maxExpiry := time.Now().Add(models.MaxAdminAPIKeyLifetime)
+		if req.ExpiresAt == nil {
+			req.ExpiresAt = &maxExpiry
+		} else if req.ExpiresAt.After(maxExpiry) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid request",
+				Error:      "keys with the admin scope must expire within " + models.MaxAdminAPIKeyLifetime.String(),
+			})
+			return
+		}
+	}
+
+	key, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		s.logger.Error("Failed to generate API key", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to create API key",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	userInfo, _ := c.Get("userInfo")
+	ui := userInfo.(*middleware.UserInfo)
+
+	apiKey := &models.APIKey{
+		UserID: ui.Sub,
+		Name:   req.Name,
+		Prefix: prefix,
+		Hash:   hash,
+		Scopes: slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		Owner: models.APIKeyOwner{
+			Username:   ui.PreferredUsername,
+			Email:      ui.Email,
+			Name:       ui.Name,
+			GivenName:  ui.GivenName,
+			FamilyName: ui.FamilyName,
+			Roles:      ui.Roles,
+		},
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	ctx := c.Request.Context()
+	if err := s.repo.SaveAPIKey(ctx, apiKey); err != nil {
+		s.logger.Error("Failed to save API key", "user", ui.Sub, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to create API key",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Created API key", "user", ui.Sub, "key", prefix, "scopes", apiKey.Scopes)
+	middleware.SetAuditTarget(c, "api_key", apiKey.ID.Hex())
+	middleware.SetAuditDetail(c, strings.Join(apiKey.Scopes, " "))
+
+	// The plaintext key is only ever returned here
+	c.JSON(http.StatusCreated, models.CreatedAPIKey{
+		APIKey: apiKey,
+		Key:    key,
+	})
+}
+
+// listAPIKeysHandler handles requests to list the current user's API keys
+func (s *Server) listAPIKeysHandler(c *gin.Context) {
+	userID := getUserID(c)
+
+	ctx := c.Request.Context()
+	keys, err := s.repo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list API keys", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to list API keys",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIKeyListResponse{
+		Count:   len(keys),
+		APIKeys: keys,
+	})
+}
+
+// revokeAPIKeyHandler handles requests to revoke one of the current user's API keys
+func (s *Server) revokeAPIKeyHandler(c *gin.Context) {
+	id := c.Param("id")
+	userID := getUserID(c)
+
+	ctx := c.Request.Context()
+	deleted, err := s.repo.DeleteAPIKey(ctx, userID, id)
+	if err != nil {
+		s.logger.Error("Failed to revoke API key", "id", id, "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to revoke API key",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Message:    "API key not found",
+		})
+		return
+	}
+
+	s.logger.Info("Revoked API key", "id", id, "user", userID)
+	c.JSON(http.StatusOK, models.RevokeAPIKeyResponse{
+		ID:      id,
+		Revoked: true,
+	})
+}
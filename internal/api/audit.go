@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/models"
+	"webPageAnalyzerGO/internal/repository"
+)
+
+// auditCSVHeader lists the columns of a CSV audit export
+var auditCSVHeader = []string{
+	"id", "created_at", "actor_id", "api_key_id", "action", "target_type", "target_id",
+	"detail", "method", "path", "status", "outcome", "ip", "user_agent",
+}
+
+// listAuditEventsHandler handles admin requests to query the audit log
+func (s *Server) listAuditEventsHandler(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid query parameters",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	// Include events still buffered in memory
+	ctx := c.Request.Context()
+	if err := s.auditLog.Flush(ctx); err != nil {
+		s.logger.Warn("Failed to flush audit log", "error", err)
+	}
+
+	page, err := s.repo.ListAuditEvents(ctx, query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid query parameters",
+				Error:      err.Error(),
+			})
+			return
+		}
+
+		s.logger.Error("Failed to query audit log", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to query audit log",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuditEventListResponse{
+		Count:      len(page.Events),
+		Events:     page.Events,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// exportAuditEventsHandler handles admin requests to export every matching
+// audit event as CSV (the default) or JSON lines
+func (s *Server) exportAuditEventsHandler(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err == nil && query.Cursor != "" {
+		err = errors.New("exports do not take a cursor")
+	}
+	format := c.DefaultQuery("format", "csv")
+	if err == nil && format != "csv" && format != "jsonl" {
+		err = fmt.Errorf("invalid format: %s", format)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid query parameters",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.auditLog.Flush(ctx); err != nil {
+		s.logger.Warn("Failed to flush audit log", "error", err)
+	}
+
+	contentType := "text/csv"
+	if format == "jsonl" {
+		contentType = "application/x-ndjson"
+	}
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	if format == "csv" {
+		csvWriter.Write(auditCSVHeader)
+	}
+
+	// Page through the log so the export never holds it in memory
+	query.Limit = repository.MaxListLimit
+	for {
+		page, err := s.repo.ListAuditEvents(ctx, query)
+		if err != nil {
+			// Headers are already sent; the truncated export is all we can do
+			s.logger.Error("Failed to export audit log", "error", err)
+			break
+		}
+
+		for _, e := range page.Events {
+			if format == "csv" {
+				csvWriter.Write([]string{
+					e.ID.Hex(), e.CreatedAt.UTC().Format(time.RFC3339Nano), csvCell(e.ActorID), csvCell(e.APIKeyID),
+					e.Action, csvCell(e.TargetType), csvCell(e.TargetID), csvCell(e.Detail), e.Method, csvCell(e.Path),
+					strconv.Itoa(e.Status), e.Outcome, csvCell(e.IP), csvCell(e.UserAgent),
+				})
+			} else {
+				encoder.Encode(e)
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	csvWriter.Flush()
+}
+
+// csvCell neutralises values that spreadsheets would evaluate as formulas
+// by prefixing them with a quote
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// parseAuditQuery reads pagination and filter parameters of an audit log request
+func parseAuditQuery(c *gin.Context) (models.AuditQuery, error) {
+	query := models.AuditQuery{
+		Limit:  repository.DefaultListLimit,
+		Cursor: c.Query("cursor"),
+		Filter: models.AuditFilter{
+			ActorID:    c.Query("actor_id"),
+			Action:     c.Query("action"),
+			TargetType: c.Query("target_type"),
+			TargetID:   c.Query("target_id"),
+			Outcome:    c.Query("outcome"),
+		},
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return query, fmt.Errorf("invalid limit: %s", limitParam)
+		}
+		query.Limit = min(limit, repository.MaxListLimit)
+	}
+
+	var err error
+	if query.Filter.From, err = parseTimeParam(c, "from"); err != nil {
+		return query, err
+	}
+	if query.Filter.To, err = parseTimeParam(c, "to"); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/authz"
+	"webPageAnalyzerGO/internal/middleware"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// subject returns the authorization subject of the request
+func subject(c *gin.Context) authz.Subject {
+	userInfo, exists := c.Get("userInfo")
+	if !exists {
+		return authz.Subject{}
+	}
+
+	ui, ok := userInfo.(*middleware.UserInfo)
+	if !ok {
+		return authz.Subject{}
+	}
+
+	sub := authz.Subject{
+		UserID: ui.Sub,
+		Admin:  ui.HasRole("admin"),
+	}
+	if ui.APIKeyID != "" {
+		sub.Scopes = ui.Scopes
+	}
+	return sub
+}
+
+// authorize checks the policy for an action on a resource and writes an
+// error response if the caller is not allowed
+func (s *Server) authorize(c *gin.Context, action authz.Action, res authz.Resource) bool {
+	err := s.policy.Authorize(c.Request.Context(), subject(c), action, res)
+	if err == nil {
+		return true
+	}
+
+	s.writeAuthzError(c, err, action, res.Kind)
+	return false
+}
+
+// writeAuthzError writes the response for a failed authorization
+func (s *Server) writeAuthzError(c *gin.Context, err error, action authz.Action, kind authz.Kind) {
+	switch {
+	case errors.Is(err, authz.ErrUnauthenticated):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "Unauthorized",
+			Error:      err.Error(),
+		})
+	case errors.Is(err, authz.ErrNotFound):
+		name := string(kind)
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Message:    strings.ToUpper(name[:1]) + name[1:] + " not found",
+		})
+	case errors.Is(err, authz.ErrForbidden):
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Message:    "You don't have permission to " + string(action) + " this " + string(kind),
+		})
+	default:
+		s.logger.Error("Failed to check permissions", "action", action, "kind", kind, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to check permissions",
+			Error:      err.Error(),
+		})
+	}
+}
+
+// authorizedAnalysis loads an analysis and checks that the caller may
+// perform the action on it, writing an error response if not
+func (s *Server) authorizedAnalysis(c *gin.Context, id string, action authz.Action) (*models.AnalysisResult, bool) {
+	analysis, err := s.repo.GetAnalysis(c.Request.Context(), id)
+	if err != nil {
+		s.logger.Error("Failed to get analysis", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to get analysis",
+			Error:      err.Error(),
+		})
+		return nil, false
+	}
+
+	if analysis == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Message:    "Analysis not found",
+		})
+		return nil, false
+	}
+
+	if !s.authorize(c, action, authz.Analysis(analysis)) {
+		return nil, false
+	}
+
+	return analysis, true
+}
+
+// analysisScope restricts a listing or search to what the caller may see:
+// the project given by the project_id parameter, or otherwise the caller's
+// personal analyses. Admins may narrow down to a user with user_id.
+func (s *Server) analysisScope(c *gin.Context) (models.AnalysisFilter, bool) {
+	scope, err := s.policy.AnalysisScope(c.Request.Context(), subject(c), c.Query("project_id"), c.Query("user_id"))
+	if err != nil {
+		s.writeAuthzError(c, err, authz.ActionRead, authz.KindProject)
+		return scope, false
+	}
+	return scope, true
+}
@@ -6,13 +6,15 @@ import (
 	"net/http"
 	"net/url"
 	_ "regexp"
+	"strconv"
 	_ "strings"
 	"time"
 	"webPageAnalyzerGO/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	_ "webPageAnalyzerGO/internal/analyzer"
+	"webPageAnalyzerGO/internal/analyzer"
+	"webPageAnalyzerGO/internal/authz"
 	"webPageAnalyzerGO/internal/models"
 )
 
@@ -20,70 +22,68 @@ import (
 func (s *Server) deepAnalysisHandler(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code": http.StatusBadRequest,
-			"message":     "Missing analysis ID",
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Missing analysis ID",
 		})
 		return
 	}
 
-	// Get analysis from database
-	ctx := c.Request.Context()
-	analysis, err := s.repo.GetAnalysis(ctx, id)
-	if err != nil {
-		s.logger.Error("Failed to get analysis", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to get analysis",
-			"error":       err.Error(),
-		})
+	// Reading a stored deep analysis needs read access to the analysis
+	analysis, ok := s.authorizedAnalysis(c, id, authz.ActionRead)
+	if !ok {
 		return
 	}
 
-	if analysis == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status_code": http.StatusNotFound,
-			"message":     "Analysis not found",
-		})
-		return
+	// Decide how old a stored deep analysis may be before it is re-run
+	maxAge := s.config.Analyzer.DeepAnalysisMaxAge
+	if maxAgeParam := c.Query("max_age"); maxAgeParam != "" {
+		var err error
+		maxAge, err = time.ParseDuration(maxAgeParam)
+		if err != nil || maxAge < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid query parameters",
+				Error:      "invalid max_age: " + maxAgeParam,
+			})
+			return
+		}
 	}
-
-	// Check permissions if the user is not an admin
-	/*if !isAdmin(c) && analysis.UserID != getUserID(c) {
-		c.JSON(http.StatusForbidden, gin.H{
-			"status_code": http.StatusForbidden,
-			"message":     "You don't have permission to access this analysis",
-		})
-		return
-	}*/
+	force := c.Query("force") == "true"
 
 	// Check if deep analysis exists
+	ctx := c.Request.Context()
 	deepAnalysis, err := s.repo.GetDeepAnalysis(ctx, id)
 	if err != nil {
 		s.logger.Error("Failed to check for deep analysis", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to check for deep analysis",
-			"error":       err.Error(),
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to check for deep analysis",
+			Error:      err.Error(),
 		})
 		return
 	}
 
-	// If deep analysis exists and is recent (less than 1 hour old), return it
-	if deepAnalysis != nil && time.Since(deepAnalysis.CreatedAt) < time.Hour {
+	// If the latest deep analysis is still fresh and used the current
+	// detection rules, return it
+	if !force && deepAnalysis != nil && time.Since(deepAnalysis.CreatedAt) < maxAge &&
+		deepAnalysis.RulesVersion == s.analyzer.RulesVersion() {
 		c.JSON(http.StatusOK, deepAnalysis)
 		return
 	}
 
+	// Running a new deep analysis fetches the page again
+	middleware.SetAuditAction(c, models.AuditDeepAnalyze)
+	if !s.authorize(c, authz.ActionAnalyze, authz.Analysis(analysis)) {
+		return
+	}
+
 	// Perform deep analysis
 	deepAnalysisResult, err := s.performDeepAnalysis(ctx, analysis)
 	if err != nil {
 		s.logger.Error("Failed to perform deep analysis", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to perform deep analysis",
-			"error":       err.Error(),
-		})
+		resp := analyzerErrorResponse(err, "Failed to perform deep analysis")
+		c.JSON(resp.StatusCode, resp)
 		return
 	}
 
@@ -91,7 +91,10 @@ func (s *Server) deepAnalysisHandler(c *gin.Context) {
 	deepAnalysisResult.ID = primitive.NewObjectID()
 	deepAnalysisResult.AnalysisID = analysis.ID
 	deepAnalysisResult.CreatedAt = time.Now()
+	deepAnalysisResult.AnalyzerVersion = analyzer.Version
+	deepAnalysisResult.RulesVersion = s.analyzer.RulesVersion()
 
+	// Every run is stored as a new version; earlier results stay available
 	if err := s.repo.SaveDeepAnalysis(ctx, deepAnalysisResult); err != nil {
 		s.logger.Error("Failed to save deep analysis", "id", id, "error", err)
 		// Continue anyway, just log the error
@@ -101,6 +104,72 @@ func (s *Server) deepAnalysisHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, deepAnalysisResult)
 }
 
+// deepAnalysisVersionsHandler lists the stored deep analysis versions of an analysis
+func (s *Server) deepAnalysisVersionsHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := s.authorizedAnalysis(c, id, authz.ActionRead); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	versions, err := s.repo.ListDeepAnalysisVersions(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list deep analysis versions", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to list deep analysis versions",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DeepAnalysisVersionsResponse{
+		AnalysisID: id,
+		Count:      len(versions),
+		Versions:   versions,
+	})
+}
+
+// deepAnalysisVersionHandler returns a single stored deep analysis version
+func (s *Server) deepAnalysisVersionHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid deep analysis version",
+		})
+		return
+	}
+
+	if _, ok := s.authorizedAnalysis(c, id, authz.ActionRead); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	deepAnalysis, err := s.repo.GetDeepAnalysisVersion(ctx, id, version)
+	if err != nil {
+		s.logger.Error("Failed to get deep analysis version", "id", id, "version", version, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to get deep analysis version",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	if deepAnalysis == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Message:    "Deep analysis version not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, deepAnalysis)
+}
+
 // performDeepAnalysis performs a deep analysis of a web page
 func (s *Server) performDeepAnalysis(ctx context.Context, analysis *models.AnalysisResult) (*models.DeepAnalysisResult, error) {
 	// Create timeout context
@@ -153,6 +222,14 @@ func (s *Server) performDeepAnalysis(ctx context.Context, analysis *models.Analy
 			HTTPS:         isHTTPS(analysis.URL),
 			CSPHeaders:    page.Security.CSPHeaders,
 			XSSProtection: page.Security.XSSProtection,
+			Headers:       page.Security.Headers,
+			CSP:           page.Security.CSP,
+			TLS:           page.Security.TLS,
+			MixedContent:  page.Security.MixedContent,
+			Forms:         page.Security.Forms,
+			Dependencies:  page.Security.Dependencies,
+			Score:         page.Security.Score,
+			Grade:         page.Security.Grade,
 		},
 		Mobile: models.MobileAnalysis{
 			Viewport:         page.Mobile.HasViewport,
@@ -165,10 +242,11 @@ func (s *Server) performDeepAnalysis(ctx context.Context, analysis *models.Analy
 			SocialLinks:  page.Social.SocialLinksCount,
 		},
 		Technology: models.TechnologyAnalysis{
-			Server:      page.Technology.Server,
-			CMS:         page.Technology.CMS,
-			Frameworks:  page.Technology.Frameworks,
-			Advertising: page.Technology.Advertising,
+			Server:       page.Technology.Server,
+			CMS:          page.Technology.CMS,
+			Frameworks:   page.Technology.Frameworks,
+			Advertising:  page.Technology.Advertising,
+			Technologies: page.Technology.Technologies,
 		},
 		Media: models.MediaAnalysis{
 			ImagesCount: page.Media.ImagesCount,
@@ -187,6 +265,7 @@ func (s *Server) performDeepAnalysis(ctx context.Context, analysis *models.Analy
 			ThirdParty: page.Cookies.ThirdParty,
 			HasConsent: page.Cookies.HasConsent,
 			MaxAgeDays: page.Cookies.MaxAgeDays,
+			Cookies:    page.Cookies.Records,
 		},
 		Links: models.LinkAnalysis{
 			AnchorText:  page.Links.AnchorText,
@@ -194,6 +273,7 @@ func (s *Server) performDeepAnalysis(ctx context.Context, analysis *models.Analy
 			BrokenLinks: page.Links.BrokenLinks,
 			MaxDepth:    page.Links.MaxDepth,
 		},
+		Privacy: page.Privacy,
 	}
 
 	return result, nil
@@ -213,13 +293,7 @@ func isAdmin(c *gin.Context) bool {
 		return false
 	}
 
-	for _, role := range ui.RealmAccess.Roles {
-		if role == "admin" {
-			return true
-		}
-	}
-
-	return false
+	return ui.HasRole("admin")
 }
 
 // getUserID gets the user ID from the context
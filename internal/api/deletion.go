@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/authz"
+	"webPageAnalyzerGO/internal/middleware"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// deleteAnalysisHandler handles requests to delete an analysis and its deep analyses
+func (s *Server) deleteAnalysisHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	// Only the owner, a project analyst or an admin may delete; anonymous
+	// analyses are admin-only
+	if _, ok := s.authorizedAnalysis(c, id, authz.ActionDelete); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.repo.DeleteAnalysis(ctx, id); err != nil {
+		s.logger.Error("Failed to delete analysis", "id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to delete analysis",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Deleted analysis", "id", id, "user", getUserID(c))
+	c.JSON(http.StatusOK, models.DeleteResponse{
+		ID:      id,
+		Deleted: 1,
+	})
+}
+
+// deleteUserDataHandler handles requests to delete all data of the current
+// user, including their API keys. Analyses filed under a project belong to
+// its organization and stay, but no longer name the user. The quota plan and
+// usage are kept so that deleting data cannot reset quotas.
+func (s *Server) deleteUserDataHandler(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "Unauthorized",
+		})
+		return
+	}
+
+	middleware.SetAuditTarget(c, "user", userID)
+
+	ctx := c.Request.Context()
+	deleted, err := s.repo.DeleteAnalyses(ctx, models.AnalysisFilter{UserID: userID})
+	if err != nil {
+		s.logger.Error("Failed to delete user data", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to delete user data",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	disowned, err := s.repo.DisownProjectAnalyses(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to disown project analyses", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to delete user data",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	// API keys hold a copy of the user's profile
+	keys, err := s.repo.DeleteUserAPIKeys(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to delete user API keys", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to delete user data",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Deleted user data", "user", userID, "analyses", deleted, "project_analyses", disowned, "api_keys", keys)
+	c.JSON(http.StatusOK, models.DeleteResponse{
+		Deleted: deleted,
+	})
+}
+
+// bulkDeleteAnalysesHandler handles admin requests to delete analyses by filter
+func (s *Server) bulkDeleteAnalysesHandler(c *gin.Context) {
+	query, err := parseAnalysisQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid query parameters",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	// A user ID alone only matches personal analyses unless project
+	// analyses are explicitly included
+	filter := query.Filter
+	filter.UserID = c.Query("user_id")
+	filter.ProjectID = c.Query("project_id")
+	filter.AllProjects = c.Query("include_projects") == "true"
+
+	// Refuse to wipe everything unless explicitly asked to
+	if filter == (models.AnalysisFilter{AllProjects: filter.AllProjects}) && c.Query("all") != "true" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "At least one filter is required; pass all=true to delete every analysis",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	deleted, err := s.repo.DeleteAnalyses(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to delete analyses", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to delete analyses",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Bulk deleted analyses", "user", getUserID(c), "count", deleted)
+	c.JSON(http.StatusOK, models.DeleteResponse{
+		Deleted: deleted,
+	})
+}
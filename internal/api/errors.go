@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"webPageAnalyzerGO/internal/analyzer"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// analyzerErrors maps analyzer errors to their response status and code
+var analyzerErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{analyzer.ErrInvalidURL, http.StatusBadRequest, models.CodeInvalidURL},
+	{analyzer.ErrDNS, http.StatusBadGateway, models.CodeDNSFailure},
+	{analyzer.ErrConnectionRefused, http.StatusBadGateway, models.CodeConnectionRefused},
+	{analyzer.ErrTLS, http.StatusBadGateway, models.CodeTLSError},
+	{analyzer.ErrTimeout, http.StatusGatewayTimeout, models.CodeTimeout},
+	{analyzer.ErrNotHTML, http.StatusUnprocessableEntity, models.CodeNotHTML},
+	{analyzer.ErrTooLarge, http.StatusUnprocessableEntity, models.CodeTooLarge},
+	{analyzer.ErrUpstreamStatus, http.StatusBadGateway, models.CodeUpstreamStatus},
+}
+
+// analyzerErrorStatuses are the statuses of analyzer failures, for the
+// OpenAPI specification
+var analyzerErrorStatuses = []int{http.StatusUnprocessableEntity, http.StatusBadGateway, http.StatusGatewayTimeout}
+
+// analyzerErrorResponse builds the response for a failed page fetch or
+// analysis. Unclassified failures are reported as bad gateway.
+func analyzerErrorResponse(err error, message string) models.ErrorResponse {
+	resp := models.ErrorResponse{
+		StatusCode: http.StatusBadGateway,
+		Code:       models.CodeFetchFailed,
+		Message:    message,
+		Error:      err.Error(),
+	}
+	for _, e := range analyzerErrors {
+		if errors.Is(err, e.err) {
+			resp.StatusCode, resp.Code = e.status, e.code
+			break
+		}
+	}
+	return resp
+}
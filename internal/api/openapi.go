@@ -0,0 +1,407 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Authentication requirements of documented routes
+const (
+	authNone     = ""         // no credentials accepted
+	authOptional = "optional" // credentials attribute the request to a user
+	authRequired = "required" // a bearer token or API key is required
+	authAdmin    = "admin"    // the caller must have the admin role
+)
+
+// routeDoc describes a route for the OpenAPI specification. Request and
+// Response are zero values of the body types; the schemas are derived from
+// them, so the specification follows the models.
+type routeDoc struct {
+	Summary     string
+	Description string // longer explanation, if the summary is not enough
+	Tag         string
+	Scope       string       // API key scope required, if any
+	BearerOnly  bool         // API keys are rejected
+	Query       []queryParam // query parameters
+	Request     interface{}  // JSON request body
+	Response    interface{}  // JSON response body
+	Status      int          // success status; 200 if zero
+	Produces    []string     // additional response media types
+	Errors      []int        // route-specific error statuses
+}
+
+// queryParam describes a query parameter
+type queryParam struct {
+	Name        string
+	Type        string // JSON schema type; "string" if empty
+	Description string
+}
+
+// Query parameters shared by several routes
+var (
+	paginationParams = []queryParam{
+		{Name: "limit", Type: "integer", Description: "Maximum number of items to return"},
+		{Name: "cursor", Description: "Cursor returned as next_cursor by the previous page"},
+	}
+	analysisFilterParams = []queryParam{
+		{Name: "sort", Description: "Sort key, e.g. -created_at, url or title"},
+		{Name: "domain", Description: "Only analyses of this domain"},
+		{Name: "url_prefix", Description: "Only analyses whose URL starts with this prefix"},
+		{Name: "html_version", Description: "Only analyses of this HTML version"},
+		{Name: "from", Description: "Analyses created at or after this RFC 3339 time or date"},
+		{Name: "to", Description: "Analyses created before this RFC 3339 time or date"},
+		{Name: "has_login_form", Type: "boolean", Description: "Only analyses with or without a login form"},
+		{Name: "has_broken_links", Type: "boolean", Description: "Only analyses with or without broken links"},
+	}
+	scopeParams = []queryParam{
+		{Name: "project_id", Description: "List a project's analyses instead of personal ones"},
+		{Name: "user_id", Description: "Admins only: list another user's analyses"},
+	}
+	auditFilterParams = []queryParam{
+		{Name: "actor_id", Description: "Only events of this user"},
+		{Name: "action", Description: "Only events of this action"},
+		{Name: "target_type", Description: "Only events on this kind of resource"},
+		{Name: "target_id", Description: "Only events on this resource"},
+		{Name: "outcome", Description: "success, denied or failure"},
+		{Name: "from", Description: "Events at or after this RFC 3339 time or date"},
+		{Name: "to", Description: "Events before this RFC 3339 time or date"},
+	}
+)
+
+// apiRoutes registers routes on a group and documents them. Routes of
+// deprecated aliases are registered with a nil spec and not documented.
+// Guards such as authentication run before the handlers of every route but
+// after its audit handler, so that rejected requests are audited too.
+type apiRoutes struct {
+	group  *gin.RouterGroup
+	spec   *openAPISpec
+	auth   string
+	guards []gin.HandlerFunc
+}
+
+// Group returns a subgroup with the same documentation settings whose
+// routes are additionally guarded by handlers
+func (r apiRoutes) Group(path string, handlers ...gin.HandlerFunc) apiRoutes {
+	return apiRoutes{group: r.group.Group(path), spec: r.spec, auth: r.auth, guards: slices.Concat(r.guards, handlers)}
+}
+
+// Handle registers a route and adds it to the specification
+func (r apiRoutes) Handle(method, path string, doc routeDoc, handlers ...gin.HandlerFunc) {
+	r.HandleAudited(method, path, doc, nil, handlers...)
+}
+
+// HandleAudited registers a route whose requests are recorded by audit,
+// including those the guards reject, and adds it to the specification
+func (r apiRoutes) HandleAudited(method, path string, doc routeDoc, audit gin.HandlerFunc, handlers ...gin.HandlerFunc) {
+	var chain []gin.HandlerFunc
+	if audit != nil {
+		chain = append(chain, audit)
+	}
+	r.group.Handle(method, path, slices.Concat(chain, r.guards, handlers)...)
+	if r.spec != nil {
+		r.spec.addOperation(method, joinPaths(r.group.BasePath(), path), r.auth, doc)
+	}
+}
+
+// openAPISpec is an OpenAPI 3 document built while routes are registered
+type openAPISpec struct {
+	doc     map[string]interface{}
+	paths   map[string]map[string]interface{}
+	schemas map[string]interface{}
+}
+
+// pathParamPattern matches gin path parameters
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// newOpenAPISpec creates an empty specification
+func newOpenAPISpec(title, version string) *openAPISpec {
+	spec := &openAPISpec{
+		paths:   make(map[string]map[string]interface{}),
+		schemas: make(map[string]interface{}),
+	}
+	spec.doc = map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths": spec.paths,
+		"components": map[string]interface{}{
+			"schemas": spec.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+				"apiKeyAuth": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+	}
+	spec.schemaFor(reflect.TypeOf(models.ErrorResponse{}))
+	return spec
+}
+
+// MarshalJSON encodes the specification document
+func (s *openAPISpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.doc)
+}
+
+// addOperation documents a route
+func (s *openAPISpec) addOperation(method, path, auth string, doc routeDoc) {
+	op := map[string]interface{}{
+		"summary":     doc.Summary,
+		"operationId": operationID(method, path),
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if doc.Tag != "" {
+		op["tags"] = []string{doc.Tag}
+	}
+
+	var params []interface{}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range doc.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		params = append(params, map[string]interface{}{
+			"name":        q.Name,
+			"in":          "query",
+			"description": q.Description,
+			"schema":      map[string]interface{}{"type": typ},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if doc.Request != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": s.schemaFor(reflect.TypeOf(doc.Request))},
+			},
+		}
+	}
+
+	switch auth {
+	case authOptional:
+		op["security"] = []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKeyAuth": []string{}},
+		}
+	case authRequired, authAdmin:
+		security := []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+		if !doc.BearerOnly {
+			security = append(security, map[string]interface{}{"apiKeyAuth": []string{}})
+		}
+		op["security"] = security
+	}
+	if doc.Scope != "" && !doc.BearerOnly {
+		op["x-api-key-scope"] = doc.Scope
+	}
+	if auth == authAdmin {
+		op["x-required-role"] = "admin"
+	}
+
+	op["responses"] = s.responses(path, auth, doc)
+
+	openAPIPath := pathParamPattern.ReplaceAllString(path, "{$1}")
+	if s.paths[openAPIPath] == nil {
+		s.paths[openAPIPath] = make(map[string]interface{})
+	}
+	s.paths[openAPIPath][strings.ToLower(method)] = op
+}
+
+// responses documents the success response of a route and the errors it
+// can return
+func (s *openAPISpec) responses(path, auth string, doc routeDoc) map[string]interface{} {
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	content := make(map[string]interface{})
+	if doc.Response != nil {
+		content["application/json"] = map[string]interface{}{"schema": s.schemaFor(reflect.TypeOf(doc.Response))}
+	}
+	for _, mediaType := range doc.Produces {
+		content[mediaType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+	}
+	if len(content) > 0 {
+		success["content"] = content
+	}
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+
+	statuses := slices.Clone(doc.Errors)
+	statuses = append(statuses, http.StatusTooManyRequests, http.StatusInternalServerError)
+	if doc.Request != nil || len(doc.Query) > 0 {
+		statuses = append(statuses, http.StatusBadRequest)
+	}
+	if auth == authRequired || auth == authAdmin {
+		statuses = append(statuses, http.StatusUnauthorized)
+	}
+	if auth != authNone && (doc.Scope != "" || doc.BearerOnly || auth == authAdmin) {
+		statuses = append(statuses, http.StatusForbidden)
+	}
+	if pathParamPattern.MatchString(path) {
+		statuses = append(statuses, http.StatusNotFound)
+	}
+
+	errorSchema := map[string]interface{}{"$ref": "#/components/schemas/ErrorResponse"}
+	for _, code := range statuses {
+		responses[strconv.Itoa(code)] = map[string]interface{}{
+			"description": http.StatusText(code),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": errorSchema},
+			},
+		}
+	}
+	return responses
+}
+
+// Types with a fixed schema
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// schemaFor returns the JSON schema of a Go type. Named structs are added
+// to the components and referenced.
+func (s *openAPISpec) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case objectIDType:
+		return map[string]interface{}{"type": "string", "pattern": "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.objectSchema(t)
+		}
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := s.schemas[t.Name()]; !ok {
+			// Register before recursing so that recursive types terminate
+			s.schemas[t.Name()] = map[string]interface{}{}
+			s.schemas[t.Name()] = s.objectSchema(t)
+		}
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// objectSchema returns the schema of a struct's JSON encoding; embedded
+// structs without a JSON name are inlined like encoding/json does
+func (s *openAPISpec) objectSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+				collect(fieldType)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = s.schemaFor(field.Type)
+			if slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required") {
+				required = append(required, name)
+			}
+		}
+	}
+	collect(t)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// joinPaths joins a group base path and a relative route path like gin does
+func joinPaths(base, path string) string {
+	if path == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// operationID derives a stable operation ID from a route, e.g.
+// "get_analysis_id_deep" for GET /api/v1/analysis/:id/deep
+func operationID(method, path string) string {
+	path = strings.TrimPrefix(path, apiV1)
+	path = strings.NewReplacer(":", "", "/", "_", "-", "_").Replace(path)
+	return strings.ToLower(method) + strings.TrimSuffix(path, "_")
+}
+
+// openAPIHandler serves the OpenAPI specification of the current API version
+func (s *Server) openAPIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.openAPI)
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"webPageAnalyzerGO/internal/authz"
+	"webPageAnalyzerGO/internal/middleware"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// errLastOwner is returned when a change would leave an organization without an owner
+var errLastOwner = errors.New("an organization must keep at least one owner")
+
+// createOrganizationHandler handles requests to create an organization; the
+// creator becomes its first owner
+func (s *Server) createOrganizationHandler(c *gin.Context) {
+	var req models.OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	userID := getUserID(c)
+	org := &models.Organization{
+		Name:      req.Name,
+		CreatedBy: userID,
+	}
+
+	ctx := c.Request.Context()
+	if err := s.repo.SaveOrganization(ctx, org); err != nil {
+		s.logger.Error("Failed to save organization", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to create organization",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	owner := &models.Membership{
+		OrganizationID: org.ID,
+		UserID:         userID,
+		Role:           models.RoleOwner,
+	}
+	if err := s.repo.SaveMembership(ctx, owner); err != nil {
+		s.logger.Error("Failed to save organization owner", "org", org.ID.Hex(), "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to create organization",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Created organization", "org", org.ID.Hex(), "user", userID)
+	middleware.SetAuditTarget(c, "organization", org.ID.Hex())
+	middleware.SetAuditDetail(c, "create")
+	c.JSON(http.StatusCreated, models.UserOrganization{
+		Organization: org,
+		Role:         models.RoleOwner,
+	})
+}
+
+// listOrganizationsHandler handles requests to list the current user's organizations
+func (s *Server) listOrganizationsHandler(c *gin.Context) {
+	userID := getUserID(c)
+
+	ctx := c.Request.Context()
+	orgs, err := s.repo.ListUserOrganizations(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list organizations", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to list organizations",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.OrganizationListResponse{
+		Count:         len(orgs),
+		Organizations: orgs,
+	})
+}
+
+// listMembersHandler handles requests to list an organization's members
+func (s *Server) listMembersHandler(c *gin.Context) {
+	orgID := c.Param("org")
+	if !s.authorize(c, authz.ActionRead, authz.Organization(orgID)) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	members, err := s.repo.ListMemberships(ctx, orgID)
+	if err != nil {
+		s.logger.Error("Failed to list members", "org", orgID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to list members",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MemberListResponse{
+		Count:   len(members),
+		Members: members,
+	})
+}
+
+// putMemberHandler handles requests to add a member or change their role
+func (s *Server) putMemberHandler(c *gin.Context) {
+	orgID := c.Param("org")
+	userID := c.Param("user")
+	middleware.SetAuditTarget(c, "organization", orgID)
+	if !s.authorize(c, authz.ActionManage, authz.Organization(orgID)) {
+		return
+	}
+
+	var req models.MembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	if !models.ValidMemberRole(req.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      "unknown role: " + req.Role,
+		})
+		return
+	}
+
+	middleware.SetAuditDetail(c, "member "+userID+" role "+req.Role)
+
+	ctx := c.Request.Context()
+	if req.Role != models.RoleOwner {
+		if err := s.checkNotLastOwner(ctx, orgID, userID); err != nil {
+			s.writeMembershipError(c, orgID, userID, err)
+			return
+		}
+	}
+
+	membership := &models.Membership{
+		OrganizationID: mustObjectID(orgID),
+		UserID:         userID,
+		Role:           req.Role,
+	}
+	if err := s.repo.SaveMembership(ctx, membership); err != nil {
+		s.writeMembershipError(c, orgID, userID, err)
+		return
+	}
+
+	s.logger.Info("Saved organization member", "org", orgID, "member", userID, "role", req.Role, "user", getUserID(c))
+	c.JSON(http.StatusOK, membership)
+}
+
+// removeMemberHandler handles requests to remove a member from an
+// organization; members may always remove themselves
+func (s *Server) removeMemberHandler(c *gin.Context) {
+	orgID := c.Param("org")
+	userID := c.Param("user")
+
+	middleware.SetAuditTarget(c, "organization", orgID)
+	middleware.SetAuditDetail(c, "remove member "+userID)
+
+	action := authz.ActionManage
+	if userID == getUserID(c) {
+		action = authz.ActionRead
+	}
+	if !s.authorize(c, action, authz.Organization(orgID)) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.checkNotLastOwner(ctx, orgID, userID); err != nil {
+		s.writeMembershipError(c, orgID, userID, err)
+		return
+	}
+
+	deleted, err := s.repo.DeleteMembership(ctx, orgID, userID)
+	if err != nil {
+		s.writeMembershipError(c, orgID, userID, err)
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Message:    "Member not found",
+		})
+		return
+	}
+
+	s.logger.Info("Removed organization member", "org", orgID, "member", userID, "user", getUserID(c))
+	c.JSON(http.StatusOK, models.RemoveMemberResponse{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Removed:        true,
+	})
+}
+
+// checkNotLastOwner fails with errLastOwner if userID is the organization's only owner
+func (s *Server) checkNotLastOwner(ctx context.Context, orgID, userID string) error {
+	members, err := s.repo.ListMemberships(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	owners, isOwner := 0, false
+	for _, m := range members {
+		if m.Role == models.RoleOwner {
+			owners++
+			isOwner = isOwner || m.UserID == userID
+		}
+	}
+
+	if isOwner && owners == 1 {
+		return errLastOwner
+	}
+	return nil
+}
+
+// writeMembershipError writes the response for a failed membership change
+func (s *Server) writeMembershipError(c *gin.Context, orgID, userID string, err error) {
+	if errors.Is(err, errLastOwner) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			StatusCode: http.StatusConflict,
+			Message:    "Cannot remove the last owner",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Error("Failed to update organization member", "org", orgID, "member", userID, "error", err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		StatusCode: http.StatusInternalServerError,
+		Message:    "Failed to update organization member",
+		Error:      err.Error(),
+	})
+}
+
+// createProjectHandler handles requests to create a project in an organization
+func (s *Server) createProjectHandler(c *gin.Context) {
+	orgID := c.Param("org")
+	middleware.SetAuditTarget(c, "organization", orgID)
+	if !s.authorize(c, authz.ActionManage, authz.Organization(orgID)) {
+		return
+	}
+
+	var req models.ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	project := &models.Project{
+		OrganizationID: mustObjectID(orgID),
+		Name:           req.Name,
+		CreatedBy:      getUserID(c),
+	}
+
+	ctx := c.Request.Context()
+	if err := s.repo.SaveProject(ctx, project); err != nil {
+		s.logger.Error("Failed to save project", "org", orgID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to create project",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Created project", "org", orgID, "project", project.ID.Hex(), "user", getUserID(c))
+	middleware.SetAuditDetail(c, "create project "+project.ID.Hex())
+	c.JSON(http.StatusCreated, project)
+}
+
+// listProjectsHandler handles requests to list an organization's projects
+func (s *Server) listProjectsHandler(c *gin.Context) {
+	orgID := c.Param("org")
+	if !s.authorize(c, authz.ActionRead, authz.Organization(orgID)) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	projects, err := s.repo.ListProjects(ctx, orgID)
+	if err != nil {
+		s.logger.Error("Failed to list projects", "org", orgID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to list projects",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ProjectListResponse{
+		Count:    len(projects),
+		Projects: projects,
+	})
+}
+
+// mustObjectID converts an ID that has already been validated
+func mustObjectID(id string) primitive.ObjectID {
+	objectID, _ := primitive.ObjectIDFromHex(id)
+	return objectID
+}
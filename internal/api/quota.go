@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/middleware"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// getQuotaHandler handles requests for the current user's quota plan and usage
+func (s *Server) getQuotaHandler(c *gin.Context) {
+	status, err := s.quotas.Status(c)
+	if err != nil {
+		s.logger.Error("Failed to get quota usage", "user", getUserID(c), "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to get quota usage",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// setUserPlanHandler handles admin requests to assign a user's quota plan
+func (s *Server) setUserPlanHandler(c *gin.Context) {
+	userID := c.Param("user")
+	middleware.SetAuditTarget(c, "user", userID)
+
+	var req models.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	if !s.quotas.HasPlan(req.Plan) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      "unknown plan: " + req.Plan,
+		})
+		return
+	}
+
+	middleware.SetAuditDetail(c, "plan "+req.Plan)
+
+	ctx := c.Request.Context()
+	if err := s.repo.SetUserPlan(ctx, userID, req.Plan); err != nil {
+		s.logger.Error("Failed to set user plan", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to set user plan",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	s.logger.Info("Set user plan", "user", userID, "plan", req.Plan, "admin", getUserID(c))
+	c.JSON(http.StatusOK, models.UserPlanResponse{
+		UserID: userID,
+		Plan:   req.Plan,
+	})
+}
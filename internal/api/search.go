@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/models"
+	"webPageAnalyzerGO/internal/repository"
+)
+
+// searchHandler handles full-text search requests over analyzed pages
+func (s *Server) searchHandler(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Missing search query",
+		})
+		return
+	}
+
+	query := models.SearchQuery{
+		Query: q,
+		Limit: repository.DefaultListLimit,
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid query parameters",
+				Error:      "invalid limit: " + limitParam,
+			})
+			return
+		}
+		query.Limit = limit
+	}
+
+	// Regular users search their own analyses or a project's; admins search
+	// everything unless they narrow it down
+	scope, ok := s.analysisScope(c)
+	if !ok {
+		return
+	}
+	query.UserID, query.ProjectID = scope.UserID, scope.ProjectID
+
+	ctx := c.Request.Context()
+	hits, err := s.repo.SearchAnalyses(ctx, query)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptySearch) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid search query",
+				Error:      err.Error(),
+			})
+			return
+		}
+
+		s.logger.Error("Failed to search analyses", "query", q, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to search analyses",
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	if hits == nil {
+		hits = []*models.SearchHit{}
+	}
+
+	// Return results
+	c.JSON(http.StatusOK, models.SearchResponse{
+		Query:   q,
+		Count:   len(hits),
+		Results: hits,
+	})
+}
@@ -2,14 +2,20 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 	"webPageAnalyzerGO/internal/analyzer"
+	"webPageAnalyzerGO/internal/audit"
+	"webPageAnalyzerGO/internal/authz"
 	"webPageAnalyzerGO/internal/config"
 	"webPageAnalyzerGO/internal/middleware"
 	"webPageAnalyzerGO/internal/models"
@@ -22,13 +28,18 @@ type Server struct {
 	httpServer *http.Server
 	repo       repository.Repository
 	analyzer   *analyzer.Analyzer
-	auth       *middleware.KeycloakAuth
+	auth       *middleware.OIDCAuth
+	policy     *authz.Policy
+	auditLog   *audit.Logger
+	limiter    *middleware.RateLimiter
+	quotas     *middleware.Quotas
+	openAPI    []byte
 	logger     *slog.Logger
 	config     *config.Config
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, repo repository.Repository, logger *slog.Logger) *Server {
+func NewServer(cfg *config.Config, repo repository.Repository, logger *slog.Logger) (*Server, error) {
 	// Set Gin mode
 	if gin.Mode() == gin.DebugMode {
 		gin.SetMode(gin.DebugMode)
@@ -38,21 +49,48 @@ func NewServer(cfg *config.Config, repo repository.Repository, logger *slog.Logg
 
 	router := gin.New()
 
+	// Only trust X-Forwarded-For from the configured proxies, so that
+	// callers cannot pick the IP they are rate limited and audited by
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Error("Invalid trusted proxies, trusting none", "error", err)
+		router.SetTrustedProxies(nil)
+	}
+
 	// Add recovery middleware
 	router.Use(gin.Recovery())
 
 	// Add CORS middleware
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.APIKeyHeader},
+		ExposeHeaders: []string{
+			"Content-Length", "Retry-After",
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+			"X-Quota-Limit-Day", "X-Quota-Remaining-Day", "X-Quota-Reset-Day",
+			"X-Quota-Limit-Month", "X-Quota-Remaining-Month", "X-Quota-Reset-Month",
+			"Deprecation", "Link",
+		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	// Create Keycloak auth middleware
-	auth := middleware.NewKeycloakAuth(&cfg.Keycloak, logger)
+	// Create auth middleware for the configured identity provider
+	var auth *middleware.OIDCAuth
+	switch cfg.Auth.Provider {
+	case "oidc":
+		auth = middleware.NewOIDCAuth(&cfg.OIDC, logger)
+	default:
+		auth = middleware.NewKeycloakAuth(&cfg.Keycloak, logger)
+	}
+
+	// Accept personal API keys as an alternative to bearer tokens
+	auth.UseAPIKeys(repo)
+
+	pageAnalyzer, err := analyzer.New(cfg.Analyzer, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the server
 	s := &Server{
@@ -64,8 +102,12 @@ func NewServer(cfg *config.Config, repo repository.Repository, logger *slog.Logg
 			WriteTimeout: cfg.Server.WriteTimeout,
 		},
 		repo:     repo,
-		analyzer: analyzer.New(cfg.Analyzer, logger),
+		analyzer: pageAnalyzer,
 		auth:     auth,
+		policy:   authz.NewPolicy(repo),
+		auditLog: audit.NewLogger(repo, cfg.Audit, logger),
+		limiter:  middleware.NewRateLimiter(cfg.RateLimit),
+		quotas:   middleware.NewQuotas(cfg.RateLimit, repo, logger),
 		logger:   logger,
 		config:   cfg,
 	}
@@ -73,7 +115,7 @@ func NewServer(cfg *config.Config, repo repository.Repository, logger *slog.Logg
 	// Register routes
 	s.registerRoutes()
 
-	return s
+	return s, nil
 }
 
 // Start starts the HTTP server
@@ -81,73 +123,268 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server
+// Handler returns the HTTP handler serving the API routes
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Shutdown gracefully shuts down the server and writes pending audit events
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.auditLog.Close(ctx)
 }
 
+// apiV1 is the base path of the current API version
+const apiV1 = "/api/v1"
+
 // registerRoutes sets up all the routes for the server
 func (s *Server) registerRoutes() {
 	// Health check
 	s.router.GET("/health", s.healthHandler)
 
-	// Public API routes
-	public := s.router.Group("/api")
+	// Current API version, documented in its OpenAPI specification
+	spec := newOpenAPISpec("Web Page Analyzer API", "1.0.0")
+	s.registerAPIRoutes(s.router.Group(apiV1), spec)
+
+	// Unversioned paths predate /api/v1 and remain as deprecated aliases
+	s.registerAPIRoutes(s.router.Group("/api", middleware.Deprecated("/api", apiV1)), nil)
+
+	s.openAPI, _ = json.Marshal(spec)
+	s.router.GET(apiV1+"/openapi.json", s.openAPIHandler)
+}
+
+// registerAPIRoutes sets up the API routes under base and documents them
+// in spec if it is not nil
+func (s *Server) registerAPIRoutes(base *gin.RouterGroup, spec *openAPISpec) {
+	// API keys are limited to the scopes they were created with
+	analyzeScope := s.auth.RequireScope(models.ScopeAnalyze)
+	readScope := s.auth.RequireScope(models.ScopeRead)
+
+	// Security-relevant requests are recorded in the audit log
+	audited := func(action, targetType string) gin.HandlerFunc {
+		return middleware.Audit(s.auditLog, action, targetType)
+	}
+	// Listings record the filter they were queried with
+	auditedList := func(c *gin.Context) {
+		middleware.SetAuditDetail(c, c.Request.URL.RawQuery)
+	}
+
+	// Requests are rate limited per API key, user or client IP, and
+	// analyses additionally count towards the caller's plan quotas
+	rateLimit := s.limiter.Middleware()
+	quota := s.quotas.Middleware()
+
+	// Public API routes; credentials are optional but attribute the analysis
+	public := apiRoutes{group: base, spec: spec, auth: authOptional, guards: []gin.HandlerFunc{s.auth.OptionalAuthenticate(), rateLimit}}
 	{
 		// Analyze URL (public endpoint for demo purposes)
-		public.POST("/analyze", s.analyzeURLHandler)
+		public.HandleAudited(http.MethodPost, "/analyze", routeDoc{
+			Summary: "Analyze a web page", Tag: "analyses", Scope: models.ScopeAnalyze,
+			Request: models.AnalysisRequest{}, Response: models.AnalysisResult{},
+			Errors: append([]int{http.StatusForbidden, http.StatusNotFound}, analyzerErrorStatuses...),
+		}, audited(models.AuditAnalyze, "analysis"), analyzeScope, quota, s.analyzeURLHandler)
 	}
 
 	// Protected API routes
-	protected := s.router.Group("/api")
-	protected.Use(s.auth.Authenticate())
+	protected := apiRoutes{group: base, spec: spec, auth: authRequired, guards: []gin.HandlerFunc{s.auth.Authenticate(), rateLimit}}
 	{
 		// Get analysis by ID
-		protected.GET("/analysis/:id", s.getAnalysisHandler)
-
-		// Get deep analysis
-		protected.GET("/analysis/:id/deep", s.deepAnalysisHandler)
+		protected.HandleAudited(http.MethodGet, "/analysis/:id", routeDoc{
+			Summary: "Get an analysis", Tag: "analyses", Scope: models.ScopeRead,
+			Response: models.AnalysisResult{},
+		}, audited(models.AuditView, "analysis"), readScope, s.getAnalysisHandler)
+
+		// Delete analysis together with its deep analyses
+		protected.HandleAudited(http.MethodDelete, "/analysis/:id", routeDoc{
+			Summary: "Delete an analysis and its deep analyses", Tag: "analyses", Scope: models.ScopeAnalyze,
+			Response: models.DeleteResponse{},
+		}, audited(models.AuditDelete, "analysis"), analyzeScope, s.deleteAnalysisHandler)
+
+		// Get deep analysis; running a new one additionally needs the analyze scope
+		protected.HandleAudited(http.MethodGet, "/analysis/:id/deep", routeDoc{
+			Summary: "Get or run the deep analysis of an analysis", Tag: "deep analyses", Scope: models.ScopeRead,
+			Query: []queryParam{
+				{Name: "max_age", Description: "Reuse a stored deep analysis at most this old, as a duration such as 30m"},
+				{Name: "force", Type: "boolean", Description: "Always run a new deep analysis"},
+			},
+			Response: models.DeepAnalysisResult{}, Errors: analyzerErrorStatuses,
+		}, audited(models.AuditView, "analysis"), readScope, s.deepAnalysisHandler)
+
+		// Deep analysis history
+		protected.HandleAudited(http.MethodGet, "/analysis/:id/deep/versions", routeDoc{
+			Summary: "List the deep analysis versions of an analysis", Tag: "deep analyses", Scope: models.ScopeRead,
+			Response: models.DeepAnalysisVersionsResponse{},
+		}, audited(models.AuditView, "analysis"), readScope, s.deepAnalysisVersionsHandler)
+		protected.HandleAudited(http.MethodGet, "/analysis/:id/deep/versions/:version", routeDoc{
+			Summary: "Get a deep analysis version", Tag: "deep analyses", Scope: models.ScopeRead,
+			Response: models.DeepAnalysisResult{}, Errors: []int{http.StatusBadRequest},
+		}, audited(models.AuditView, "analysis"), readScope, s.deepAnalysisVersionHandler)
 
 		// Get recent analyses
-		protected.GET("/analyses", s.getRecentAnalysesHandler)
+		protected.HandleAudited(http.MethodGet, "/analyses", routeDoc{
+			Summary: "List analyses", Tag: "analyses", Scope: models.ScopeRead,
+			Query:    slices.Concat(paginationParams, analysisFilterParams, scopeParams),
+			Response: models.AnalysisListResponse{}, Errors: []int{http.StatusNotFound},
+		}, audited(models.AuditList, "analysis"), auditedList, readScope, s.getRecentAnalysesHandler)
 
 		// Get current user's analyses
-		protected.GET("/user/analyses", s.getUserAnalysesHandler)
+		protected.HandleAudited(http.MethodGet, "/user/analyses", routeDoc{
+			Summary: "List the current user's personal analyses", Tag: "analyses", Scope: models.ScopeRead,
+			Query:    slices.Concat(paginationParams, analysisFilterParams),
+			Response: models.AnalysisListResponse{},
+		}, audited(models.AuditList, "analysis"), auditedList, readScope, s.getUserAnalysesHandler)
+
+		// Delete all of the current user's data
+		protected.HandleAudited(http.MethodDelete, "/user/data", routeDoc{
+			Summary: "Delete the current user's data", Tag: "users", Scope: models.ScopeAnalyze,
+			Description: "Deletes the user's personal analyses with their deep analyses and all of their API keys. " +
+				"Project analyses belong to the project's organization: they are kept but no longer name the user. " +
+				"The user's quota plan and quota usage are kept so that deleting data does not reset quotas.",
+			Response: models.DeleteResponse{},
+		}, audited(models.AuditDelete, "user"), analyzeScope, s.deleteUserDataHandler)
+
+		// Full-text search over analyzed pages
+		protected.HandleAudited(http.MethodGet, "/search", routeDoc{
+			Summary: "Search analyzed pages", Tag: "analyses", Scope: models.ScopeRead,
+			Query: slices.Concat([]queryParam{
+				{Name: "q", Description: "Search terms"},
+				{Name: "limit", Type: "integer", Description: "Maximum number of results to return"},
+			}, scopeParams),
+			Response: models.SearchResponse{}, Errors: []int{http.StatusNotFound},
+		}, audited(models.AuditList, "analysis"), auditedList, readScope, s.searchHandler)
+
+		// Current user's quota plan and usage
+		protected.Handle(http.MethodGet, "/user/quota", routeDoc{
+			Summary: "Get the current user's quota plan and usage", Tag: "users", Scope: models.ScopeRead,
+			Response: models.QuotaStatus{},
+		}, readScope, s.getQuotaHandler)
+
+		// Personal API keys; managing keys requires an interactive login
+		apiKeys := protected.Group("/user/api-keys", s.auth.DenyAPIKeys())
+		apiKeys.HandleAudited(http.MethodPost, "", routeDoc{
+			Summary: "Create a personal API key", Tag: "api keys", BearerOnly: true,
+			Request: models.APIKeyRequest{}, Response: models.CreatedAPIKey{}, Status: http.StatusCreated,
+		}, audited(models.AuditAPIKeyCreate, "api_key"), s.createAPIKeyHandler)
+		apiKeys.Handle(http.MethodGet, "", routeDoc{
+			Summary: "List personal API keys", Tag: "api keys", BearerOnly: true,
+			Response: models.APIKeyListResponse{},
+		}, s.listAPIKeysHandler)
+		apiKeys.HandleAudited(http.MethodDelete, "/:id", routeDoc{
+			Summary: "Revoke a personal API key", Tag: "api keys", BearerOnly: true,
+			Response: models.RevokeAPIKeyResponse{},
+		}, audited(models.AuditAPIKeyRevoke, "api_key"), s.revokeAPIKeyHandler)
+
+		// Organizations, their members and projects; like API keys, changes
+		// require an interactive login
+		denyAPIKeys := s.auth.DenyAPIKeys()
+		orgs := protected.Group("/orgs")
+		auditOrg := audited(models.AuditOrganization, "organization")
+		orgs.HandleAudited(http.MethodPost, "", routeDoc{
+			Summary: "Create an organization", Tag: "organizations", BearerOnly: true,
+			Request: models.OrganizationRequest{}, Response: models.UserOrganization{}, Status: http.StatusCreated,
+		}, auditOrg, denyAPIKeys, s.createOrganizationHandler)
+		orgs.Handle(http.MethodGet, "", routeDoc{
+			Summary: "List the current user's organizations", Tag: "organizations", Scope: models.ScopeRead,
+			Response: models.OrganizationListResponse{},
+		}, readScope, s.listOrganizationsHandler)
+		orgs.Handle(http.MethodGet, "/:org/members", routeDoc{
+			Summary: "List the members of an organization", Tag: "organizations", Scope: models.ScopeRead,
+			Response: models.MemberListResponse{},
+		}, readScope, s.listMembersHandler)
+		orgs.HandleAudited(http.MethodPut, "/:org/members/:user", routeDoc{
+			Summary: "Add a member or change their role", Tag: "organizations", BearerOnly: true,
+			Request: models.MembershipRequest{}, Response: models.Membership{}, Errors: []int{http.StatusConflict},
+		}, auditOrg, denyAPIKeys, s.putMemberHandler)
+		orgs.HandleAudited(http.MethodDelete, "/:org/members/:user", routeDoc{
+			Summary: "Remove a member from an organization", Tag: "organizations", BearerOnly: true,
+			Response: models.RemoveMemberResponse{}, Errors: []int{http.StatusConflict},
+		}, auditOrg, denyAPIKeys, s.removeMemberHandler)
+		orgs.HandleAudited(http.MethodPost, "/:org/projects", routeDoc{
+			Summary: "Create a project", Tag: "organizations", BearerOnly: true,
+			Request: models.ProjectRequest{}, Response: models.Project{}, Status: http.StatusCreated,
+		}, auditOrg, denyAPIKeys, s.createProjectHandler)
+		orgs.Handle(http.MethodGet, "/:org/projects", routeDoc{
+			Summary: "List the projects of an organization", Tag: "organizations", Scope: models.ScopeRead,
+			Response: models.ProjectListResponse{},
+		}, readScope, s.listProjectsHandler)
 	}
 
-	// Admin-only routes
-	admin := s.router.Group("/api/admin")
-	admin.Use(s.auth.Authenticate(), s.auth.RequireRoles("admin"))
+	// Admin-only routes; every access attempt is audited
+	admin := apiRoutes{
+		group: base.Group("/admin", audited(models.AuditAdminAccess, ""), s.auth.Authenticate(),
+			s.auth.RequireRoles("admin"), s.auth.RequireScope(models.ScopeAdmin), rateLimit),
+		spec: spec,
+		auth: authAdmin,
+	}
 	{
 		// Admin endpoints would go here
-		admin.GET("/stats", s.getStatsHandler)
+		admin.Handle(http.MethodGet, "/stats", routeDoc{
+			Summary: "Get application statistics", Tag: "admin", Scope: models.ScopeAdmin,
+			Response: models.Stats{},
+		}, s.getStatsHandler)
+
+		// Bulk delete analyses by filter
+		admin.Handle(http.MethodDelete, "/analyses", routeDoc{
+			Summary: "Delete analyses matching a filter", Tag: "admin", Scope: models.ScopeAdmin,
+			Query: slices.Concat(analysisFilterParams[1:], []queryParam{
+				{Name: "user_id", Description: "Only personal analyses of this user, outside any project"},
+				{Name: "include_projects", Type: "boolean", Description: "With user_id, also delete the user's project analyses"},
+				{Name: "project_id", Description: "Only analyses of this project"},
+				{Name: "all", Type: "boolean", Description: "Required to delete every analysis when no filter is given"},
+			}),
+			Response: models.DeleteResponse{},
+		}, s.bulkDeleteAnalysesHandler)
+
+		// Query and export the audit log
+		admin.Handle(http.MethodGet, "/audit", routeDoc{
+			Summary: "Query the audit log", Tag: "admin", Scope: models.ScopeAdmin,
+			Query:    slices.Concat(paginationParams, auditFilterParams),
+			Response: models.AuditEventListResponse{},
+		}, s.listAuditEventsHandler)
+		admin.Handle(http.MethodGet, "/audit/export", routeDoc{
+			Summary: "Export the audit log as CSV or JSON lines", Tag: "admin", Scope: models.ScopeAdmin,
+			Query:    append([]queryParam{{Name: "format", Description: "csv (default) or jsonl"}}, auditFilterParams...),
+			Produces: []string{"text/csv", "application/x-ndjson"},
+		}, s.exportAuditEventsHandler)
+
+		// Assign a user's quota plan
+		admin.Handle(http.MethodPut, "/users/:user/plan", routeDoc{
+			Summary: "Assign a user's quota plan", Tag: "admin", Scope: models.ScopeAdmin,
+			Request: models.PlanRequest{}, Response: models.UserPlanResponse{},
+		}, s.setUserPlanHandler)
 	}
 }
 
 // healthHandler handles health check requests
 func (s *Server) healthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
-		"time":   time.Now().Format(time.RFC3339),
+	c.JSON(http.StatusOK, models.HealthResponse{
+		Status: "ok",
+		Time:   time.Now().Format(time.RFC3339),
 	})
 }
 
 // analyzeURLHandler handles requests to analyze a URL
 func (s *Server) analyzeURLHandler(c *gin.Context) {
 	// Parse request
-	var req struct {
-		URL string `json:"url" binding:"required,url"`
-	}
-
+	var req models.AnalysisRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code": http.StatusBadRequest,
-			"message":     "Invalid request",
-			"error":       err.Error(),
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid request",
+			Error:      err.Error(),
 		})
 		return
 	}
 
+	middleware.SetAuditDetail(c, req.URL)
+
+	// Analyses filed under a project need the analyst role there
+	if req.ProjectID != "" && !s.authorize(c, authz.ActionAnalyze, authz.Project(req.ProjectID)) {
+		return
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(c.Request.Context(), s.config.Analyzer.RequestTimeout)
 	defer cancel()
@@ -157,11 +394,8 @@ func (s *Server) analyzeURLHandler(c *gin.Context) {
 	result, err := s.analyzer.AnalyzeURL(ctx, req.URL)
 	if err != nil {
 		s.logger.Error("Failed to analyze URL", "url", req.URL, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code": http.StatusBadRequest,
-			"message":     fmt.Sprintf("Failed to analyze URL: %s", req.URL),
-			"error":       err.Error(),
-		})
+		resp := analyzerErrorResponse(err, fmt.Sprintf("Failed to analyze URL: %s", req.URL))
+		c.JSON(resp.StatusCode, resp)
 		return
 	}
 
@@ -171,6 +405,7 @@ func (s *Server) analyzeURLHandler(c *gin.Context) {
 			result.UserID = ui.Sub
 		}
 	}
+	result.ProjectID = req.ProjectID
 
 	// Save analysis to database
 	if err := s.repo.SaveAnalysis(ctx, result); err != nil {
@@ -178,6 +413,8 @@ func (s *Server) analyzeURLHandler(c *gin.Context) {
 		// Continue anyway, just log the error
 	}
 
+	middleware.SetAuditTarget(c, "analysis", result.ID.Hex())
+
 	// Return result
 	c.JSON(http.StatusOK, result)
 }
@@ -186,183 +423,185 @@ func (s *Server) analyzeURLHandler(c *gin.Context) {
 func (s *Server) getAnalysisHandler(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status_code": http.StatusBadRequest,
-			"message":     "Missing analysis ID",
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Missing analysis ID",
 		})
 		return
 	}
 
-	// Get analysis from database
-	ctx := c.Request.Context()
-	result, err := s.repo.GetAnalysis(ctx, id)
-	if err != nil {
-		s.logger.Error("Failed to get analysis", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to get analysis",
-			"error":       err.Error(),
-		})
+	// Get the analysis if it belongs to the user or their project, or the
+	// user is an admin
+	result, ok := s.authorizedAnalysis(c, id, authz.ActionRead)
+	if !ok {
 		return
 	}
 
-	if result == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status_code": http.StatusNotFound,
-			"message":     "Analysis not found",
+	// Return result
+	c.JSON(http.StatusOK, result)
+}
+
+// getRecentAnalysesHandler handles requests to get recent analyses
+func (s *Server) getRecentAnalysesHandler(c *gin.Context) {
+	query, err := parseAnalysisQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid query parameters",
+			Error:      err.Error(),
 		})
 		return
 	}
 
 	// Get authenticated user info
-	userInfo, exists := c.Get("userInfo")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status_code": http.StatusUnauthorized,
-			"message":     "Unauthorized",
+	if _, exists := c.Get("userInfo"); !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "Unauthorized",
 		})
 		return
 	}
 
-	// Check if the analysis belongs to the user or if user is admin
-	ui := userInfo.(*middleware.UserInfo)
-	isAdmin := false
-	for _, role := range ui.RealmAccess.Roles {
-		if role == "admin" {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin && result.UserID != "" && result.UserID != ui.Sub {
-		c.JSON(http.StatusForbidden, gin.H{
-			"status_code": http.StatusForbidden,
-			"message":     "You don't have permission to access this analysis",
-		})
+	// List a project's analyses, or the user's personal ones; only admins
+	// list across all users
+	scope, ok := s.analysisScope(c)
+	if !ok {
 		return
 	}
+	query.Filter.UserID, query.Filter.ProjectID = scope.UserID, scope.ProjectID
 
-	// Return result
-	c.JSON(http.StatusOK, result)
+	s.listAnalyses(c, query, "Failed to get recent analyses")
 }
 
-// getRecentAnalysesHandler handles requests to get recent analyses
-func (s *Server) getRecentAnalysesHandler(c *gin.Context) {
-	// Default limit to 10
-	limit := 10
-
-	// Try to get limit from query parameter
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if n, err := fmt.Sscanf(limitParam, "%d", &limit); err != nil || n != 1 {
-			// Invalid limit, use default
-			limit = 10
-		}
-	}
-
-	// Cap limit to reasonable value
-	if limit > 100 {
-		limit = 100
+// getUserAnalysesHandler handles requests to get the current user's analyses
+func (s *Server) getUserAnalysesHandler(c *gin.Context) {
+	query, err := parseAnalysisQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Message:    "Invalid query parameters",
+			Error:      err.Error(),
+		})
+		return
 	}
 
 	// Get authenticated user info
 	userInfo, exists := c.Get("userInfo")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status_code": http.StatusUnauthorized,
-			"message":     "Unauthorized",
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "Unauthorized",
 		})
 		return
 	}
 
-	// Check if user is admin
 	ui := userInfo.(*middleware.UserInfo)
-	isAdmin := false
-	for _, role := range ui.RealmAccess.Roles {
-		if role == "admin" {
-			isAdmin = true
-			//break
-		}
-	}
+	query.Filter.UserID = ui.Sub
 
-	// Get recent analyses from database
-	ctx := c.Request.Context()
-	var results []*models.AnalysisResult
-	var err error
-
-	if isAdmin {
-		// Admins can see all analyses
-		results, err = s.repo.GetRecentAnalyses(ctx, limit)
-	} else {
-		// Regular users can only see their own analyses
-		//results, err = s.repo.GetUserAnalyses(ctx, ui.Sub, limit)
-		results, err = s.repo.GetRecentAnalyses(ctx, limit)
-	}
+	s.listAnalyses(c, query, "Failed to get user analyses")
+}
 
+// listAnalyses runs a listing query and writes the page to the response
+func (s *Server) listAnalyses(c *gin.Context, query models.AnalysisQuery, failureMessage string) {
+	ctx := c.Request.Context()
+	page, err := s.repo.ListAnalyses(ctx, query)
 	if err != nil {
-		s.logger.Error("Failed to get recent analyses", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to get recent analyses",
-			"error":       err.Error(),
+		if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrInvalidSort) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid query parameters",
+				Error:      err.Error(),
+			})
+			return
+		}
+
+		s.logger.Error(failureMessage, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    failureMessage,
+			Error:      err.Error(),
 		})
 		return
 	}
 
 	// Return results
-	c.JSON(http.StatusOK, gin.H{
-		"count":    len(results),
-		"analyses": results,
+	c.JSON(http.StatusOK, models.AnalysisListResponse{
+		Count:      len(page.Analyses),
+		Analyses:   page.Analyses,
+		NextCursor: page.NextCursor,
 	})
 }
 
-// getUserAnalysesHandler handles requests to get the current user's analyses
-func (s *Server) getUserAnalysesHandler(c *gin.Context) {
-	// Default limit to 10
-	limit := 10
+// parseAnalysisQuery reads pagination, filter and sort parameters of a listing request
+func parseAnalysisQuery(c *gin.Context) (models.AnalysisQuery, error) {
+	query := models.AnalysisQuery{
+		Limit:  repository.DefaultListLimit,
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+		Filter: models.AnalysisFilter{
+			Domain:      c.Query("domain"),
+			URLPrefix:   c.Query("url_prefix"),
+			HTMLVersion: c.Query("html_version"),
+		},
+	}
 
 	// Try to get limit from query parameter
 	if limitParam := c.Query("limit"); limitParam != "" {
-		if n, err := fmt.Sscanf(limitParam, "%d", &limit); err != nil || n != 1 {
-			// Invalid limit, use default
-			limit = 10
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return query, fmt.Errorf("invalid limit: %s", limitParam)
 		}
+		query.Limit = limit
 	}
 
 	// Cap limit to reasonable value
-	if limit > 100 {
-		limit = 100
+	if query.Limit > repository.MaxListLimit {
+		query.Limit = repository.MaxListLimit
 	}
 
-	// Get authenticated user info
-	userInfo, exists := c.Get("userInfo")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status_code": http.StatusUnauthorized,
-			"message":     "Unauthorized",
-		})
-		return
+	var err error
+	if query.Filter.From, err = parseTimeParam(c, "from"); err != nil {
+		return query, err
+	}
+	if query.Filter.To, err = parseTimeParam(c, "to"); err != nil {
+		return query, err
+	}
+	if query.Filter.HasLoginForm, err = parseBoolParam(c, "has_login_form"); err != nil {
+		return query, err
+	}
+	if query.Filter.HasBrokenLinks, err = parseBoolParam(c, "has_broken_links"); err != nil {
+		return query, err
 	}
 
-	ui := userInfo.(*middleware.UserInfo)
+	return query, nil
+}
 
-	// Get user analyses from database
-	ctx := c.Request.Context()
-	results, err := s.repo.GetUserAnalyses(ctx, ui.Sub, limit)
-	if err != nil {
-		s.logger.Error("Failed to get user analyses", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to get user analyses",
-			"error":       err.Error(),
-		})
-		return
+// parseTimeParam parses an RFC 3339 timestamp or YYYY-MM-DD date query parameter
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
 	}
+	return time.Time{}, fmt.Errorf("invalid %s: %s", name, value)
+}
 
-	// Return results
-	c.JSON(http.StatusOK, gin.H{
-		"count":    len(results),
-		"analyses": results,
-	})
+// parseBoolParam parses an optional boolean query parameter
+func parseBoolParam(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return &b, nil
 }
 
 // getStatsHandler handles requests to get admin stats
@@ -372,10 +611,10 @@ func (s *Server) getStatsHandler(c *gin.Context) {
 	stats, err := s.repo.GetStats(ctx)
 	if err != nil {
 		s.logger.Error("Failed to get stats", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status_code": http.StatusInternalServerError,
-			"message":     "Failed to get stats",
-			"error":       err.Error(),
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Failed to get stats",
+			Error:      err.Error(),
 		})
 		return
 	}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Defaults used when the configuration leaves them unset
+const (
+	defaultBufferSize    = 1000
+	defaultFlushInterval = time.Second
+	maxBatchSize         = 100
+)
+
+// Store persists audit events
+type Store interface {
+	SaveAuditEvents(ctx context.Context, events []*models.AuditEvent) error
+}
+
+// Logger writes audit events asynchronously in batches so that recording
+// never blocks a request. Events are dropped, and the drop is logged, if the
+// buffer is full.
+type Logger struct {
+	store         Store
+	logger        *slog.Logger
+	events        chan *models.AuditEvent
+	flush         chan chan struct{}
+	flushInterval time.Duration
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewLogger creates an audit logger and starts its writer
+func NewLogger(store Store, cfg config.AuditConfig, logger *slog.Logger) *Logger {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	l := &Logger{
+		store:         store,
+		logger:        logger,
+		events:        make(chan *models.AuditEvent, bufferSize),
+		flush:         make(chan chan struct{}),
+		flushInterval: flushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go l.run()
+
+	return l
+}
+
+// Record queues an event for writing
+func (l *Logger) Record(event *models.AuditEvent) {
+	select {
+	case l.events <- event:
+	default:
+		l.logger.Error("Audit log buffer full, dropping event",
+			"action", event.Action, "actor", event.ActorID, "path", event.Path)
+	}
+}
+
+// Flush waits until every event recorded before the call has been written
+func (l *Logger) Flush(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case l.flush <- reply:
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes the queued events and stops the writer
+func (l *Logger) Close(ctx context.Context) error {
+	l.closeOnce.Do(func() { close(l.stop) })
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run batches queued events and writes them on every flush interval, when a
+// batch is full, on Flush and on Close
+func (l *Logger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*models.AuditEvent
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// Writes outlive the requests that produced the events
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := l.store.SaveAuditEvents(ctx, batch); err != nil {
+			l.logger.Error("Failed to write audit events", "count", len(batch), "error", err)
+		}
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case event := <-l.events:
+				batch = append(batch, event)
+				if len(batch) >= maxBatchSize {
+					write()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case event := <-l.events:
+			batch = append(batch, event)
+			if len(batch) >= maxBatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case reply := <-l.flush:
+			drain()
+			write()
+			close(reply)
+		case <-l.stop:
+			drain()
+			write()
+			return
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Authorization errors
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("permission denied")
+	ErrNotFound        = errors.New("resource not found")
+)
+
+// Action is an operation a subject performs on a resource
+type Action string
+
+// Actions, from least to most privileged
+const (
+	ActionRead    Action = "read"    // view a resource
+	ActionAnalyze Action = "analyze" // trigger work: analyze into a project or run a deep analysis
+	ActionDelete  Action = "delete"  // delete a resource
+	ActionManage  Action = "manage"  // manage an organization's members and projects
+)
+
+// requiredRole is the minimum member role for each action on organization resources
+var requiredRole = map[Action]string{
+	ActionRead:    models.RoleViewer,
+	ActionAnalyze: models.RoleAnalyst,
+	ActionDelete:  models.RoleAnalyst,
+	ActionManage:  models.RoleOwner,
+}
+
+// requiredScope is the API key scope needed for each action; API keys can
+// never manage organizations
+var requiredScope = map[Action]string{
+	ActionRead:    models.ScopeRead,
+	ActionAnalyze: models.ScopeAnalyze,
+	ActionDelete:  models.ScopeAnalyze,
+}
+
+// Kind is the type of a resource
+type Kind string
+
+// Resource kinds
+const (
+	KindAnalysis     Kind = "analysis"
+	KindOrganization Kind = "organization"
+	KindProject      Kind = "project"
+)
+
+// Resource identifies what an action is performed on. Analyses carry their
+// owner and project so no lookup is needed to authorize them.
+type Resource struct {
+	Kind      Kind
+	ID        string
+	OwnerID   string // analyses: the user who created it, "" if anonymous
+	ProjectID string // analyses: the project it belongs to, "" if personal
+}
+
+// Analysis returns the resource for an analysis
+func Analysis(analysis *models.AnalysisResult) Resource {
+	return Resource{
+		Kind:      KindAnalysis,
+		ID:        analysis.ID.Hex(),
+		OwnerID:   analysis.UserID,
+		ProjectID: analysis.ProjectID,
+	}
+}
+
+// Organization returns the resource for an organization
+func Organization(id string) Resource {
+	return Resource{Kind: KindOrganization, ID: id}
+}
+
+// Project returns the resource for a project
+func Project(id string) Resource {
+	return Resource{Kind: KindProject, ID: id}
+}
+
+// Subject is the caller an action is authorized for
+type Subject struct {
+	UserID string   // "" for anonymous callers
+	Admin  bool     // admins may do anything to existing resources
+	Scopes []string // API key scopes; nil for unrestricted credentials
+}
+
+// hasScope reports whether the subject's credentials allow the action
+func (s Subject) hasScope(action Action) bool {
+	if s.Scopes == nil {
+		return true
+	}
+	scope, ok := requiredScope[action]
+	return ok && slices.Contains(s.Scopes, scope)
+}
+
+// Store looks up the tenancy data the policy decides on
+type Store interface {
+	GetOrganization(ctx context.Context, id string) (*models.Organization, error)
+	GetMembership(ctx context.Context, orgID, userID string) (*models.Membership, error)
+	GetProject(ctx context.Context, id string) (*models.Project, error)
+}
+
+// Policy decides whether a subject may perform an action on a resource
+type Policy struct {
+	store Store
+}
+
+// NewPolicy creates a policy backed by the given store
+func NewPolicy(store Store) *Policy {
+	return &Policy{store: store}
+}
+
+// Authorize returns nil if the subject may perform the action on the
+// resource. It returns ErrUnauthenticated if the action needs a user,
+// ErrNotFound for organizations and projects the subject cannot see and
+// ErrForbidden otherwise.
+//
+// Personal analyses are only accessible to their owner; anonymous ones can
+// be read by anyone and deep-analyzed by any user but changed by admins
+// only. Project analyses, projects and organizations follow the subject's
+// member role in the organization.
+func (p *Policy) Authorize(ctx context.Context, sub Subject, action Action, res Resource) error {
+	if !sub.hasScope(action) {
+		return ErrForbidden
+	}
+
+	switch res.Kind {
+	case KindAnalysis:
+		return p.authorizeAnalysis(ctx, sub, action, res)
+	case KindOrganization, KindProject:
+		if sub.UserID == "" && !sub.Admin {
+			return ErrUnauthenticated
+		}
+		role, err := p.role(ctx, sub, res)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return ErrNotFound
+		}
+		return checkRole(role, action)
+	default:
+		return ErrForbidden
+	}
+}
+
+// authorizeAnalysis applies the ownership rules of analyses
+func (p *Policy) authorizeAnalysis(ctx context.Context, sub Subject, action Action, res Resource) error {
+	if sub.Admin {
+		return nil
+	}
+
+	if res.ProjectID != "" {
+		if sub.UserID == "" {
+			return ErrUnauthenticated
+		}
+		role, err := p.role(ctx, sub, Project(res.ProjectID))
+		if err != nil {
+			return err
+		}
+		return checkRole(role, action)
+	}
+
+	// Anonymous analyses are shared: anyone reads them and any user may
+	// deep-analyze them, but only admins change them
+	if res.OwnerID == "" {
+		switch {
+		case action == ActionRead:
+			return nil
+		case action == ActionAnalyze && sub.UserID == "":
+			return ErrUnauthenticated
+		case action == ActionAnalyze:
+			return nil
+		}
+		return ErrForbidden
+	}
+
+	if sub.UserID == "" {
+		return ErrUnauthenticated
+	}
+	if res.OwnerID != sub.UserID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AnalysisScope returns the tenant filter for listing or searching analyses:
+// the given project's analyses, or otherwise the subject's personal ones.
+// Admins see every analysis unless they narrow it down to userID; for other
+// subjects userID is ignored. The repository applies the returned filter.
+func (p *Policy) AnalysisScope(ctx context.Context, sub Subject, projectID, userID string) (models.AnalysisFilter, error) {
+	if projectID != "" {
+		if err := p.Authorize(ctx, sub, ActionRead, Project(projectID)); err != nil {
+			return models.AnalysisFilter{}, err
+		}
+		return models.AnalysisFilter{ProjectID: projectID}, nil
+	}
+
+	if !sub.hasScope(ActionRead) {
+		return models.AnalysisFilter{}, ErrForbidden
+	}
+	if sub.Admin {
+		return models.AnalysisFilter{UserID: userID}, nil
+	}
+	if sub.UserID == "" {
+		return models.AnalysisFilter{}, ErrUnauthenticated
+	}
+	return models.AnalysisFilter{UserID: sub.UserID}, nil
+}
+
+// role returns the subject's member role for an organization or project,
+// or "" if the subject is not a member or the resource does not exist.
+// Admins act as owners of every existing organization.
+func (p *Policy) role(ctx context.Context, sub Subject, res Resource) (string, error) {
+	if !primitive.IsValidObjectID(res.ID) {
+		return "", nil
+	}
+
+	orgID := res.ID
+	if res.Kind == KindProject {
+		project, err := p.store.GetProject(ctx, res.ID)
+		if err != nil || project == nil {
+			return "", err
+		}
+		orgID = project.OrganizationID.Hex()
+	}
+
+	if sub.Admin {
+		org, err := p.store.GetOrganization(ctx, orgID)
+		if err != nil || org == nil {
+			return "", err
+		}
+		return models.RoleOwner, nil
+	}
+
+	membership, err := p.store.GetMembership(ctx, orgID, sub.UserID)
+	if err != nil || membership == nil {
+		return "", err
+	}
+	return membership.Role, nil
+}
+
+// checkRole checks that a member role allows the action
+func checkRole(role string, action Action) error {
+	required, ok := requiredRole[action]
+	if !ok || !models.RoleAtLeast(role, required) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -2,17 +2,26 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	MongoDB  MongoDBConfig
-	Analyzer AnalyzerConfig
-	Keycloak KeycloakConfig
+	Server    ServerConfig
+	Storage   StorageConfig
+	MongoDB   MongoDBConfig
+	SQL       SQLConfig
+	Retention RetentionConfig
+	Audit     AuditConfig
+	RateLimit RateLimitConfig
+	Analyzer  AnalyzerConfig
+	Auth      AuthConfig
+	Keycloak  KeycloakConfig
+	OIDC      OIDCConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -21,6 +30,15 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	// TrustedProxies lists the IPs and CIDRs of reverse proxies whose
+	// X-Forwarded-For headers are trusted; none are by default, so the
+	// client IP is the remote address
+	TrustedProxies []string
+}
+
+// StorageConfig selects the storage backend used by the repository
+type StorageConfig struct {
+	Backend string // "mongo", "postgres" or "sqlite"
 }
 
 // MongoDBConfig holds MongoDB connection configuration
@@ -31,10 +49,55 @@ type MongoDBConfig struct {
 	Timeout        time.Duration
 }
 
+// SQLConfig holds database/sql connection configuration
+type SQLConfig struct {
+	DSN          string
+	MaxOpenConns int
+	Timeout      time.Duration
+}
+
+// RetentionConfig holds data retention configuration. A zero max age keeps
+// data forever.
+type RetentionConfig struct {
+	AnalysesMaxAge     time.Duration
+	DeepAnalysesMaxAge time.Duration
+	PurgeInterval      time.Duration
+}
+
+// AuditConfig holds audit log configuration. Events are buffered in memory
+// and written in batches every flush interval.
+type AuditConfig struct {
+	BufferSize    int
+	FlushInterval time.Duration
+}
+
 // AnalyzerConfig holds webpage analyzer configuration
 type AnalyzerConfig struct {
-	RequestTimeout time.Duration
-	UserAgent      string
+	RequestTimeout     time.Duration
+	UserAgent          string
+	DeepAnalysisMaxAge time.Duration
+	MaxPageSize        int64 // bytes; zero means no limit
+	// VerifyIntegrity makes deep analyses download scripts and stylesheets
+	// with an integrity attribute to check their hashes
+	VerifyIntegrity bool
+	// TrackerSignatures is the path of a JSON file with tracker signatures
+	// extending the bundled ones
+	TrackerSignatures string
+}
+
+// AuthConfig selects the identity provider used to authenticate requests
+type AuthConfig struct {
+	Provider string // "keycloak" or "oidc"
+}
+
+// OIDCConfig holds configuration for a generic OpenID Connect provider.
+// Claim paths are dot-separated, e.g. "realm_access.roles".
+type OIDCConfig struct {
+	IssuerURL    string
+	Audience     string
+	UserIDClaim  string
+	RolesClaim   string
+	JWKSCacheTTL time.Duration
 }
 
 // KeycloakConfig holds Keycloak authentication configuration
@@ -44,6 +107,8 @@ type KeycloakConfig struct {
 	ClientID     string
 	ClientSecret string
 	FallbackURL  string
+	Audience     string
+	JWKSCacheTTL time.Duration
 }
 
 // New creates a new Config with values from environment variables
@@ -64,22 +129,149 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
 	}
 
+	trustedProxies, err := parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
 	requestTimeout, err := strconv.Atoi(getEnv("REQUEST_TIMEOUT", "10"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid REQUEST_TIMEOUT: %w", err)
 	}
 
+	deepAnalysisMaxAge, err := strconv.Atoi(getEnv("DEEP_ANALYSIS_MAX_AGE", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEEP_ANALYSIS_MAX_AGE: %w", err)
+	}
+
+	maxPageSize, err := strconv.Atoi(getEnv("MAX_PAGE_SIZE_MB", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_PAGE_SIZE_MB: %w", err)
+	}
+
 	mongoTimeout, err := strconv.Atoi(getEnv("MONGO_TIMEOUT", "10"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid MONGO_TIMEOUT: %w", err)
 	}
 
+	jwksCacheTTL, err := strconv.Atoi(getEnv("KEYCLOAK_JWKS_CACHE_TTL", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEYCLOAK_JWKS_CACHE_TTL: %w", err)
+	}
+
+	oidcJWKSCacheTTL, err := strconv.Atoi(getEnv("OIDC_JWKS_CACHE_TTL", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC_JWKS_CACHE_TTL: %w", err)
+	}
+
+	sqlMaxOpenConns, err := strconv.Atoi(getEnv("SQL_MAX_OPEN_CONNS", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL_MAX_OPEN_CONNS: %w", err)
+	}
+
+	sqlTimeout, err := strconv.Atoi(getEnv("SQL_TIMEOUT", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL_TIMEOUT: %w", err)
+	}
+
+	analysesRetention, err := strconv.Atoi(getEnv("RETENTION_ANALYSES_DAYS", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETENTION_ANALYSES_DAYS: %w", err)
+	}
+
+	deepAnalysesRetention, err := strconv.Atoi(getEnv("RETENTION_DEEP_ANALYSES_DAYS", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETENTION_DEEP_ANALYSES_DAYS: %w", err)
+	}
+
+	purgeInterval, err := strconv.Atoi(getEnv("RETENTION_PURGE_INTERVAL", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RETENTION_PURGE_INTERVAL: %w", err)
+	}
+
+	auditBufferSize, err := strconv.Atoi(getEnv("AUDIT_BUFFER_SIZE", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_BUFFER_SIZE: %w", err)
+	}
+
+	auditFlushInterval, err := strconv.Atoi(getEnv("AUDIT_FLUSH_INTERVAL", "1"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_FLUSH_INTERVAL: %w", err)
+	}
+
+	verifyIntegrity, err := strconv.ParseBool(getEnv("VERIFY_SUBRESOURCE_INTEGRITY", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VERIFY_SUBRESOURCE_INTEGRITY: %w", err)
+	}
+
+	rateLimitEnabled, err := strconv.ParseBool(getEnv("RATE_LIMIT_ENABLED", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_ENABLED: %w", err)
+	}
+
+	defaultRateLimit, err := parseRateLimit(getEnv("RATE_LIMIT_DEFAULT", "120/m:30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_DEFAULT: %w", err)
+	}
+
+	routeRateLimits, err := parseRouteRateLimits(getEnv("RATE_LIMIT_ROUTES",
+		"POST /api/v1/analyze=10/m:5;GET /api/v1/analysis/:id/deep=10/m:3"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %w", err)
+	}
+
+	quotaPlans, err := parseQuotaPlans(getEnv("QUOTA_PLANS", "anonymous=20/200;free=100/2000;pro=2000/50000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUOTA_PLANS: %w", err)
+	}
+
+	defaultPlan := getEnv("QUOTA_DEFAULT_PLAN", "free")
+	if _, ok := quotaPlans[defaultPlan]; !ok {
+		return nil, fmt.Errorf("QUOTA_DEFAULT_PLAN %q is not defined in QUOTA_PLANS", defaultPlan)
+	}
+
+	backend := getEnv("STORAGE_BACKEND", "mongo")
+	switch backend {
+	case "mongo", "postgres", "sqlite":
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND: %q", backend)
+	}
+
+	// Tokens must be meant for this backend: Keycloak's audience defaults
+	// to the client ID, and a generic provider needs one configured
+	keycloakClientID := getEnv("KEYCLOAK_CLIENT_ID", "web-analyzer-backend")
+	keycloakAudience := getEnv("KEYCLOAK_AUDIENCE", "")
+	if keycloakAudience == "" {
+		keycloakAudience = keycloakClientID
+	}
+
+	authProvider := getEnv("AUTH_PROVIDER", "keycloak")
+	switch authProvider {
+	case "keycloak":
+		if keycloakAudience == "" {
+			return nil, fmt.Errorf("KEYCLOAK_AUDIENCE or KEYCLOAK_CLIENT_ID is required when AUTH_PROVIDER is keycloak")
+		}
+	case "oidc":
+		if getEnv("OIDC_ISSUER_URL", "") == "" {
+			return nil, fmt.Errorf("OIDC_ISSUER_URL is required when AUTH_PROVIDER is oidc")
+		}
+		if getEnv("OIDC_AUDIENCE", "") == "" {
+			return nil, fmt.Errorf("OIDC_AUDIENCE is required when AUTH_PROVIDER is oidc")
+		}
+	default:
+		return nil, fmt.Errorf("invalid AUTH_PROVIDER: %q", authProvider)
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port:            port,
 			ReadTimeout:     time.Duration(readTimeout) * time.Second,
 			WriteTimeout:    time.Duration(writeTimeout) * time.Second,
 			ShutdownTimeout: time.Duration(shutdownTimeout) * time.Second,
+			TrustedProxies:  trustedProxies,
+		},
+		Storage: StorageConfig{
+			Backend: backend,
 		},
 		MongoDB: MongoDBConfig{
 			URI:            getEnv("MONGO_URI", "mongodb://mongo:27017"),
@@ -87,20 +279,75 @@ func New() (*Config, error) {
 			CollectionName: getEnv("MONGO_COLLECTION", "analyses"),
 			Timeout:        time.Duration(mongoTimeout) * time.Second,
 		},
+		SQL: SQLConfig{
+			DSN:          getEnv("SQL_DSN", "file:web_analyzer.db"),
+			MaxOpenConns: sqlMaxOpenConns,
+			Timeout:      time.Duration(sqlTimeout) * time.Second,
+		},
+		Retention: RetentionConfig{
+			AnalysesMaxAge:     time.Duration(analysesRetention) * 24 * time.Hour,
+			DeepAnalysesMaxAge: time.Duration(deepAnalysesRetention) * 24 * time.Hour,
+			PurgeInterval:      time.Duration(purgeInterval) * time.Minute,
+		},
+		Audit: AuditConfig{
+			BufferSize:    auditBufferSize,
+			FlushInterval: time.Duration(auditFlushInterval) * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:     rateLimitEnabled,
+			Default:     defaultRateLimit,
+			Routes:      routeRateLimits,
+			Plans:       quotaPlans,
+			DefaultPlan: defaultPlan,
+		},
 		Analyzer: AnalyzerConfig{
-			RequestTimeout: time.Duration(requestTimeout) * time.Second,
-			UserAgent:      getEnv("USER_AGENT", "WebAnalyzer/1.0"),
+			RequestTimeout:     time.Duration(requestTimeout) * time.Second,
+			UserAgent:          getEnv("USER_AGENT", "WebAnalyzer/1.0"),
+			DeepAnalysisMaxAge: time.Duration(deepAnalysisMaxAge) * time.Minute,
+			MaxPageSize:        int64(maxPageSize) << 20,
+			VerifyIntegrity:    verifyIntegrity,
+			TrackerSignatures:  getEnv("TRACKER_SIGNATURES_FILE", ""),
+		},
+		Auth: AuthConfig{
+			Provider: authProvider,
 		},
 		Keycloak: KeycloakConfig{
 			URL:          getEnv("KEYCLOAK_URL", "http://localhost:8080"),
 			Realm:        getEnv("KEYCLOAK_REALM", "web-analyzer"),
-			ClientID:     getEnv("KEYCLOAK_CLIENT_ID", "web-analyzer-backend"),
+			ClientID:     keycloakClientID,
 			ClientSecret: getEnv("KEYCLOAK_CLIENT_SECRET", ""),
 			FallbackURL:  getEnv("KEYCLOAK_FALLBACK_URL", "http://localhost:8080"),
+			Audience:     keycloakAudience,
+			JWKSCacheTTL: time.Duration(jwksCacheTTL) * time.Minute,
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+			Audience:     getEnv("OIDC_AUDIENCE", ""),
+			UserIDClaim:  getEnv("OIDC_USER_ID_CLAIM", "sub"),
+			RolesClaim:   getEnv("OIDC_ROLES_CLAIM", "roles"),
+			JWKSCacheTTL: time.Duration(oidcJWKSCacheTTL) * time.Minute,
 		},
 	}, nil
 }
 
+// parseTrustedProxies parses a comma-separated list of IPs and CIDRs
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return nil, fmt.Errorf("%q is neither an IP nor a CIDR", entry)
+			}
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies, nil
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
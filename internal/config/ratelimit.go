@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnonymousPlan is the quota plan applied to unauthenticated callers
+const AnonymousPlan = "anonymous"
+
+// RateLimitConfig holds request rate limits and analysis quotas. Routes are
+// keyed by method and route pattern, e.g. "POST /api/v1/analyze"; routes
+// without an entry use Default.
+type RateLimitConfig struct {
+	Enabled     bool
+	Default     RateLimit
+	Routes      map[string]RateLimit
+	Plans       map[string]QuotaPlan
+	DefaultPlan string
+}
+
+// RateLimit is a token bucket refilled at Rate tokens per second holding at
+// most Burst tokens
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// QuotaPlan bounds the analyses a caller may run per UTC day and month.
+// Zero means unlimited.
+type QuotaPlan struct {
+	Daily   int
+	Monthly int
+}
+
+// parseRateLimit parses a limit such as "10/m:5": 10 requests per minute
+// with bursts of up to 5. The burst defaults to 1.
+func parseRateLimit(value string) (RateLimit, error) {
+	spec, burstSpec, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+	countSpec, unitSpec, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected <count>/<s|m|h>[:<burst>]", value)
+	}
+
+	count, err := strconv.ParseFloat(countSpec, 64)
+	if err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: bad count", value)
+	}
+
+	var unit time.Duration
+	switch unitSpec {
+	case "s":
+		unit = time.Second
+	case "m":
+		unit = time.Minute
+	case "h":
+		unit = time.Hour
+	default:
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", value)
+	}
+
+	burst := 1
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstSpec); err != nil || burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q: bad burst", value)
+		}
+	}
+
+	return RateLimit{Rate: count / unit.Seconds(), Burst: burst}, nil
+}
+
+// parseRouteRateLimits parses semicolon-separated route limits such as
+// "POST /api/v1/analyze=10/m:5;GET /api/v1/search=60/m:20". Paths under the
+// deprecated /api prefix are keyed by their /api/v1 successor, which the
+// limiter resolves requests to.
+func parseRouteRateLimits(value string) (map[string]RateLimit, error) {
+	routes := make(map[string]RateLimit)
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, limitSpec, ok := strings.Cut(entry, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected <METHOD> <path>=<limit>", entry)
+		}
+
+		limit, err := parseRateLimit(limitSpec)
+		if err != nil {
+			return nil, err
+		}
+		path = strings.TrimSpace(path)
+		if rest, ok := strings.CutPrefix(path, "/api/"); ok && rest != "v1" && !strings.HasPrefix(rest, "v1/") {
+			path = "/api/v1/" + rest
+		}
+		routes[strings.ToUpper(method)+" "+path] = limit
+	}
+	return routes, nil
+}
+
+// parseQuotaPlans parses semicolon-separated plans such as
+// "anonymous=20/200;free=100/2000", giving daily and monthly analyses
+func parseQuotaPlans(value string) (map[string]QuotaPlan, error) {
+	plans := make(map[string]QuotaPlan)
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, limits, ok := strings.Cut(entry, "=")
+		dailySpec, monthlySpec, hasMonthly := strings.Cut(limits, "/")
+		if !ok || !hasMonthly {
+			return nil, fmt.Errorf("invalid quota plan %q: expected <name>=<daily>/<monthly>", entry)
+		}
+
+		daily, err := strconv.Atoi(strings.TrimSpace(dailySpec))
+		if err != nil || daily < 0 {
+			return nil, fmt.Errorf("invalid quota plan %q: bad daily quota", entry)
+		}
+		monthly, err := strconv.Atoi(strings.TrimSpace(monthlySpec))
+		if err != nil || monthly < 0 {
+			return nil, fmt.Errorf("invalid quota plan %q: bad monthly quota", entry)
+		}
+		plans[strings.TrimSpace(name)] = QuotaPlan{Daily: daily, Monthly: monthly}
+	}
+	return plans, nil
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyPrefix marks API keys so they can be told apart from JWTs
+const APIKeyPrefix = "wak_"
+
+// apiKeyDisplayLength is the number of leading key characters kept to help
+// users recognise their keys
+const apiKeyDisplayLength = 12
+
+// apiKeyTouchInterval throttles last-used updates to one write per interval
+const apiKeyTouchInterval = time.Minute
+
+// adminKeyCheckInterval throttles checks for admin keys of users who are no
+// longer admins to one per user and interval
+const adminKeyCheckInterval = time.Minute
+
+// API key validation errors
+var (
+	ErrInvalidAPIKey   = errors.New("invalid API key")
+	ErrAPIKeyExpired   = errors.New("API key expired")
+	ErrAPIKeysDisabled = errors.New("API keys are not enabled")
+)
+
+// APIKeyStore is the part of the repository used to authenticate API keys
+type APIKeyStore interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id string) (bool, error)
+}
+
+// UseAPIKeys enables API key authentication backed by the given store
+func (k *OIDCAuth) UseAPIKeys(store APIKeyStore) {
+	k.apiKeys = store
+}
+
+// GenerateAPIKey creates a new random API key and returns the key, its
+// display prefix and the hash to store
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the stored hash of an API key. Keys carry 256 bits of
+// randomness, so a plain SHA-256 is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyAPIKey looks up an API key and maps its owner into UserInfo
+func (k *OIDCAuth) verifyAPIKey(ctx context.Context, key string) (*UserInfo, error) {
+	if k.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	record, err := k.apiKeys.GetAPIKeyByHash(ctx, HashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if record.Expired(now) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > apiKeyTouchInterval {
+		if err := k.apiKeys.TouchAPIKey(ctx, record.ID.Hex(), now); err != nil {
+			k.logger.Warn("Failed to record API key usage", "key", record.Prefix, "error", err)
+		}
+	}
+
+	// A key only carries the admin role if it was granted the admin scope,
+	// and at most for MaxAdminAPIKeyLifetime whatever its expiry
+	roles := slices.Clone(record.Owner.Roles)
+	if !slices.Contains(record.Scopes, models.ScopeAdmin) || now.Sub(record.CreatedAt) >= models.MaxAdminAPIKeyLifetime {
+		roles = slices.DeleteFunc(roles, func(role string) bool { return role == "admin" })
+	}
+
+	return &UserInfo{
+		Sub:               record.UserID,
+		PreferredUsername: record.Owner.Username,
+		Email:             record.Owner.Email,
+		Name:              record.Owner.Name,
+		GivenName:         record.Owner.GivenName,
+		FamilyName:        record.Owner.FamilyName,
+		Roles:             roles,
+		APIKeyID:          record.ID.Hex(),
+		Scopes:            record.Scopes,
+	}, nil
+}
+
+// revokeAdminAPIKeys revokes the admin-scoped API keys of a user whose token
+// no longer carries the admin role
+func (k *OIDCAuth) revokeAdminAPIKeys(ctx context.Context, userID string) {
+	if k.apiKeys == nil {
+		return
+	}
+	now := time.Now()
+	if last, ok := k.adminKeyChecks.Load(userID); ok && now.Sub(last.(time.Time)) < adminKeyCheckInterval {
+		return
+	}
+
+	keys, err := k.apiKeys.ListAPIKeys(ctx, userID)
+	if err != nil {
+		k.logger.Warn("Failed to check API keys of a former admin", "user", userID, "error", err)
+		return
+	}
+	for _, key := range keys {
+		if !slices.Contains(key.Scopes, models.ScopeAdmin) {
+			continue
+		}
+		if _, err := k.apiKeys.DeleteAPIKey(ctx, userID, key.ID.Hex()); err != nil {
+			k.logger.Warn("Failed to revoke admin API key", "user", userID, "key", key.Prefix, "error", err)
+			return
+		}
+		k.logger.Info("Revoked admin API key of a user who lost the admin role", "user", userID, "key", key.Prefix)
+	}
+	k.adminKeyChecks.Store(userID, now)
+}
+
+// HasScope reports whether the request may use the given scope. Bearer
+// tokens are not scoped; API keys are limited to the scopes they were
+// created with.
+func (u *UserInfo) HasScope(scope string) bool {
+	return u.APIKeyID == "" || slices.Contains(u.Scopes, scope)
+}
+
+// RequireScope is a middleware to check that an API key has the given scope
+func (k *OIDCAuth) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Anonymous requests are left to the authentication middleware
+		userInfo, exists := c.Get("userInfo")
+		if exists && !userInfo.(*UserInfo).HasScope(scope) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				StatusCode: http.StatusForbidden,
+				Message:    "Forbidden",
+				Error:      "API key lacks the " + scope + " scope",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DenyAPIKeys is a middleware that only admits interactive bearer tokens,
+// so API keys cannot be used to mint further keys
+func (k *OIDCAuth) DenyAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userInfo, exists := c.Get("userInfo")
+		if exists && userInfo.(*UserInfo).APIKeyID != "" {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				StatusCode: http.StatusForbidden,
+				Message:    "Forbidden",
+				Error:      "API keys cannot be used for this endpoint",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
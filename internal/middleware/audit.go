@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Context keys handlers use to refine the audit event of a request
+const (
+	auditActionKey     = "auditAction"
+	auditTargetTypeKey = "auditTargetType"
+	auditTargetIDKey   = "auditTargetID"
+	auditDetailKey     = "auditDetail"
+)
+
+// AuditRecorder receives audit events; it must not block
+type AuditRecorder interface {
+	Record(event *models.AuditEvent)
+}
+
+// Audit is a middleware that records the request in the audit log once it
+// has been handled. The target defaults to the :id route parameter;
+// handlers refine the event with SetAuditAction, SetAuditTarget and
+// SetAuditDetail. Register it before authentication so that rejected
+// requests are recorded too.
+func Audit(recorder AuditRecorder, action, targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		status := c.Writer.Status()
+		event := &models.AuditEvent{
+			Action:     action,
+			TargetType: targetType,
+			TargetID:   c.Param("id"),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Status:     status,
+			Outcome:    auditOutcome(status),
+			IP:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			CreatedAt:  time.Now(),
+		}
+
+		if userInfo, exists := c.Get("userInfo"); exists {
+			ui := userInfo.(*UserInfo)
+			event.ActorID = ui.Sub
+			event.APIKeyID = ui.APIKeyID
+		}
+		if v := c.GetString(auditActionKey); v != "" {
+			event.Action = v
+		}
+		if v := c.GetString(auditTargetTypeKey); v != "" {
+			event.TargetType = v
+		}
+		if v := c.GetString(auditTargetIDKey); v != "" {
+			event.TargetID = v
+		}
+		event.Detail = c.GetString(auditDetailKey)
+
+		recorder.Record(event)
+	}
+}
+
+// SetAuditAction overrides the audited action of the request
+func SetAuditAction(c *gin.Context, action string) {
+	c.Set(auditActionKey, action)
+}
+
+// SetAuditTarget sets the resource the audited request acted on
+func SetAuditTarget(c *gin.Context, targetType, targetID string) {
+	c.Set(auditTargetTypeKey, targetType)
+	c.Set(auditTargetIDKey, targetID)
+}
+
+// SetAuditDetail adds free-form detail, such as the analyzed URL, to the
+// audit event of the request
+func SetAuditDetail(c *gin.Context, detail string) {
+	c.Set(auditDetailKey, detail)
+}
+
+// auditOutcome classifies a response status
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.AuditDenied
+	case status >= http.StatusBadRequest:
+		return models.AuditFailure
+	default:
+		return models.AuditSuccess
+	}
+}
@@ -7,86 +7,172 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
 	/*"log.o/slog"*/)
 
-// KeycloakAuth represents the Keycloak authentication middleware
-type KeycloakAuth struct {
-	keycloakConfig *config.KeycloakConfig
-	logger         *slog.Logger
-	jwksCache      map[string]interface{}
-	cacheTime      time.Time
+// OIDCAuth represents the OpenID Connect authentication middleware
+type OIDCAuth struct {
+	logger      *slog.Logger
+	verifier    *tokenVerifier
+	userIDClaim string
+	rolesClaim  string
+	apiKeys     APIKeyStore
+	// adminKeyChecks holds when each user's admin keys were last checked
+	adminKeyChecks sync.Map
 }
 
-// UserInfo contains the user information from the JWT token
+// UserInfo contains the user information from the JWT token. Sub holds the
+// user ID read from the configured claim and Roles the configured role claim.
 type UserInfo struct {
-	Sub               string `json:"sub"`
-	PreferredUsername string `json:"preferred_username"`
-	Email             string `json:"email"`
-	Name              string `json:"name"`
-	GivenName         string `json:"given_name"`
-	FamilyName        string `json:"family_name"`
-	RealmAccess       struct {
-		Roles []string `json:"roles"`
-	} `json:"realm_access"`
+	Sub               string   `json:"sub"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	Name              string   `json:"name"`
+	GivenName         string   `json:"given_name"`
+	FamilyName        string   `json:"family_name"`
+	Roles             []string `json:"roles"`
+
+	// Set when the request was authenticated with an API key
+	APIKeyID string   `json:"-"`
+	Scopes   []string `json:"-"`
 }
 
-// NewKeycloakAuth creates a new Keycloak authentication middleware
-func NewKeycloakAuth(keycloakConfig *config.KeycloakConfig, logger *slog.Logger) *KeycloakAuth {
-	return &KeycloakAuth{
-		keycloakConfig: keycloakConfig,
-		logger:         logger,
-		jwksCache:      make(map[string]interface{}),
+// HasRole reports whether the user has the given role
+func (u *UserInfo) HasRole(role string) bool {
+	return slices.Contains(u.Roles, role)
+}
+
+// Claim paths used by the Keycloak preset
+const (
+	keycloakUserIDClaim = "sub"
+	keycloakRolesClaim  = "realm_access.roles"
+)
+
+// NewOIDCAuth creates an authentication middleware for any OpenID Connect
+// provider that publishes a discovery document under its issuer URL
+func NewOIDCAuth(oidcConfig *config.OIDCConfig, logger *slog.Logger) *OIDCAuth {
+	issuer := strings.TrimSuffix(oidcConfig.IssuerURL, "/")
+	return newOIDCAuth([]string{issuer}, oidcConfig.Audience, oidcConfig.JWKSCacheTTL,
+		oidcConfig.UserIDClaim, oidcConfig.RolesClaim, logger)
+}
+
+// NewKeycloakAuth creates an OIDC authentication middleware preset for a
+// Keycloak realm
+func NewKeycloakAuth(keycloakConfig *config.KeycloakConfig, logger *slog.Logger) *OIDCAuth {
+	// Tokens carry the public (fallback) URL as issuer while the backend may
+	// reach Keycloak through an internal hostname, so accept both
+	var issuers []string
+	for _, baseURL := range []string{keycloakConfig.URL, keycloakConfig.FallbackURL} {
+		if baseURL == "" {
+			continue
+		}
+		issuer := fmt.Sprintf("%s/realms/%s", strings.TrimSuffix(baseURL, "/"), keycloakConfig.Realm)
+		if !slices.Contains(issuers, issuer) {
+			issuers = append(issuers, issuer)
+		}
+	}
+
+	return newOIDCAuth(issuers, keycloakConfig.Audience, keycloakConfig.JWKSCacheTTL,
+		keycloakUserIDClaim, keycloakRolesClaim, logger)
+}
+
+// newOIDCAuth wires the token verifier for the given issuers
+func newOIDCAuth(issuers []string, audience string, cacheTTL time.Duration, userIDClaim, rolesClaim string, logger *slog.Logger) *OIDCAuth {
+	if userIDClaim == "" {
+		userIDClaim = "sub"
+	}
+
+	return &OIDCAuth{
+		logger: logger,
+		verifier: &tokenVerifier{
+			keys:     newJWKSCache(issuers, cacheTTL, logger),
+			issuers:  issuers,
+			audience: audience,
+		},
+		userIDClaim: userIDClaim,
+		rolesClaim:  rolesClaim,
 	}
 }
 
-// Authenticate is a middleware to authenticate users with Keycloak
-func (k *KeycloakAuth) Authenticate() gin.HandlerFunc {
+// Authenticate is a middleware to authenticate users with bearer tokens or
+// API keys
+func (k *OIDCAuth) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := extractToken(c.Request)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status_code": http.StatusUnauthorized,
-				"message":     "Unauthorized",
-				"error":       "Invalid or missing token",
-			})
-			c.Abort()
-			return
-		}
+		k.authenticate(c, false)
+	}
+}
 
-		// Verify token
-		userInfo, err := k.verifyToken(c.Request.Context(), token)
-		if err != nil {
-			k.logger.Error("Failed to verify token", "error", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status_code": http.StatusUnauthorized,
-				"message":     "Unauthorized",
-				"error":       "Invalid token",
-			})
-			c.Abort()
-			return
-		}
+// OptionalAuthenticate authenticates requests that carry credentials and
+// lets anonymous requests through. Invalid credentials are still rejected.
+func (k *OIDCAuth) OptionalAuthenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k.authenticate(c, true)
+	}
+}
 
-		// Set user info in context
-		c.Set("userInfo", userInfo)
+// authenticate verifies the request credentials and stores the user info
+func (k *OIDCAuth) authenticate(c *gin.Context, optional bool) {
+	if optional && c.GetHeader("Authorization") == "" && c.GetHeader(APIKeyHeader) == "" {
 		c.Next()
+		return
+	}
+
+	credential, isAPIKey, err := extractCredential(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "Unauthorized",
+			Error:      "Invalid or missing token",
+		})
+		c.Abort()
+		return
+	}
+
+	// Verify token or API key
+	var userInfo *UserInfo
+	if isAPIKey {
+		userInfo, err = k.verifyAPIKey(c.Request.Context(), credential)
+	} else {
+		userInfo, err = k.verifyToken(c.Request.Context(), credential)
+	}
+	if err != nil {
+		k.logger.Error("Failed to verify credentials", "api_key", isAPIKey, "error", err)
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Message:    "Unauthorized",
+			Error:      "Invalid token",
+		})
+		c.Abort()
+		return
+	}
+
+	// Tokens carry the current roles; admin keys must not outlive the role
+	if !isAPIKey && !userInfo.HasRole("admin") {
+		k.revokeAdminAPIKeys(c.Request.Context(), userInfo.Sub)
 	}
+
+	// Set user info in context
+	c.Set("userInfo", userInfo)
+	c.Next()
 }
 
 // RequireRoles is a middleware to check if the user has the required roles
-func (k *KeycloakAuth) RequireRoles(roles ...string) gin.HandlerFunc {
+func (k *OIDCAuth) RequireRoles(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get user info from context
 		userInfo, exists := c.Get("userInfo")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status_code": http.StatusUnauthorized,
-				"message":     "Unauthorized",
-				"error":       "User not authenticated",
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				StatusCode: http.StatusUnauthorized,
+				Message:    "Unauthorized",
+				Error:      "User not authenticated",
 			})
 			c.Abort()
 			return
@@ -95,10 +181,10 @@ func (k *KeycloakAuth) RequireRoles(roles ...string) gin.HandlerFunc {
 		// Check roles
 		user := userInfo.(*UserInfo)
 		if !hasRequiredRole(user, roles) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"status_code": http.StatusForbidden,
-				"message":     "Forbidden",
-				"error":       "Insufficient permissions",
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				StatusCode: http.StatusForbidden,
+				Message:    "Forbidden",
+				Error:      "Insufficient permissions",
 			})
 			c.Abort()
 			return
@@ -108,94 +194,49 @@ func (k *KeycloakAuth) RequireRoles(roles ...string) gin.HandlerFunc {
 	}
 }
 
-// extractToken extracts the bearer token from the Authorization header
-func extractToken(r *http.Request) (string, error) {
+// extractCredential extracts a bearer token or API key from the request.
+// API keys are sent in the X-API-Key header or as a prefixed bearer token.
+func extractCredential(r *http.Request) (string, bool, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key, true, nil
+	}
+
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return "", errors.New("authorization header is missing")
+		return "", false, errors.New("authorization header is missing")
 	}
 
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return "", errors.New("invalid authorization header format")
+		return "", false, errors.New("invalid authorization header format")
 	}
 
-	return parts[1], nil
+	return parts[1], strings.HasPrefix(parts[1], APIKeyPrefix), nil
 }
 
-// verifyToken verifies the JWT token with Keycloak
-func (k *KeycloakAuth) verifyToken(ctx context.Context, token string) (*UserInfo, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-
-	// Create request to userinfo endpoint
-	userinfoURL := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/userinfo",
-		k.keycloakConfig.URL,
-		k.keycloakConfig.Realm)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", userinfoURL, nil)
+// verifyToken verifies the JWT signature and claims locally and maps the
+// claims into UserInfo
+func (k *OIDCAuth) verifyToken(ctx context.Context, token string) (*UserInfo, error) {
+	payload, err := k.verifier.verify(ctx, token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	// Add token to request
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	// Send request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get userinfo: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		// Try with the fallback URL if the main URL fails
-		if fallbackURL := k.keycloakConfig.FallbackURL; fallbackURL != "" && fallbackURL != k.keycloakConfig.URL {
-			k.logger.Info("Using fallback URL for token verification",
-				"main", k.keycloakConfig.URL,
-				"fallback", fallbackURL)
-
-			// Create a new request with the fallback URL
-			fallbackUserinfoURL := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/userinfo",
-				fallbackURL,
-				k.keycloakConfig.Realm)
-
-			fallbackReq, err := http.NewRequestWithContext(ctx, "GET", fallbackUserinfoURL, nil)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create fallback request: %w", err)
-			}
-
-			// Add token to request
-			fallbackReq.Header.Set("Authorization", "Bearer "+token)
-
-			// Send fallback request
-			fallbackResp, err := client.Do(fallbackReq)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get userinfo with fallback URL: %w", err)
-			}
-			defer fallbackResp.Body.Close()
-
-			if fallbackResp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("invalid token or userinfo request failed: %d", fallbackResp.StatusCode)
-			}
-
-			// Parse response from fallback
-			var userInfo UserInfo
-			if err := json.NewDecoder(fallbackResp.Body).Decode(&userInfo); err != nil {
-				return nil, fmt.Errorf("failed to parse userinfo response from fallback: %w", err)
-			}
-
-			return &userInfo, nil
-		}
+	var userInfo UserInfo
+	if err := json.Unmarshal(payload, &userInfo); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
 
-		return nil, fmt.Errorf("invalid token or userinfo request failed: %d", resp.StatusCode)
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
 	}
 
-	// Parse response
-	var userInfo UserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse userinfo response: %w", err)
+	userInfo.Sub = claimString(claims, k.userIDClaim)
+	if userInfo.Sub == "" {
+		return nil, fmt.Errorf("token has no %q claim", k.userIDClaim)
 	}
+	userInfo.Roles = claimStrings(claims, k.rolesClaim)
 
 	return &userInfo, nil
 }
@@ -206,12 +247,9 @@ func hasRequiredRole(user *UserInfo, requiredRoles []string) bool {
 		return true
 	}
 
-	userRoles := user.RealmAccess.Roles
 	for _, required := range requiredRoles {
-		for _, role := range userRoles {
-			if role == required {
-				return true
-			}
+		if user.HasRole(required) {
+			return true
 		}
 	}
 
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// lookupClaim resolves a dot-separated claim path such as
+// "resource_access.web-analyzer.roles". Keys may themselves contain dots
+// (e.g. namespaced claims like "https://example.com/roles"), so the longest
+// matching key is tried first at every level.
+func lookupClaim(claims map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	if value, ok := claims[path]; ok {
+		return value, true
+	}
+
+	for i := strings.LastIndex(path, "."); i > 0; i = strings.LastIndex(path[:i], ".") {
+		nested, ok := claims[path[:i]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := lookupClaim(nested, path[i+1:]); ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// claimString returns a claim as a string; numeric IDs are formatted as-is
+func claimString(claims map[string]interface{}, path string) string {
+	value, _ := lookupClaim(claims, path)
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return ""
+	}
+}
+
+// claimStrings returns a claim holding a list of strings. A single string is
+// treated as a space-separated list, as used by the "scope" claim.
+func claimStrings(claims map[string]interface{}, path string) []string {
+	value, _ := lookupClaim(claims, path)
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeKey is the context key of the canonical route of an aliased request
+const routeKey = "route"
+
+// Deprecated is a middleware for routes under a deprecated path prefix that
+// alias the same routes under successor. Responses carry a Deprecation
+// header and link to the successor path, and the request is treated as the
+// successor route, e.g. when rate limiting.
+func Deprecated(prefix, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+strings.TrimPrefix(c.Request.URL.Path, prefix)+`>; rel="successor-version"`)
+		c.Set(routeKey, successor+strings.TrimPrefix(c.FullPath(), prefix))
+		c.Next()
+	}
+}
+
+// Route returns the route pattern of the request, resolving deprecated
+// aliases to their successor
+func Route(c *gin.Context) string {
+	if route := c.GetString(routeKey); route != "" {
+		return route
+	}
+	return c.FullPath()
+}
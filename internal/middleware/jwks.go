@@ -0,0 +1,319 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when a token is signed with a key that is not in
+// the provider's JWKS, even after refetching it
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// defaultJWKSCacheTTL is used when no cache lifetime is configured
+const defaultJWKSCacheTTL = time.Hour
+
+// jwksMinRefreshInterval limits how often an unknown kid may force a JWKS
+// refetch, so forged tokens cannot be used to hammer the provider
+const jwksMinRefreshInterval = 10 * time.Second
+
+// jwksFetchTimeout bounds a JWKS refresh, discovery included
+const jwksFetchTimeout = 10 * time.Second
+
+// jwksCache discovers an OIDC provider's JWKS and caches its signing keys
+type jwksCache struct {
+	issuerURLs []string
+	client     *http.Client
+	ttl        time.Duration
+	logger     *slog.Logger
+
+	mu         sync.Mutex
+	issuer     string
+	jwksURI    string
+	keys       map[string]crypto.PublicKey
+	fetchedAt  time.Time
+	lastForced time.Time
+	inflight   *jwksFetch
+}
+
+// jwksFetch is a JWKS refresh shared by every caller waiting for it
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// newJWKSCache creates a JWKS cache. issuerURLs are tried in order for OIDC
+// discovery; the first one that answers is used.
+func newJWKSCache(issuerURLs []string, ttl time.Duration, logger *slog.Logger) *jwksCache {
+	if ttl <= 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+
+	return &jwksCache{
+		issuerURLs: issuerURLs,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		ttl:        ttl,
+		logger:     logger,
+	}
+}
+
+// key returns the public key for a kid, refetching the JWKS when the cache
+// has expired or the kid is unknown (key rotation)
+func (j *jwksCache) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	stale := j.keys == nil || time.Since(j.fetchedAt) > j.ttl
+	j.mu.Unlock()
+
+	refreshed := false
+	if stale {
+		if err := j.refresh(ctx); err != nil {
+			if !j.hasKeys() {
+				return nil, err
+			}
+			// Keep serving the stale keys while the provider is unreachable
+			j.logger.Warn("Failed to refresh JWKS, using cached keys", "error", err)
+		} else {
+			refreshed = true
+		}
+	}
+
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	force := !ok && !refreshed && time.Since(j.lastForced) >= jwksMinRefreshInterval
+	if force {
+		j.lastForced = time.Now()
+	}
+	j.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if !force {
+		return nil, ErrUnknownKey
+	}
+
+	if err := j.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// hasKeys reports whether any keys have been fetched yet
+func (j *jwksCache) hasKeys() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.keys != nil
+}
+
+// discoveredIssuer returns the issuer announced by the discovery document
+func (j *jwksCache) discoveredIssuer() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.issuer
+}
+
+// refresh waits for a JWKS refresh, starting one unless another caller
+// already has. The fetch runs without the lock and on its own context, so
+// a slow provider does not block requests that find their key in the cache
+// and a disconnecting client does not cancel it for the others.
+func (j *jwksCache) refresh(ctx context.Context) error {
+	j.mu.Lock()
+	call := j.inflight
+	if call == nil {
+		call = &jwksFetch{done: make(chan struct{})}
+		j.inflight = call
+		go j.fetch(call)
+	}
+	j.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fetch downloads the JWKS, running OIDC discovery first if needed, and
+// stores the keys
+func (j *jwksCache) fetch(call *jwksFetch) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	j.mu.Lock()
+	jwksURI := j.jwksURI
+	j.mu.Unlock()
+
+	var keys map[string]crypto.PublicKey
+	var err error
+	if jwksURI == "" {
+		var issuer string
+		if issuer, jwksURI, err = j.discover(ctx); err == nil {
+			j.mu.Lock()
+			j.issuer, j.jwksURI = issuer, jwksURI
+			j.mu.Unlock()
+		}
+	}
+	if err == nil {
+		keys, err = j.fetchKeys(ctx, jwksURI)
+	}
+
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+		j.fetchedAt = time.Now()
+	}
+	j.inflight = nil
+	j.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// fetchKeys downloads a JWKS and parses its signing keys
+func (j *jwksCache) fetchKeys(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := j.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			j.logger.Warn("Skipping invalid JWK", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// discover reads the OIDC discovery document to locate the JWKS, returning
+// the announced issuer and the JWKS URI
+func (j *jwksCache) discover(ctx context.Context) (issuer, jwksURI string, err error) {
+	var lastErr error
+	for _, issuerURL := range j.issuerURLs {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+		if err := j.getJSON(ctx, discoveryURL, &doc); err != nil {
+			lastErr = err
+			continue
+		}
+		if doc.JWKSURI == "" {
+			lastErr = fmt.Errorf("discovery document at %s has no jwks_uri", discoveryURL)
+			continue
+		}
+
+		return doc.Issuer, doc.JWKSURI, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no issuer URL configured")
+	}
+	return "", "", fmt.Errorf("OIDC discovery failed: %w", lastErr)
+}
+
+// getJSON fetches a URL and decodes its JSON body
+func (j *jwksCache) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jsonWebKey is a single key of a JWKS (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey converts an RSA or P-256 EC JWK into a Go public key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("unsupported RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, errors.New("invalid x coordinate")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, errors.New("invalid y coordinate")
+		}
+		// Reject points that are not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("invalid EC point: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Token validation errors
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token expired")
+	ErrTokenNotYetValid     = errors.New("token not yet valid")
+	ErrInvalidIssuer        = errors.New("invalid token issuer")
+	ErrInvalidAudience      = errors.New("invalid token audience")
+)
+
+// clockSkew is the leeway applied to exp and nbf checks
+const clockSkew = 30 * time.Second
+
+// tokenVerifier validates JWT access tokens locally against a provider's JWKS
+type tokenVerifier struct {
+	keys     *jwksCache
+	issuers  []string
+	audience string
+}
+
+// jwtHeader is the JOSE header of a signed token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// registeredClaims holds the standard claims checked during validation
+type registeredClaims struct {
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	AuthParty string   `json:"azp"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// audience accepts the aud claim as either a string or an array of strings
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// verify checks the token signature and registered claims and returns the
+// raw claims payload
+func (v *tokenVerifier) verify(ctx context.Context, token string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, header.Alg)
+	}
+
+	key, err := v.keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims registeredClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// validateClaims checks exp, nbf, iss and aud
+func (v *tokenVerifier) validateClaims(claims registeredClaims) error {
+	now := time.Now()
+
+	if claims.ExpiresAt == nil || now.After(unixTime(*claims.ExpiresAt).Add(clockSkew)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(unixTime(*claims.NotBefore)) {
+		return ErrTokenNotYetValid
+	}
+
+	issuers := v.issuers
+	if discovered := v.keys.discoveredIssuer(); discovered != "" {
+		issuers = append(slices.Clone(issuers), strings.TrimSuffix(discovered, "/"))
+	}
+	if !slices.Contains(issuers, strings.TrimSuffix(claims.Issuer, "/")) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, claims.Issuer)
+	}
+
+	// The token must be meant for us, either as audience or authorized party
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) && claims.AuthParty != v.audience {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+// verifySignature checks an RS256 or ES256 signature over a SHA-256 digest
+func verifySignature(alg string, key crypto.PublicKey, digest, signature []byte) error {
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 with non-RSA key", ErrInvalidSignature)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature); err != nil {
+			return ErrInvalidSignature
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: ES256 with non-EC key", ErrInvalidSignature)
+		}
+		// JWS encodes ECDSA signatures as the fixed-size concatenation r || s
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url JSON token segment
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// unixTime converts a NumericDate claim to a time
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
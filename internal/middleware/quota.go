@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// QuotaStore is the part of the repository used to enforce quotas
+type QuotaStore interface {
+	GetUserPlan(ctx context.Context, userID string) (string, error)
+	IncrementQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error)
+	GetQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error)
+}
+
+// Quotas enforces the daily and monthly analysis quotas of each caller's
+// plan. Users, including through their API keys, are on the plan assigned
+// to them or the default plan; anonymous callers are counted per client IP
+// on the anonymous plan.
+type Quotas struct {
+	config config.RateLimitConfig
+	store  QuotaStore
+	logger *slog.Logger
+}
+
+// NewQuotas creates quota enforcement backed by the given store
+func NewQuotas(cfg config.RateLimitConfig, store QuotaStore, logger *slog.Logger) *Quotas {
+	return &Quotas{
+		config: cfg,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Middleware returns a middleware that counts the request against the
+// caller's quotas and rejects it once a quota is exhausted. Every attempt
+// counts, whether or not the analysis succeeds. If the store is unavailable
+// the request is let through.
+func (q *Quotas) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !q.config.Enabled {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		subject, userID := quotaSubject(c)
+		name, plan, err := q.plan(ctx, userID)
+		if err != nil {
+			q.logger.Error("Failed to get quota plan", "subject", subject, "error", err)
+			c.Next()
+			return
+		}
+		if plan.Daily == 0 && plan.Monthly == 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		status := quotaCounters(now, plan)
+		counts, err := q.store.IncrementQuotaUsage(ctx, subject, []string{status.Daily.Period, status.Monthly.Period})
+		if err != nil {
+			q.logger.Error("Failed to count quota usage", "subject", subject, "error", err)
+			c.Next()
+			return
+		}
+		status.Daily.Used, status.Monthly.Used = counts[0], counts[1]
+
+		periods := []struct {
+			header, name string
+			counter      *models.QuotaCounter
+		}{
+			{"Month", "monthly", &status.Monthly},
+			{"Day", "daily", &status.Daily},
+		}
+
+		exceeded := ""
+		var resetsAt time.Time
+		for _, p := range periods {
+			if p.counter.Limit == 0 {
+				continue
+			}
+			remaining := max(int64(p.counter.Limit)-p.counter.Used, 0)
+			c.Header("X-Quota-Limit-"+p.header, strconv.Itoa(p.counter.Limit))
+			c.Header("X-Quota-Remaining-"+p.header, strconv.FormatInt(remaining, 10))
+			c.Header("X-Quota-Reset-"+p.header, strconv.FormatInt(p.counter.ResetsAt.Unix(), 10))
+
+			if exceeded == "" && p.counter.Used > int64(p.counter.Limit) {
+				exceeded, resetsAt = p.name, p.counter.ResetsAt
+			}
+		}
+
+		if exceeded != "" {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(resetsAt.Sub(now).Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				StatusCode: http.StatusTooManyRequests,
+				Code:       models.CodeQuotaExceeded,
+				Message:    "Analysis quota exceeded",
+				Error:      "the " + exceeded + " analysis quota of the " + name + " plan is used up",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Status reports the caller's plan and current usage without counting
+func (q *Quotas) Status(c *gin.Context) (*models.QuotaStatus, error) {
+	ctx := c.Request.Context()
+	subject, userID := quotaSubject(c)
+	name, plan, err := q.plan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := quotaCounters(time.Now(), plan)
+	status.Plan = name
+	counts, err := q.store.GetQuotaUsage(ctx, subject, []string{status.Daily.Period, status.Monthly.Period})
+	if err != nil {
+		return nil, err
+	}
+	status.Daily.Used, status.Monthly.Used = counts[0], counts[1]
+	return status, nil
+}
+
+// HasPlan reports whether a plan with the given name is configured
+func (q *Quotas) HasPlan(name string) bool {
+	_, ok := q.config.Plans[name]
+	return ok
+}
+
+// plan resolves the quota plan of a user, or of anonymous callers if
+// userID is empty. Plans that are no longer configured fall back to the
+// default plan.
+func (q *Quotas) plan(ctx context.Context, userID string) (string, config.QuotaPlan, error) {
+	if userID == "" {
+		return config.AnonymousPlan, q.config.Plans[config.AnonymousPlan], nil
+	}
+
+	name, err := q.store.GetUserPlan(ctx, userID)
+	if err != nil {
+		return "", config.QuotaPlan{}, err
+	}
+	plan, ok := q.config.Plans[name]
+	if !ok {
+		name = q.config.DefaultPlan
+		plan = q.config.Plans[name]
+	}
+	return name, plan, nil
+}
+
+// quotaSubject identifies whose quota a request counts against. API keys
+// count towards their owner.
+func quotaSubject(c *gin.Context) (subject, userID string) {
+	if userInfo, exists := c.Get("userInfo"); exists {
+		ui := userInfo.(*UserInfo)
+		return "user:" + ui.Sub, ui.Sub
+	}
+	return "ip:" + c.ClientIP(), ""
+}
+
+// quotaCounters returns the UTC day and month periods containing now
+func quotaCounters(now time.Time, plan config.QuotaPlan) *models.QuotaStatus {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return &models.QuotaStatus{
+		Daily: models.QuotaCounter{
+			Period:   "day:" + day.Format("2006-01-02"),
+			Limit:    plan.Daily,
+			ResetsAt: day.AddDate(0, 0, 1),
+		},
+		Monthly: models.QuotaCounter{
+			Period:   "month:" + month.Format("2006-01"),
+			Limit:    plan.Monthly,
+			ResetsAt: month.AddDate(0, 1, 0),
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// rateLimitIdleTTL is how long an unused bucket is kept before it is dropped
+const rateLimitIdleTTL = 10 * time.Minute
+
+// RateLimiter throttles requests with a token bucket per caller and route.
+// Callers are identified by API key, user or client IP, in that order.
+type RateLimiter struct {
+	config config.RateLimitConfig
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+// rateBucket is a caller's token bucket for one route
+type rateBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiter for the given configuration
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		config:    cfg,
+		buckets:   make(map[string]*rateBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware returns the rate limiting middleware. Register it after
+// authentication so that authenticated callers get their own bucket rather
+// than sharing one per IP.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.config.Enabled {
+			c.Next()
+			return
+		}
+
+		route := c.Request.Method + " " + Route(c)
+		limit, ok := l.config.Routes[route]
+		if !ok {
+			limit = l.config.Default
+		}
+		if limit.Rate <= 0 || limit.Burst <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		limiter := l.bucket(callerKey(c)+" "+route, limit, now)
+
+		reservation := limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if delay > 0 {
+			reservation.CancelAt(now)
+		}
+
+		remaining := int(math.Max(0, math.Floor(limiter.TokensAt(now))))
+		refill := time.Duration(float64(limit.Burst-remaining) / limit.Rate * float64(time.Second))
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(now.Add(refill).Unix(), 10))
+
+		if delay > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				StatusCode: http.StatusTooManyRequests,
+				Code:       models.CodeRateLimited,
+				Message:    "Rate limit exceeded",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// bucket returns the limiter for key, creating it on first use, and drops
+// buckets that have been idle for a while
+func (l *RateLimiter) bucket(key string, limit config.RateLimit, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitIdleTTL {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > rateLimitIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
+
+// callerKey identifies the caller of a request for rate limiting
+func callerKey(c *gin.Context) string {
+	if userInfo, exists := c.Get("userInfo"); exists {
+		ui := userInfo.(*UserInfo)
+		if ui.APIKeyID != "" {
+			return "key:" + ui.APIKeyID
+		}
+		return "user:" + ui.Sub
+	}
+	return "ip:" + c.ClientIP()
+}
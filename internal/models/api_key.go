@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// API key scopes
+const (
+	ScopeAnalyze = "analyze" // run analyses and manage the owner's analyses
+	ScopeRead    = "read"    // read analyses, deep analyses and search
+	ScopeAdmin   = "admin"   // admin endpoints; requires the owner to be an admin
+)
+
+// APIKeyScopes lists every valid API key scope
+var APIKeyScopes = []string{ScopeAnalyze, ScopeRead, ScopeAdmin}
+
+// MaxAdminAPIKeyLifetime bounds how long keys with the admin scope grant
+// admin rights. The owner's roles are a snapshot taken at creation, so the
+// admin role must not outlive its removal in the identity provider by long.
+const MaxAdminAPIKeyLifetime = 7 * 24 * time.Hour
+
+// APIKey is a personal API key for machine clients. Only a hash of the key
+// is stored; the owner's profile is captured at creation so requests made
+// with the key carry the same identity as the owner's tokens.
+type APIKey struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     string             `json:"user_id" bson:"user_id"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"`
+	Hash       string             `json:"-" bson:"hash"`
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	Owner      APIKeyOwner        `json:"-" bson:"owner"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// APIKeyOwner is the profile of the user who created an API key
+type APIKeyOwner struct {
+	Username   string   `json:"preferred_username" bson:"username"`
+	Email      string   `json:"email" bson:"email"`
+	Name       string   `json:"name" bson:"name"`
+	GivenName  string   `json:"given_name" bson:"given_name"`
+	FamilyName string   `json:"family_name" bson:"family_name"`
+	Roles      []string `json:"roles" bson:"roles"`
+}
+
+// Expired reports whether the key has expired at the given time
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// APIKeyRequest represents the request to create an API key
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatedAPIKey is returned once when a key is created; the plaintext key
+// cannot be retrieved again
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
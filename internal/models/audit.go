@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audited actions
+const (
+	AuditAnalyze      = "analyze"        // analyze a URL
+	AuditView         = "view"           // read an analysis or deep analysis
+	AuditList         = "list"           // list or search analyses
+	AuditDeepAnalyze  = "deep_analyze"   // run a new deep analysis
+	AuditDelete       = "delete"         // delete analyses or user data
+	AuditAPIKeyCreate = "api_key.create" // create a personal API key
+	AuditAPIKeyRevoke = "api_key.revoke" // revoke a personal API key
+	AuditOrganization = "organization"   // change an organization, its members or projects
+	AuditAdminAccess  = "admin_access"   // call an admin-only endpoint
+)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success" // the request succeeded
+	AuditDenied  = "denied"  // authentication or authorization failed
+	AuditFailure = "failure" // the request was invalid or failed
+)
+
+// AuditEvent is an append-only record of a security-relevant request
+type AuditEvent struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ActorID    string             `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	APIKeyID   string             `json:"api_key_id,omitempty" bson:"api_key_id,omitempty"`
+	Action     string             `json:"action" bson:"action"`
+	TargetType string             `json:"target_type,omitempty" bson:"target_type,omitempty"`
+	TargetID   string             `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	Detail     string             `json:"detail,omitempty" bson:"detail,omitempty"`
+	Method     string             `json:"method" bson:"method"`
+	Path       string             `json:"path" bson:"path"`
+	Status     int                `json:"status" bson:"status"`
+	Outcome    string             `json:"outcome" bson:"outcome"`
+	IP         string             `json:"ip" bson:"ip"`
+	UserAgent  string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// AuditFilter narrows down an audit log query. Zero values are ignored.
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Outcome    string
+	From       time.Time
+	To         time.Time
+}
+
+// AuditQuery describes a page of the audit log, newest events first
+type AuditQuery struct {
+	Filter AuditFilter
+	Limit  int
+	Cursor string
+}
+
+// AuditPage is a page of audit events together with the cursor of the next page
+type AuditPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
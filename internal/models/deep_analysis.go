@@ -13,6 +13,11 @@ type DeepAnalysisResult struct {
 	URL        string             `json:"url" bson:"url"`
 	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
 
+	// Each deep run is stored as a new version of the analysis
+	Version         int    `json:"version" bson:"version"`
+	AnalyzerVersion string `json:"analyzer_version" bson:"analyzer_version"`
+	RulesVersion    string `json:"rules_version" bson:"rules_version"`
+
 	// 1. Performance metrics
 	Performance PerformanceMetrics `json:"performance" bson:"performance"`
 
@@ -48,6 +53,19 @@ type DeepAnalysisResult struct {
 
 	// 12. Link Analysis
 	Links LinkAnalysis `json:"links" bson:"links"`
+
+	// 13. Privacy
+	Privacy PrivacyAnalysis `json:"privacy" bson:"privacy"`
+}
+
+// DeepAnalysisVersion summarises a stored deep analysis run
+type DeepAnalysisVersion struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id"`
+	AnalysisID      primitive.ObjectID `json:"analysis_id" bson:"analysis_id"`
+	Version         int                `json:"version" bson:"version"`
+	AnalyzerVersion string             `json:"analyzer_version" bson:"analyzer_version"`
+	RulesVersion    string             `json:"rules_version" bson:"rules_version"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
 }
 
 // PerformanceMetrics represents performance metrics of a webpage
@@ -102,9 +120,163 @@ type ContentAnalysis struct {
 
 // SecurityAnalysis represents security-related information
 type SecurityAnalysis struct {
-	HTTPS         bool `json:"https" bson:"https"`
-	CSPHeaders    bool `json:"cspHeaders" bson:"csp_headers"`
-	XSSProtection bool `json:"xssProtection" bson:"xss_protection"`
+	HTTPS         bool                 `json:"https" bson:"https"`
+	CSPHeaders    bool                 `json:"cspHeaders" bson:"csp_headers"`
+	XSSProtection bool                 `json:"xssProtection" bson:"xss_protection"`
+	Headers       []SecurityCheck      `json:"headers" bson:"headers"`
+	CSP           CSPAnalysis          `json:"csp" bson:"csp"`
+	TLS           TLSAnalysis          `json:"tls" bson:"tls"`
+	MixedContent  MixedContentAnalysis `json:"mixedContent" bson:"mixed_content"`
+	Forms         FormAnalysis         `json:"forms" bson:"forms"`
+	Dependencies  DependencyAnalysis   `json:"dependencies" bson:"dependencies"`
+	Score         int                  `json:"score" bson:"score"` // 0-100
+	Grade         string               `json:"grade" bson:"grade"` // A+ to F
+}
+
+// Security check verdicts
+const (
+	VerdictPass = "pass"
+	VerdictWarn = "warn"
+	VerdictFail = "fail"
+)
+
+// SecurityCheck is the verdict of one security check, e.g. of a response header
+type SecurityCheck struct {
+	Name        string `json:"name" bson:"name"`
+	Value       string `json:"value,omitempty" bson:"value,omitempty"`
+	Verdict     string `json:"verdict" bson:"verdict"`
+	Explanation string `json:"explanation" bson:"explanation"`
+}
+
+// CSPAnalysis describes the content security policies of a page and
+// whether they allow its scripts
+type CSPAnalysis struct {
+	Policies []CSPPolicy `json:"policies" bson:"policies"`
+	Scripts  []CSPScript `json:"scripts" bson:"scripts"`
+}
+
+// CSPPolicy is a parsed content security policy
+type CSPPolicy struct {
+	Source     string              `json:"source" bson:"source"` // header or meta
+	ReportOnly bool                `json:"reportOnly" bson:"report_only"`
+	Raw        string              `json:"raw" bson:"raw"`
+	Directives map[string][]string `json:"directives" bson:"directives"`
+	Weaknesses []SecurityCheck     `json:"weaknesses" bson:"weaknesses"`
+}
+
+// CSPScript tells whether the policies allow a script of the page
+type CSPScript struct {
+	URL     string `json:"url,omitempty" bson:"url,omitempty"` // empty for inline scripts
+	Inline  bool   `json:"inline" bson:"inline"`
+	Allowed bool   `json:"allowed" bson:"allowed"`
+	Reason  string `json:"reason,omitempty" bson:"reason,omitempty"`
+}
+
+// TLSAnalysis describes the TLS connection an HTTPS page was served over
+type TLSAnalysis struct {
+	Enabled           bool              `json:"enabled" bson:"enabled"`
+	Version           string            `json:"version,omitempty" bson:"version,omitempty"`
+	CipherSuite       string            `json:"cipherSuite,omitempty" bson:"cipher_suite,omitempty"`
+	ALPN              string            `json:"alpn,omitempty" bson:"alpn,omitempty"`
+	Trusted           bool              `json:"trusted" bson:"trusted"`
+	VerificationError string            `json:"verificationError,omitempty" bson:"verification_error,omitempty"`
+	SelfSigned        bool              `json:"selfSigned" bson:"self_signed"`
+	HostnameMismatch  bool              `json:"hostnameMismatch" bson:"hostname_mismatch"`
+	Expired           bool              `json:"expired" bson:"expired"`
+	DaysUntilExpiry   int               `json:"daysUntilExpiry" bson:"days_until_expiry"`
+	Chain             []CertificateInfo `json:"chain,omitempty" bson:"chain,omitempty"` // leaf first
+	Checks            []SecurityCheck   `json:"checks,omitempty" bson:"checks,omitempty"`
+}
+
+// CertificateInfo describes a certificate of a TLS certificate chain
+type CertificateInfo struct {
+	Subject   string    `json:"subject" bson:"subject"`
+	Issuer    string    `json:"issuer" bson:"issuer"`
+	SANs      []string  `json:"sans" bson:"sans"`
+	NotBefore time.Time `json:"notBefore" bson:"not_before"`
+	NotAfter  time.Time `json:"notAfter" bson:"not_after"`
+	KeyType   string    `json:"keyType" bson:"key_type"`
+	KeySize   int       `json:"keySize" bson:"key_size"` // in bits
+	IsCA      bool      `json:"isCa" bson:"is_ca"`
+}
+
+// Mixed content types
+const (
+	MixedContentActive  = "active"
+	MixedContentPassive = "passive"
+)
+
+// MixedContentAnalysis lists the subresources an HTTPS page loads over plain HTTP
+type MixedContentAnalysis struct {
+	Active  int                `json:"active" bson:"active"`
+	Passive int                `json:"passive" bson:"passive"`
+	Items   []MixedContentItem `json:"items" bson:"items"`
+	Checks  []SecurityCheck    `json:"checks,omitempty" bson:"checks,omitempty"`
+}
+
+// MixedContentItem is a subresource loaded over plain HTTP
+type MixedContentItem struct {
+	URL       string `json:"url" bson:"url"`
+	Element   string `json:"element" bson:"element"`
+	Attribute string `json:"attribute" bson:"attribute"` // e.g. src, srcset, style or url() in a <style>
+	Type      string `json:"type" bson:"type"`           // active or passive
+}
+
+// FormAnalysis audits the forms and password fields of a page
+type FormAnalysis struct {
+	Forms                      []FormRecord    `json:"forms" bson:"forms"`
+	PasswordFieldsOutsideForms int             `json:"passwordFieldsOutsideForms" bson:"password_fields_outside_forms"`
+	Findings                   []SecurityCheck `json:"findings" bson:"findings"`
+}
+
+// FormRecord describes a form and how it submits
+type FormRecord struct {
+	ID                   string          `json:"id,omitempty" bson:"id,omitempty"`
+	Name                 string          `json:"name,omitempty" bson:"name,omitempty"`
+	Method               string          `json:"method" bson:"method"` // GET, POST or DIALOG
+	Action               string          `json:"action" bson:"action"` // resolved URL
+	Login                bool            `json:"login" bson:"login"`
+	PasswordFields       int             `json:"passwordFields" bson:"password_fields"`
+	PasswordAutocomplete []string        `json:"passwordAutocomplete" bson:"password_autocomplete"` // per password field, "" when unset
+	InsecureAction       bool            `json:"insecureAction" bson:"insecure_action"`
+	CrossOrigin          bool            `json:"crossOrigin" bson:"cross_origin"`
+	CSRFToken            bool            `json:"csrfToken" bson:"csrf_token"`
+	Findings             []SecurityCheck `json:"findings" bson:"findings"`
+}
+
+// Dependency types
+const (
+	DependencyScript     = "script"
+	DependencyStylesheet = "stylesheet"
+)
+
+// Integrity verification outcomes
+const (
+	IntegrityMatch       = "match"
+	IntegrityMismatch    = "mismatch"
+	IntegrityUnavailable = "unavailable"
+)
+
+// DependencyAnalysis inventories the external scripts and stylesheets of a page
+type DependencyAnalysis struct {
+	Items            []Dependency `json:"items" bson:"items"`
+	FirstParty       int          `json:"firstParty" bson:"first_party"`
+	ThirdParty       int          `json:"thirdParty" bson:"third_party"`
+	WithoutIntegrity int          `json:"withoutIntegrity" bson:"without_integrity"`
+	ThirdPartyHosts  []string     `json:"thirdPartyHosts" bson:"third_party_hosts"`
+}
+
+// Dependency is a script or stylesheet loaded by URL
+type Dependency struct {
+	URL            string          `json:"url" bson:"url"`
+	Host           string          `json:"host" bson:"host"`
+	Type           string          `json:"type" bson:"type"` // script or stylesheet
+	ThirdParty     bool            `json:"thirdParty" bson:"third_party"`
+	Integrity      string          `json:"integrity,omitempty" bson:"integrity,omitempty"`
+	IntegrityValid bool            `json:"integrityValid" bson:"integrity_valid"`
+	CrossOrigin    string          `json:"crossOrigin,omitempty" bson:"cross_origin,omitempty"`  // anonymous or use-credentials
+	Verification   string          `json:"verification,omitempty" bson:"verification,omitempty"` // match, mismatch or unavailable when downloaded
+	Findings       []SecurityCheck `json:"findings" bson:"findings"`
 }
 
 // MobileAnalysis represents mobile-friendliness metrics
@@ -127,6 +299,18 @@ type TechnologyAnalysis struct {
 	CMS         string   `json:"cms" bson:"cms"`
 	Frameworks  []string `json:"frameworks" bson:"frameworks"`
 	Advertising []string `json:"advertising" bson:"advertising"`
+	// Technologies fingerprinted on the page, most confident first
+	Technologies []Technology `json:"technologies" bson:"technologies"`
+}
+
+// Technology is a technology fingerprinted on a page. Confidence ranges from
+// 0 to 100; technologies only implied by others list them in ImpliedBy.
+type Technology struct {
+	Name       string   `json:"name" bson:"name"`
+	Version    string   `json:"version,omitempty" bson:"version,omitempty"`
+	Categories []string `json:"categories" bson:"categories"`
+	Confidence int      `json:"confidence" bson:"confidence"`
+	ImpliedBy  []string `json:"impliedBy,omitempty" bson:"implied_by,omitempty"`
 }
 
 // MediaAnalysis represents media usage metrics
@@ -146,11 +330,30 @@ type SchemaAnalysis struct {
 
 // CookieAnalysis represents cookie usage metrics
 type CookieAnalysis struct {
-	TotalCount int  `json:"totalCount" bson:"total_count"`
-	FirstParty int  `json:"firstParty" bson:"first_party"`
-	ThirdParty int  `json:"thirdParty" bson:"third_party"`
-	HasConsent bool `json:"hasConsent" bson:"has_consent"`
-	MaxAgeDays int  `json:"maxAgeDays" bson:"max_age_days"`
+	TotalCount int            `json:"totalCount" bson:"total_count"`
+	FirstParty int            `json:"firstParty" bson:"first_party"`
+	ThirdParty int            `json:"thirdParty" bson:"third_party"`
+	HasConsent bool           `json:"hasConsent" bson:"has_consent"`
+	MaxAgeDays int            `json:"maxAgeDays" bson:"max_age_days"`
+	Cookies    []CookieRecord `json:"cookies" bson:"cookies"`
+}
+
+// CookieRecord describes a cookie set by the page and its attributes
+type CookieRecord struct {
+	Name        string          `json:"name" bson:"name"`
+	Domain      string          `json:"domain,omitempty" bson:"domain,omitempty"` // empty for host-only cookies
+	Path        string          `json:"path,omitempty" bson:"path,omitempty"`
+	Secure      bool            `json:"secure" bson:"secure"`
+	HttpOnly    bool            `json:"httpOnly" bson:"http_only"`
+	SameSite    string          `json:"sameSite,omitempty" bson:"same_site,omitempty"` // Lax, Strict or None
+	Partitioned bool            `json:"partitioned" bson:"partitioned"`
+	Session     bool            `json:"session" bson:"session"` // deleted when the browser closes
+	Expires     *time.Time      `json:"expires,omitempty" bson:"expires,omitempty"`
+	Size        int             `json:"size" bson:"size"` // name and value in bytes
+	ThirdParty  bool            `json:"thirdParty" bson:"third_party"`
+	Prefix      string          `json:"prefix,omitempty" bson:"prefix,omitempty"` // __Host- or __Secure-
+	PrefixValid bool            `json:"prefixValid" bson:"prefix_valid"`
+	Findings    []SecurityCheck `json:"findings" bson:"findings"`
 }
 
 // LinkAnalysis represents link-related metrics
@@ -160,3 +363,58 @@ type LinkAnalysis struct {
 	BrokenLinks int            `json:"brokenLinks" bson:"broken_links"`
 	MaxDepth    int            `json:"maxDepth" bson:"max_depth"`
 }
+
+// Tracker categories
+const (
+	TrackerAnalytics     = "analytics"
+	TrackerAdvertising   = "advertising"
+	TrackerSocial        = "social"
+	TrackerSessionReplay = "session_replay"
+	// TrackerConsent marks consent management platforms, which are reported
+	// apart from trackers
+	TrackerConsent = "consent"
+)
+
+// Kinds of evidence for a tracker
+const (
+	EvidenceScript = "script"
+	EvidenceInline = "inline"
+	EvidencePixel  = "pixel"
+	EvidenceIframe = "iframe"
+	EvidenceCookie = "cookie"
+	EvidenceMarker = "marker"
+)
+
+// PrivacyAnalysis reports the trackers and analytics vendors found on a page
+type PrivacyAnalysis struct {
+	Trackers   []TrackerMatch  `json:"trackers" bson:"trackers"`
+	Categories map[string]int  `json:"categories" bson:"categories"` // trackers per category
+	Consent    ConsentAnalysis `json:"consent" bson:"consent"`
+}
+
+// ConsentAnalysis reports the consent management platforms of a page and the
+// tracking that happens before visitors interact with them
+type ConsentAnalysis struct {
+	Platforms             []TrackerMatch  `json:"platforms" bson:"platforms"`
+	TCF                   bool            `json:"tcf" bson:"tcf"` // IAB Transparency and Consent Framework API present
+	TrackersBeforeConsent []string        `json:"trackersBeforeConsent" bson:"trackers_before_consent"`
+	CookiesBeforeConsent  []string        `json:"cookiesBeforeConsent" bson:"cookies_before_consent"`
+	Findings              []SecurityCheck `json:"findings" bson:"findings"`
+}
+
+// TrackerMatch is a known tracker found on a page
+type TrackerMatch struct {
+	Name     string            `json:"name" bson:"name"`
+	Vendor   string            `json:"vendor" bson:"vendor"`
+	Category string            `json:"category" bson:"category"`
+	Evidence []TrackerEvidence `json:"evidence" bson:"evidence"`
+}
+
+// TrackerEvidence is what identified a tracker
+type TrackerEvidence struct {
+	Type  string `json:"type" bson:"type"`   // script, inline, pixel, iframe, cookie or marker
+	Value string `json:"value" bson:"value"` // URL, matched code, cookie name or element marker
+	// BeforeConsent is set when the evidence loads, runs or is stored without
+	// any consent interaction, unlike scripts blocked with type="text/plain"
+	BeforeConsent bool `json:"beforeConsent" bson:"before_consent"`
+}
@@ -8,7 +8,8 @@ import (
 
 // AnalysisRequest represents the request to analyze a URL
 type AnalysisRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL       string `json:"url" binding:"required,url"`
+	ProjectID string `json:"project_id,omitempty"`
 }
 
 // LinkStatus represents the status of a link
@@ -31,23 +32,46 @@ type HeadingCount struct {
 type AnalysisResult struct {
 	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	URL           string             `json:"url" bson:"url"`
+	Domain        string             `json:"domain,omitempty" bson:"domain,omitempty"`
 	HTMLVersion   string             `json:"html_version" bson:"html_version"`
 	Title         string             `json:"title" bson:"title"`
 	Headings      HeadingCount       `json:"headings" bson:"headings"`
 	InternalLinks LinkStatus         `json:"internal_links" bson:"internal_links"`
 	ExternalLinks LinkStatus         `json:"external_links" bson:"external_links"`
 	HasLoginForm  bool               `json:"has_login_form" bson:"has_login_form"`
+	Description   string             `json:"description,omitempty" bson:"description,omitempty"`
+	AnchorText    string             `json:"-" bson:"anchor_text,omitempty"`
+	TextContent   string             `json:"-" bson:"text_content,omitempty"`
 	UserID        string             `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	ProjectID     string             `json:"project_id,omitempty" bson:"project_id,omitempty"`
 	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. Code identifies the kind of
+// failure for clients to branch on; it is stable across releases, unlike
+// Message and Error.
 type ErrorResponse struct {
 	StatusCode int    `json:"status_code"`
+	Code       string `json:"code,omitempty"`
 	Message    string `json:"message"`
 	Error      string `json:"error,omitempty"`
 }
 
+// Error codes
+const (
+	CodeInvalidURL        = "invalid_url"        // the URL is malformed or not http(s)
+	CodeDNSFailure        = "dns_failure"        // the host could not be resolved
+	CodeConnectionRefused = "connection_refused" // the host refused the connection
+	CodeTLSError          = "tls_error"          // the TLS handshake or certificate check failed
+	CodeTimeout           = "timeout"            // the site did not respond in time
+	CodeNotHTML           = "not_html"           // the response is not an HTML page
+	CodeTooLarge          = "too_large"          // the page exceeds the size limit
+	CodeUpstreamStatus    = "upstream_status"    // the site responded with a non-200 status
+	CodeFetchFailed       = "fetch_failed"       // the page could not be fetched for another reason
+	CodeRateLimited       = "rate_limited"       // the caller's rate limit is exhausted
+	CodeQuotaExceeded     = "quota_exceeded"     // the caller's analysis quota is used up
+)
+
 // Stats represents application statistics
 type Stats struct {
 	TotalAnalyses      int       `json:"total_analyses" bson:"total_analyses"`
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Member roles within an organization, from least to most privileged
+const (
+	RoleViewer  = "viewer"  // read the organization's projects and analyses
+	RoleAnalyst = "analyst" // additionally run and delete analyses
+	RoleOwner   = "owner"   // additionally manage projects and members
+)
+
+// memberRoleRank orders member roles by privilege
+var memberRoleRank = map[string]int{
+	RoleViewer:  1,
+	RoleAnalyst: 2,
+	RoleOwner:   3,
+}
+
+// ValidMemberRole reports whether role is a known member role
+func ValidMemberRole(role string) bool {
+	_, ok := memberRoleRank[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants at least the privileges of required
+func RoleAtLeast(role, required string) bool {
+	return ValidMemberRole(role) && memberRoleRank[role] >= memberRoleRank[required]
+}
+
+// Organization groups users and projects of a team
+type Organization struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	CreatedBy string             `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Membership grants a user a role in an organization
+type Membership struct {
+	OrganizationID primitive.ObjectID `json:"organization_id" bson:"organization_id"`
+	UserID         string             `json:"user_id" bson:"user_id"`
+	Role           string             `json:"role" bson:"role"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Project is a shared workspace within an organization; analyses created in
+// a project are visible to every member of its organization
+type Project struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OrganizationID primitive.ObjectID `json:"organization_id" bson:"organization_id"`
+	Name           string             `json:"name" bson:"name"`
+	CreatedBy      string             `json:"created_by" bson:"created_by"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// UserOrganization is an organization together with the caller's role in it
+type UserOrganization struct {
+	*Organization
+	Role string `json:"role"`
+}
+
+// OrganizationRequest represents the request to create an organization
+type OrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// ProjectRequest represents the request to create a project
+type ProjectRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// MembershipRequest represents the request to add or update a member
+type MembershipRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Sort keys accepted by analysis listings. A leading "-" sorts descending.
+const (
+	SortCreatedAtDesc = "-created_at"
+	SortCreatedAtAsc  = "created_at"
+	SortURLAsc        = "url"
+	SortURLDesc       = "-url"
+	SortTitleAsc      = "title"
+	SortTitleDesc     = "-title"
+)
+
+// AnalysisFilter narrows down an analysis listing. Zero values are ignored.
+// UserID and ProjectID select the tenant: with ProjectID set only that
+// project's analyses match, while UserID alone matches the user's personal
+// analyses, i.e. those that belong to no project, unless AllProjects is set.
+type AnalysisFilter struct {
+	UserID         string
+	ProjectID      string
+	AllProjects    bool
+	Domain         string
+	URLPrefix      string
+	From           time.Time
+	To             time.Time
+	HasLoginForm   *bool
+	HTMLVersion    string
+	HasBrokenLinks *bool
+}
+
+// AnalysisQuery describes a page of an analysis listing
+type AnalysisQuery struct {
+	Filter AnalysisFilter
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// AnalysisPage is a single page of an analysis listing
+type AnalysisPage struct {
+	Analyses   []*AnalysisResult `json:"analyses"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// SearchQuery describes a full-text search over analyzed pages. UserID and
+// ProjectID scope the search like in AnalysisFilter.
+type SearchQuery struct {
+	Query     string
+	UserID    string
+	ProjectID string
+	Limit     int
+}
+
+// SearchHit is a single ranked full-text search result. Highlights maps a
+// field name (title, description, anchor_text, text) to a snippet with the
+// matched terms wrapped in <mark> tags.
+type SearchHit struct {
+	Analysis   *AnalysisResult   `json:"analysis"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// QuotaStatus reports a caller's plan and analysis usage
+type QuotaStatus struct {
+	Plan    string       `json:"plan"`
+	Daily   QuotaCounter `json:"daily"`
+	Monthly QuotaCounter `json:"monthly"`
+}
+
+// QuotaCounter is the usage of one quota period. A zero limit means unlimited.
+type QuotaCounter struct {
+	Period   string    `json:"period"`
+	Used     int64     `json:"used"`
+	Limit    int       `json:"limit"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// PlanRequest represents a request to assign a quota plan to a user
+type PlanRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
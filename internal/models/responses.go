@@ -0,0 +1,85 @@
+package models
+
+// HealthResponse represents the health check response
+type HealthResponse struct {
+	Status string `json:"status"`
+	Time   string `json:"time"`
+}
+
+// AnalysisListResponse represents a page of analyses
+type AnalysisListResponse struct {
+	Count      int               `json:"count"`
+	Analyses   []*AnalysisResult `json:"analyses"`
+	NextCursor string            `json:"next_cursor"`
+}
+
+// SearchResponse represents full-text search results
+type SearchResponse struct {
+	Query   string       `json:"query"`
+	Count   int          `json:"count"`
+	Results []*SearchHit `json:"results"`
+}
+
+// DeepAnalysisVersionsResponse represents the deep analysis history of an analysis
+type DeepAnalysisVersionsResponse struct {
+	AnalysisID string                 `json:"analysis_id"`
+	Count      int                    `json:"count"`
+	Versions   []*DeepAnalysisVersion `json:"versions"`
+}
+
+// DeleteResponse reports how many analyses were deleted; ID is set when a
+// single analysis was deleted
+type DeleteResponse struct {
+	ID      string `json:"id,omitempty"`
+	Deleted int64  `json:"deleted"`
+}
+
+// APIKeyListResponse represents a user's API keys
+type APIKeyListResponse struct {
+	Count   int       `json:"count"`
+	APIKeys []*APIKey `json:"api_keys"`
+}
+
+// RevokeAPIKeyResponse confirms that an API key was revoked
+type RevokeAPIKeyResponse struct {
+	ID      string `json:"id"`
+	Revoked bool   `json:"revoked"`
+}
+
+// AuditEventListResponse represents a page of the audit log
+type AuditEventListResponse struct {
+	Count      int           `json:"count"`
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// OrganizationListResponse represents the organizations of a user
+type OrganizationListResponse struct {
+	Count         int                 `json:"count"`
+	Organizations []*UserOrganization `json:"organizations"`
+}
+
+// MemberListResponse represents the members of an organization
+type MemberListResponse struct {
+	Count   int           `json:"count"`
+	Members []*Membership `json:"members"`
+}
+
+// RemoveMemberResponse confirms that a member was removed from an organization
+type RemoveMemberResponse struct {
+	OrganizationID string `json:"organization_id"`
+	UserID         string `json:"user_id"`
+	Removed        bool   `json:"removed"`
+}
+
+// ProjectListResponse represents the projects of an organization
+type ProjectListResponse struct {
+	Count    int        `json:"count"`
+	Projects []*Project `json:"projects"`
+}
+
+// UserPlanResponse confirms a user's quota plan
+type UserPlanResponse struct {
+	UserID string `json:"user_id"`
+	Plan   string `json:"plan"`
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// auditSort is the fixed order of audit log queries, newest first
+var auditSort = sortSpec{key: "-created_at", field: "created_at", desc: true}
+
+// encodeAuditCursor builds the cursor pointing after the given audit event
+func encodeAuditCursor(e *models.AuditEvent) string {
+	c := listCursor{
+		Sort:  auditSort.key,
+		Value: e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:    e.ID.Hex(),
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// newAuditPage trims a result set fetched with limit+1 rows into a page
+func newAuditPage(events []*models.AuditEvent, limit int) *models.AuditPage {
+	page := &models.AuditPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = encodeAuditCursor(page.Events[limit-1])
+	}
+	if page.Events == nil {
+		page.Events = []*models.AuditEvent{}
+	}
+	return page
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,26 +15,18 @@ import (
 	"webPageAnalyzerGO/internal/models"
 )
 
-// Repository defines operations on analysis data
-type Repository interface {
-	SaveAnalysis(ctx context.Context, analysis *models.AnalysisResult) error
-	GetAnalysis(ctx context.Context, id string) (*models.AnalysisResult, error)
-	GetRecentAnalyses(ctx context.Context, limit int) ([]*models.AnalysisResult, error)
-	GetUserAnalyses(ctx context.Context, userID string, limit int) ([]*models.AnalysisResult, error)
-
-	// Deep analysis methods
-	SaveDeepAnalysis(ctx context.Context, analysis *models.DeepAnalysisResult) error
-	GetDeepAnalysis(ctx context.Context, analysisID string) (*models.DeepAnalysisResult, error)
-
-	GetStats(ctx context.Context) (*models.Stats, error)
-	Close(ctx context.Context) error
-}
-
 // MongoRepository implements Repository interface for MongoDB
 type MongoRepository struct {
 	client         *mongo.Client
 	collection     *mongo.Collection
 	deepCollection *mongo.Collection
+	apiKeys        *mongo.Collection
+	organizations  *mongo.Collection
+	projects       *mongo.Collection
+	memberships    *mongo.Collection
+	auditLog       *mongo.Collection
+	userPlans      *mongo.Collection
+	quotaUsage     *mongo.Collection
 }
 
 // NewMongoRepository creates a new MongoDB repository
@@ -68,16 +63,81 @@ func NewMongoRepository(ctx context.Context, cfg config.MongoDBConfig) (*MongoRe
 			Keys:    bson.D{{Key: "created_at", Value: -1}},
 			Options: options.Index().SetBackground(true),
 		},
+		// Listing indexes: keyset pagination sorts on (field, _id)
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "url", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "project_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "domain", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "html_version", Value: 1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "has_login_form", Value: 1}},
+			Options: options.Index().SetBackground(true),
+		},
+		// Full-text search index, weighted towards titles and descriptions
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "anchor_text", Value: "text"},
+				{Key: "text_content", Value: "text"},
+			},
+			Options: options.Index().
+				SetBackground(true).
+				SetName("analysis_text").
+				SetWeights(bson.D{
+					{Key: "title", Value: 10},
+					{Key: "description", Value: 5},
+					{Key: "anchor_text", Value: 2},
+					{Key: "text_content", Value: 1},
+				}),
+		},
 	}
 
 	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
 		return nil, err
 	}
 
+	// Deep analyses used to be unique per analysis; versions replaced that
+	// index with a compound one
+	if _, err := deepCollection.Indexes().DropOne(ctx, "analysis_id_1"); err != nil && !isIndexNotFound(err) {
+		return nil, err
+	}
+
+	// Existing deep analyses become the first version of their analysis
+	if _, err := deepCollection.UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 1}},
+	); err != nil {
+		return nil, err
+	}
+
 	// Create indexes for deep analysis collection
 	deepIndexModels := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{Key: "analysis_id", Value: 1}},
+			Keys:    bson.D{{Key: "analysis_id", Value: 1}, {Key: "version", Value: -1}},
 			Options: options.Index().SetBackground(true).SetUnique(true),
 		},
 		{
@@ -90,11 +150,129 @@ func NewMongoRepository(ctx context.Context, cfg config.MongoDBConfig) (*MongoRe
 		return nil, err
 	}
 
-	return &MongoRepository{
+	// API keys are looked up by hash and listed per user
+	apiKeys := client.Database(cfg.Database).Collection("api_keys")
+	apiKeyIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetBackground(true).SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+	}
+
+	if _, err := apiKeys.Indexes().CreateMany(ctx, apiKeyIndexModels); err != nil {
+		return nil, err
+	}
+
+	// Tenancy: a user has at most one membership per organization and
+	// projects are listed per organization
+	organizations := client.Database(cfg.Database).Collection("organizations")
+	projects := client.Database(cfg.Database).Collection("projects")
+	memberships := client.Database(cfg.Database).Collection("memberships")
+
+	if _, err := projects.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "created_at", Value: 1}},
+		Options: options.Index().SetBackground(true),
+	}); err != nil {
+		return nil, err
+	}
+
+	membershipIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetBackground(true).SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetBackground(true),
+		},
+	}
+
+	if _, err := memberships.Indexes().CreateMany(ctx, membershipIndexModels); err != nil {
+		return nil, err
+	}
+
+	// The audit log is read newest first, optionally per actor or action
+	auditLog := client.Database(cfg.Database).Collection("audit_log")
+	auditIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			Keys:    bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+	}
+
+	if _, err := auditLog.Indexes().CreateMany(ctx, auditIndexModels); err != nil {
+		return nil, err
+	}
+
+	// Quota usage is counted per subject and period; plans are keyed by user ID
+	userPlans := client.Database(cfg.Database).Collection("user_plans")
+	quotaUsage := client.Database(cfg.Database).Collection("quota_usage")
+
+	if _, err := quotaUsage.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "subject", Value: 1}, {Key: "period", Value: 1}},
+		Options: options.Index().SetBackground(true).SetUnique(true),
+	}); err != nil {
+		return nil, err
+	}
+
+	r := &MongoRepository{
 		client:         client,
 		collection:     collection,
 		deepCollection: deepCollection,
-	}, nil
+		apiKeys:        apiKeys,
+		organizations:  organizations,
+		projects:       projects,
+		memberships:    memberships,
+		auditLog:       auditLog,
+		userPlans:      userPlans,
+		quotaUsage:     quotaUsage,
+	}
+
+	// Populate the domain field on analyses stored before it existed
+	if err := r.backfillDomains(ctx); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// backfillDomains sets the domain field on analyses that do not have one
+func (r *MongoRepository) backfillDomains(ctx context.Context) error {
+	findOptions := options.Find().SetProjection(bson.M{"url": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"domain": bson.M{"$exists": false}}, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID  primitive.ObjectID `bson:"_id"`
+			URL string             `bson:"url"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		update := bson.M{"$set": bson.M{"domain": domainOf(doc.URL)}}
+		if _, err := r.collection.UpdateByID(ctx, doc.ID, update); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
 }
 
 // SaveAnalysis saves an analysis result to MongoDB
@@ -103,6 +281,9 @@ func (r *MongoRepository) SaveAnalysis(ctx context.Context, analysis *models.Ana
 	if analysis.CreatedAt.IsZero() {
 		analysis.CreatedAt = time.Now()
 	}
+	if analysis.Domain == "" {
+		analysis.Domain = domainOf(analysis.URL)
+	}
 
 	// Insert document
 	result, err := r.collection.InsertOne(ctx, analysis)
@@ -157,13 +338,14 @@ func (r *MongoRepository) GetRecentAnalyses(ctx context.Context, limit int) ([]*
 	return analyses, nil
 }
 
-// GetUserAnalyses retrieves analyses for a specific user
+// GetUserAnalyses retrieves the personal analyses of a specific user
 func (r *MongoRepository) GetUserAnalyses(ctx context.Context, userID string, limit int) ([]*models.AnalysisResult, error) {
 	findOptions := options.Find().
 		SetSort(bson.D{{Key: "created_at", Value: -1}}).
 		SetLimit(int64(limit))
 
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	filter := bson.M{"$and": mongoAnalysisFilter(models.AnalysisFilter{UserID: userID})}
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -177,31 +359,226 @@ func (r *MongoRepository) GetUserAnalyses(ctx context.Context, userID string, li
 	return analyses, nil
 }
 
-// SaveDeepAnalysis saves a deep analysis result to MongoDB
+// ListAnalyses retrieves a filtered, sorted page of analyses
+func (r *MongoRepository) ListAnalyses(ctx context.Context, query models.AnalysisQuery) (*models.AnalysisPage, error) {
+	spec, err := parseSort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+	limit := listLimit(query.Limit)
+
+	direction := 1
+	comparison := "$gt"
+	if spec.desc {
+		direction = -1
+		comparison = "$lt"
+	}
+
+	conditions := mongoAnalysisFilter(query.Filter)
+
+	// Keyset pagination: continue strictly after the cursor's (field, _id)
+	if query.Cursor != "" {
+		c, id, err := decodeCursor(query.Cursor, spec)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{} = c.Value
+		if spec.field == "created_at" {
+			value, _ = time.Parse(time.RFC3339Nano, c.Value)
+		}
+
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{spec.field: bson.M{comparison: value}},
+			bson.M{spec.field: value, "_id": bson.M{comparison: id}},
+		}})
+	}
+
+	filter := bson.M{}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: spec.field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var analyses []*models.AnalysisResult
+	if err := cursor.All(ctx, &analyses); err != nil {
+		return nil, err
+	}
+
+	return newAnalysisPage(analyses, limit, spec), nil
+}
+
+// mongoAnalysisFilter converts a listing filter into query conditions
+func mongoAnalysisFilter(f models.AnalysisFilter) bson.A {
+	conditions := bson.A{}
+
+	if f.UserID != "" {
+		conditions = append(conditions, bson.M{"user_id": f.UserID})
+	}
+	if f.ProjectID != "" {
+		conditions = append(conditions, bson.M{"project_id": f.ProjectID})
+	} else if f.UserID != "" && !f.AllProjects {
+		// Personal analyses are those outside any project
+		conditions = append(conditions, bson.M{"project_id": bson.M{"$in": bson.A{nil, ""}}})
+	}
+	if f.Domain != "" {
+		conditions = append(conditions, bson.M{"domain": strings.ToLower(f.Domain)})
+	}
+	if f.URLPrefix != "" {
+		conditions = append(conditions, bson.M{"url": bson.M{"$regex": "^" + regexp.QuoteMeta(f.URLPrefix)}})
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$gte": f.From}})
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$lt": f.To}})
+	}
+	if f.HasLoginForm != nil {
+		conditions = append(conditions, bson.M{"has_login_form": *f.HasLoginForm})
+	}
+	if f.HTMLVersion != "" {
+		conditions = append(conditions, bson.M{"html_version": f.HTMLVersion})
+	}
+	if f.HasBrokenLinks != nil {
+		broken := bson.A{
+			bson.M{"internal_links.inaccessible": bson.M{"$gt": 0}},
+			bson.M{"external_links.inaccessible": bson.M{"$gt": 0}},
+		}
+		if *f.HasBrokenLinks {
+			conditions = append(conditions, bson.M{"$or": broken})
+		} else {
+			conditions = append(conditions, bson.M{"$nor": broken})
+		}
+	}
+
+	return conditions
+}
+
+// SearchAnalyses performs a ranked full-text search over analyzed pages
+func (r *MongoRepository) SearchAnalyses(ctx context.Context, query models.SearchQuery) ([]*models.SearchHit, error) {
+	terms := searchTerms(query.Query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	// Quoting every term makes all of them required
+	phrases := make([]string, len(terms))
+	for i, t := range terms {
+		phrases[i] = `"` + t + `"`
+	}
+
+	filter := bson.M{"$text": bson.M{"$search": strings.Join(phrases, " ")}}
+	scope := models.AnalysisFilter{UserID: query.UserID, ProjectID: query.ProjectID}
+	if conditions := mongoAnalysisFilter(scope); len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(listLimit(query.Limit)))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		models.AnalysisResult `bson:",inline"`
+		Score                 float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	hits := make([]*models.SearchHit, len(docs))
+	for i := range docs {
+		analysis := docs[i].AnalysisResult
+		hits[i] = &models.SearchHit{
+			Analysis:   &analysis,
+			Score:      docs[i].Score,
+			Highlights: highlights(&analysis, terms),
+		}
+	}
+
+	return hits, nil
+}
+
+// SaveDeepAnalysis stores a deep analysis result as the next version of its analysis
 func (r *MongoRepository) SaveDeepAnalysis(ctx context.Context, analysis *models.DeepAnalysisResult) error {
 	// Set creation time if not set
 	if analysis.CreatedAt.IsZero() {
 		analysis.CreatedAt = time.Now()
 	}
+	if analysis.ID.IsZero() {
+		analysis.ID = primitive.NewObjectID()
+	}
 
-	// Use upsert to replace existing analysis if it exists
-	filter := bson.M{"analysis_id": analysis.AnalysisID}
-	update := bson.M{"$set": analysis}
-	opts := options.Update().SetUpsert(true)
+	// Concurrent runs may pick the same version; the unique index rejects
+	// the loser, which then retries with the following version
+	var err error
+	for attempt := 0; attempt < saveVersionAttempts; attempt++ {
+		var latest struct {
+			Version int `bson:"version"`
+		}
+		findOptions := options.FindOne().
+			SetSort(bson.D{{Key: "version", Value: -1}}).
+			SetProjection(bson.M{"version": 1})
+		err = r.deepCollection.FindOne(ctx, bson.M{"analysis_id": analysis.AnalysisID}, findOptions).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		analysis.Version = latest.Version + 1
+		if _, err = r.deepCollection.InsertOne(ctx, analysis); !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
 
-	_, err := r.deepCollection.UpdateOne(ctx, filter, update, opts)
 	return err
 }
 
-// GetDeepAnalysis retrieves a deep analysis by analysis ID
+// GetDeepAnalysis retrieves the latest deep analysis by analysis ID
 func (r *MongoRepository) GetDeepAnalysis(ctx context.Context, analysisID string) (*models.DeepAnalysisResult, error) {
 	objectID, err := primitive.ObjectIDFromHex(analysisID)
 	if err != nil {
 		return nil, err
 	}
 
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var analysis models.DeepAnalysisResult
+	err = r.deepCollection.FindOne(ctx, bson.M{"analysis_id": objectID}, findOptions).Decode(&analysis)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return &analysis, nil
+}
+
+// GetDeepAnalysisVersion retrieves a specific deep analysis version
+func (r *MongoRepository) GetDeepAnalysisVersion(ctx context.Context, analysisID string, version int) (*models.DeepAnalysisResult, error) {
+	objectID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, err
+	}
+
 	var analysis models.DeepAnalysisResult
-	err = r.deepCollection.FindOne(ctx, bson.M{"analysis_id": objectID}).Decode(&analysis)
+	err = r.deepCollection.FindOne(ctx, bson.M{"analysis_id": objectID, "version": version}).Decode(&analysis)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil // Not found
@@ -212,6 +589,558 @@ func (r *MongoRepository) GetDeepAnalysis(ctx context.Context, analysisID string
 	return &analysis, nil
 }
 
+// ListDeepAnalysisVersions lists the stored deep analysis versions, newest first
+func (r *MongoRepository) ListDeepAnalysisVersions(ctx context.Context, analysisID string) ([]*models.DeepAnalysisVersion, error) {
+	objectID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{
+			"analysis_id":      1,
+			"version":          1,
+			"analyzer_version": 1,
+			"rules_version":    1,
+			"created_at":       1,
+		})
+
+	cursor, err := r.deepCollection.Find(ctx, bson.M{"analysis_id": objectID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	versions := []*models.DeepAnalysisVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// DeleteAnalysis deletes an analysis and its deep analyses
+func (r *MongoRepository) DeleteAnalysis(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := r.deepCollection.DeleteMany(ctx, bson.M{"analysis_id": objectID}); err != nil {
+		return false, err
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// DisownProjectAnalyses removes the user ID from a user's project analyses
+func (r *MongoRepository) DisownProjectAnalyses(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "project_id": bson.M{"$nin": bson.A{nil, ""}}},
+		bson.M{"$unset": bson.M{"user_id": ""}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeleteAnalyses deletes all analyses matching a filter and their deep analyses
+func (r *MongoRepository) DeleteAnalyses(ctx context.Context, filter models.AnalysisFilter) (int64, error) {
+	query := bson.M{}
+	if conditions := mongoAnalysisFilter(filter); len(conditions) > 0 {
+		query["$and"] = conditions
+	}
+
+	var deleted int64
+	for {
+		// Delete in batches so the cascade never has to hold every ID at once
+		findOptions := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(deleteBatchSize)
+		cursor, err := r.collection.Find(ctx, query, findOptions)
+		if err != nil {
+			return deleted, err
+		}
+
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &docs); err != nil {
+			return deleted, err
+		}
+		if len(docs) == 0 {
+			return deleted, nil
+		}
+
+		ids := make(bson.A, len(docs))
+		for i, d := range docs {
+			ids[i] = d.ID
+		}
+
+		if _, err := r.deepCollection.DeleteMany(ctx, bson.M{"analysis_id": bson.M{"$in": ids}}); err != nil {
+			return deleted, err
+		}
+
+		result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+}
+
+// DeleteDeepAnalysesBefore deletes deep analyses created before the given time
+func (r *MongoRepository) DeleteDeepAnalysesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.deepCollection.DeleteMany(ctx, bson.M{"created_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// isIndexNotFound reports whether dropping an index failed because the index
+// or its collection does not exist
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Code == 26)
+}
+
+// SaveAPIKey stores a new API key
+func (r *MongoRepository) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.apiKeys.InsertOne(ctx, key)
+	return err
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of its secret
+func (r *MongoRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.apiKeys.FindOne(ctx, bson.M{"hash": hash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys lists a user's API keys, newest first
+func (r *MongoRepository) ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.apiKeys.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey deletes one of a user's API keys
+func (r *MongoRepository) DeleteAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	// No key has a malformed ID
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	result, err := r.apiKeys.DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// DeleteUserAPIKeys deletes all of a user's API keys
+func (r *MongoRepository) DeleteUserAPIKeys(ctx context.Context, userID string) (int64, error) {
+	result, err := r.apiKeys.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// TouchAPIKey records when an API key was last used
+func (r *MongoRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.apiKeys.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return err
+}
+
+// SaveOrganization stores a new organization
+func (r *MongoRepository) SaveOrganization(ctx context.Context, org *models.Organization) error {
+	if org.CreatedAt.IsZero() {
+		org.CreatedAt = time.Now()
+	}
+	if org.ID.IsZero() {
+		org.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.organizations.InsertOne(ctx, org)
+	return err
+}
+
+// GetOrganization retrieves an organization by ID
+func (r *MongoRepository) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var org models.Organization
+	err = r.organizations.FindOne(ctx, bson.M{"_id": objectID}).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+// ListUserOrganizations lists the organizations a user is a member of,
+// together with the user's role in each
+func (r *MongoRepository) ListUserOrganizations(ctx context.Context, userID string) ([]*models.UserOrganization, error) {
+	cursor, err := r.memberships.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var memberships []*models.Membership
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+
+	roles := make(map[primitive.ObjectID]string, len(memberships))
+	orgIDs := bson.A{}
+	for _, m := range memberships {
+		roles[m.OrganizationID] = m.Role
+		orgIDs = append(orgIDs, m.OrganizationID)
+	}
+
+	orgs := []*models.UserOrganization{}
+	if len(orgIDs) == 0 {
+		return orgs, nil
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	orgCursor, err := r.organizations.Find(ctx, bson.M{"_id": bson.M{"$in": orgIDs}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer orgCursor.Close(ctx)
+
+	for orgCursor.Next(ctx) {
+		var org models.Organization
+		if err := orgCursor.Decode(&org); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, &models.UserOrganization{Organization: &org, Role: roles[org.ID]})
+	}
+
+	return orgs, orgCursor.Err()
+}
+
+// SaveMembership adds a member to an organization or updates their role
+func (r *MongoRepository) SaveMembership(ctx context.Context, membership *models.Membership) error {
+	if membership.CreatedAt.IsZero() {
+		membership.CreatedAt = time.Now()
+	}
+
+	_, err := r.memberships.UpdateOne(ctx,
+		bson.M{"organization_id": membership.OrganizationID, "user_id": membership.UserID},
+		bson.M{
+			"$set":         bson.M{"role": membership.Role},
+			"$setOnInsert": bson.M{"created_at": membership.CreatedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetMembership retrieves a user's membership in an organization
+func (r *MongoRepository) GetMembership(ctx context.Context, orgID, userID string) (*models.Membership, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var membership models.Membership
+	err = r.memberships.FindOne(ctx, bson.M{"organization_id": objectID, "user_id": userID}).Decode(&membership)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+// ListMemberships lists the members of an organization in the order they joined
+func (r *MongoRepository) ListMemberships(ctx context.Context, orgID string) ([]*models.Membership, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.memberships.Find(ctx, bson.M{"organization_id": objectID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	memberships := []*models.Membership{}
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// DeleteMembership removes a user from an organization
+func (r *MongoRepository) DeleteMembership(ctx context.Context, orgID, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.memberships.DeleteOne(ctx, bson.M{"organization_id": objectID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// SaveProject stores a new project
+func (r *MongoRepository) SaveProject(ctx context.Context, project *models.Project) error {
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = time.Now()
+	}
+	if project.ID.IsZero() {
+		project.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.projects.InsertOne(ctx, project)
+	return err
+}
+
+// GetProject retrieves a project by ID
+func (r *MongoRepository) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var project models.Project
+	err = r.projects.FindOne(ctx, bson.M{"_id": objectID}).Decode(&project)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// ListProjects lists an organization's projects in creation order
+func (r *MongoRepository) ListProjects(ctx context.Context, orgID string) ([]*models.Project, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.projects.Find(ctx, bson.M{"organization_id": objectID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []*models.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// SaveAuditEvents appends events to the audit log
+func (r *MongoRepository) SaveAuditEvents(ctx context.Context, events []*models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i, e := range events {
+		if e.ID.IsZero() {
+			e.ID = primitive.NewObjectID()
+		}
+		docs[i] = e
+	}
+
+	_, err := r.auditLog.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// ListAuditEvents retrieves a page of the audit log, newest first
+func (r *MongoRepository) ListAuditEvents(ctx context.Context, query models.AuditQuery) (*models.AuditPage, error) {
+	limit := listLimit(query.Limit)
+	conditions := mongoAuditFilter(query.Filter)
+
+	// Keyset pagination: continue strictly after the cursor's (created_at, _id)
+	if query.Cursor != "" {
+		c, id, err := decodeCursor(query.Cursor, auditSort)
+		if err != nil {
+			return nil, err
+		}
+
+		createdAt, _ := time.Parse(time.RFC3339Nano, c.Value)
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"created_at": bson.M{"$lt": createdAt}},
+			bson.M{"created_at": createdAt, "_id": bson.M{"$lt": id}},
+		}})
+	}
+
+	filter := bson.M{}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.auditLog.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*models.AuditEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return newAuditPage(events, limit), nil
+}
+
+// mongoAuditFilter converts an audit filter into $and conditions
+func mongoAuditFilter(f models.AuditFilter) bson.A {
+	conditions := bson.A{}
+
+	for field, value := range map[string]string{
+		"actor_id":    f.ActorID,
+		"action":      f.Action,
+		"target_type": f.TargetType,
+		"target_id":   f.TargetID,
+		"outcome":     f.Outcome,
+	} {
+		if value != "" {
+			conditions = append(conditions, bson.M{field: value})
+		}
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$gte": f.From}})
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$lt": f.To}})
+	}
+
+	return conditions
+}
+
+// GetUserPlan returns the quota plan assigned to a user, or "" if none is
+func (r *MongoRepository) GetUserPlan(ctx context.Context, userID string) (string, error) {
+	var doc struct {
+		Plan string `bson:"plan"`
+	}
+	err := r.userPlans.FindOne(ctx, bson.M{"_id": userID}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", err
+	}
+	return doc.Plan, nil
+}
+
+// SetUserPlan assigns a quota plan to a user
+func (r *MongoRepository) SetUserPlan(ctx context.Context, userID, plan string) error {
+	_, err := r.userPlans.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"plan": plan, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// IncrementQuotaUsage counts one use against each period of a subject
+func (r *MongoRepository) IncrementQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error) {
+	counts := make([]int64, len(periods))
+	for i, period := range periods {
+		var doc struct {
+			Count int64 `bson:"count"`
+		}
+		err := r.quotaUsage.FindOneAndUpdate(ctx,
+			bson.M{"subject": subject, "period": period},
+			bson.M{"$inc": bson.M{"count": int64(1)}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&doc)
+		if err != nil {
+			return nil, err
+		}
+		counts[i] = doc.Count
+	}
+	return counts, nil
+}
+
+// GetQuotaUsage returns the usage of each period of a subject
+func (r *MongoRepository) GetQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error) {
+	counts := make([]int64, len(periods))
+	for i, period := range periods {
+		var doc struct {
+			Count int64 `bson:"count"`
+		}
+		err := r.quotaUsage.FindOne(ctx, bson.M{"subject": subject, "period": period}).Decode(&doc)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		counts[i] = doc.Count
+	}
+	return counts, nil
+}
+
 // GetStats retrieves application statistics
 func (r *MongoRepository) GetStats(ctx context.Context) (*models.Stats, error) {
 	// Implementation remains the same...
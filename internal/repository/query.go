@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// ErrInvalidCursor is returned when a listing cursor cannot be decoded or
+// does not belong to the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidSort is returned for an unknown listing sort key
+var ErrInvalidSort = errors.New("invalid sort")
+
+// DefaultListLimit and MaxListLimit bound the size of a listing page
+const (
+	DefaultListLimit = 10
+	MaxListLimit     = 100
+)
+
+// deleteBatchSize is the number of analyses removed per bulk delete round
+const deleteBatchSize = 500
+
+// saveVersionAttempts bounds retries when two deep analyses race for a version
+const saveVersionAttempts = 3
+
+// sortSpec is a parsed listing sort key
+type sortSpec struct {
+	key   string
+	field string
+	desc  bool
+}
+
+// parseSort parses a sort key such as "-created_at" or "url"
+func parseSort(key string) (sortSpec, error) {
+	if key == "" {
+		key = models.SortCreatedAtDesc
+	}
+
+	spec := sortSpec{key: key, field: strings.TrimPrefix(key, "-"), desc: strings.HasPrefix(key, "-")}
+	switch spec.field {
+	case "created_at", "url", "title":
+		return spec, nil
+	default:
+		return sortSpec{}, ErrInvalidSort
+	}
+}
+
+// listCursor is the decoded form of an opaque listing cursor
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// decodeCursor decodes a cursor and checks it matches the sort order
+func decodeCursor(raw string, spec sortSpec) (*listCursor, primitive.ObjectID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != spec.key {
+		return nil, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	if spec.field == "created_at" {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return nil, primitive.NilObjectID, ErrInvalidCursor
+		}
+	}
+
+	return &c, id, nil
+}
+
+// encodeCursor builds the cursor pointing after the given analysis
+func encodeCursor(a *models.AnalysisResult, spec sortSpec) string {
+	c := listCursor{Sort: spec.key, ID: a.ID.Hex()}
+	switch spec.field {
+	case "created_at":
+		c.Value = a.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "url":
+		c.Value = a.URL
+	case "title":
+		c.Value = a.Title
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// listLimit clamps a requested page size
+func listLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		return MaxListLimit
+	}
+	return limit
+}
+
+// newAnalysisPage trims a result set fetched with limit+1 rows into a page
+func newAnalysisPage(analyses []*models.AnalysisResult, limit int, spec sortSpec) *models.AnalysisPage {
+	page := &models.AnalysisPage{Analyses: analyses}
+	if len(analyses) > limit {
+		page.Analyses = analyses[:limit]
+		page.NextCursor = encodeCursor(page.Analyses[limit-1], spec)
+	}
+	if page.Analyses == nil {
+		page.Analyses = []*models.AnalysisResult{}
+	}
+	return page
+}
+
+// domainOf returns the normalised host of a URL for domain filtering
+func domainOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// Repository defines operations on analysis data
+type Repository interface {
+	SaveAnalysis(ctx context.Context, analysis *models.AnalysisResult) error
+	GetAnalysis(ctx context.Context, id string) (*models.AnalysisResult, error)
+	GetRecentAnalyses(ctx context.Context, limit int) ([]*models.AnalysisResult, error)
+	GetUserAnalyses(ctx context.Context, userID string, limit int) ([]*models.AnalysisResult, error)
+	ListAnalyses(ctx context.Context, query models.AnalysisQuery) (*models.AnalysisPage, error)
+	SearchAnalyses(ctx context.Context, query models.SearchQuery) ([]*models.SearchHit, error)
+
+	// Deletion methods; deleting an analysis also deletes its deep analyses
+	DeleteAnalysis(ctx context.Context, id string) (bool, error)
+	DeleteAnalyses(ctx context.Context, filter models.AnalysisFilter) (int64, error)
+	// DisownProjectAnalyses removes the user ID from a user's project
+	// analyses, which stay with their project
+	DisownProjectAnalyses(ctx context.Context, userID string) (int64, error)
+	DeleteDeepAnalysesBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// Deep analysis methods. Every save stores a new version; GetDeepAnalysis
+	// returns the latest one.
+	SaveDeepAnalysis(ctx context.Context, analysis *models.DeepAnalysisResult) error
+	GetDeepAnalysis(ctx context.Context, analysisID string) (*models.DeepAnalysisResult, error)
+	GetDeepAnalysisVersion(ctx context.Context, analysisID string, version int) (*models.DeepAnalysisResult, error)
+	ListDeepAnalysisVersions(ctx context.Context, analysisID string) ([]*models.DeepAnalysisVersion, error)
+
+	// API key methods. Keys are looked up by the hash of their secret and
+	// only ever deleted through their owner.
+	SaveAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id string) (bool, error)
+	DeleteUserAPIKeys(ctx context.Context, userID string) (int64, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+
+	// Tenancy methods. Organizations have members with a role each and own
+	// projects; analyses saved with a project ID belong to that project.
+	SaveOrganization(ctx context.Context, org *models.Organization) error
+	GetOrganization(ctx context.Context, id string) (*models.Organization, error)
+	ListUserOrganizations(ctx context.Context, userID string) ([]*models.UserOrganization, error)
+	SaveMembership(ctx context.Context, membership *models.Membership) error
+	GetMembership(ctx context.Context, orgID, userID string) (*models.Membership, error)
+	ListMemberships(ctx context.Context, orgID string) ([]*models.Membership, error)
+	DeleteMembership(ctx context.Context, orgID, userID string) (bool, error)
+	SaveProject(ctx context.Context, project *models.Project) error
+	GetProject(ctx context.Context, id string) (*models.Project, error)
+	ListProjects(ctx context.Context, orgID string) ([]*models.Project, error)
+
+	// Audit log methods. The log is append-only: events are never updated
+	// or deleted through the repository.
+	SaveAuditEvents(ctx context.Context, events []*models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, query models.AuditQuery) (*models.AuditPage, error)
+
+	// Quota methods. Usage is counted per subject (a user or client IP) and
+	// period key; IncrementQuotaUsage returns the new count of each period.
+	GetUserPlan(ctx context.Context, userID string) (string, error)
+	SetUserPlan(ctx context.Context, userID, plan string) error
+	IncrementQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error)
+	GetQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error)
+
+	GetStats(ctx context.Context) (*models.Stats, error)
+	Close(ctx context.Context) error
+}
+
+// New creates the repository selected by the storage configuration
+func New(ctx context.Context, cfg *config.Config) (Repository, error) {
+	switch cfg.Storage.Backend {
+	case "", "mongo":
+		return NewMongoRepository(ctx, cfg.MongoDB)
+	case "postgres", "sqlite":
+		return NewSQLRepository(ctx, cfg.Storage.Backend, cfg.SQL)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Storage.Backend)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// ErrEmptySearch is returned when a search query contains no searchable terms
+var ErrEmptySearch = errors.New("search query has no terms")
+
+// snippetRadius is the number of words kept on each side of the first match
+const snippetRadius = 12
+
+// searchTerms splits a query into lower-cased word terms
+func searchTerms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// highlights builds snippets for every field of an analysis that matches the terms
+func highlights(a *models.AnalysisResult, terms []string) map[string]string {
+	fields := []struct {
+		name, text string
+	}{
+		{"title", a.Title},
+		{"description", a.Description},
+		{"anchor_text", a.AnchorText},
+		{"text", a.TextContent},
+	}
+
+	result := make(map[string]string)
+	for _, f := range fields {
+		if snippet := highlightSnippet(f.text, terms); snippet != "" {
+			result[f.name] = snippet
+		}
+	}
+	return result
+}
+
+// highlightSnippet returns an HTML-escaped excerpt around the first matching
+// word, with every word starting with a search term wrapped in <mark> tags.
+// Prefix matching lets stemmed index hits such as "running" for "run" show up.
+func highlightSnippet(text string, terms []string) string {
+	words := strings.Fields(text)
+
+	first := -1
+	marked := make([]bool, len(words))
+	for i, w := range words {
+		if matchesTerm(w, terms) {
+			marked[i] = true
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := max(first-snippetRadius, 0)
+	end := min(first+snippetRadius+1, len(words))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(words[i]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(words[i]))
+		}
+	}
+	if end < len(words) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
+
+// matchesTerm reports whether a word starts with any of the terms
+func matchesTerm(word string, terms []string) bool {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) {
+			return true
+		}
+	}
+	return false
+}
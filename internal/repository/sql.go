@@ -0,0 +1,1670 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// pgUniqueViolation is the PostgreSQL SQLSTATE for unique constraint violations
+const pgUniqueViolation = "23505"
+
+// SQLRepository implements Repository interface for database/sql backends
+type SQLRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+// sqlMigration is a single schema migration step. dialects holds extra
+// statements for a single backend; backfill, if set, runs after the
+// statements inside the same transaction.
+type sqlMigration struct {
+	version    int
+	statements []string
+	dialects   map[string][]string
+	backfill   func(ctx context.Context, tx *sql.Tx, r *SQLRepository) error
+}
+
+// sqlMigrations holds the schema history, applied in order. {json} and
+// {timestamp} are replaced with the column types of the active dialect.
+var sqlMigrations = []sqlMigration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE analyses (
+				id TEXT PRIMARY KEY,
+				url TEXT NOT NULL,
+				html_version TEXT NOT NULL DEFAULT '',
+				title TEXT NOT NULL DEFAULT '',
+				headings {json} NOT NULL,
+				internal_links {json} NOT NULL,
+				external_links {json} NOT NULL,
+				has_login_form BOOLEAN NOT NULL DEFAULT FALSE,
+				user_id TEXT,
+				created_at {timestamp} NOT NULL
+			)`,
+			`CREATE INDEX idx_analyses_url ON analyses (url)`,
+			`CREATE INDEX idx_analyses_user_id ON analyses (user_id)`,
+			`CREATE INDEX idx_analyses_created_at ON analyses (created_at DESC)`,
+			`CREATE TABLE deep_analyses (
+				id TEXT PRIMARY KEY,
+				analysis_id TEXT NOT NULL,
+				url TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				performance {json} NOT NULL,
+				seo {json} NOT NULL,
+				accessibility {json} NOT NULL,
+				content {json} NOT NULL,
+				security {json} NOT NULL,
+				mobile {json} NOT NULL,
+				social {json} NOT NULL,
+				technology {json} NOT NULL,
+				media {json} NOT NULL,
+				schema_markup {json} NOT NULL,
+				cookies {json} NOT NULL,
+				links {json} NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX idx_deep_analyses_analysis_id ON deep_analyses (analysis_id)`,
+			`CREATE INDEX idx_deep_analyses_created_at ON deep_analyses (created_at DESC)`,
+		},
+	},
+	{
+		version: 2,
+		statements: []string{
+			`ALTER TABLE analyses ADD COLUMN domain TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE analyses ADD COLUMN broken_links INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX idx_analyses_created_at_id ON analyses (created_at DESC, id DESC)`,
+			`CREATE INDEX idx_analyses_url_id ON analyses (url, id)`,
+			`CREATE INDEX idx_analyses_title_id ON analyses (title, id)`,
+			`CREATE INDEX idx_analyses_user_id_created_at ON analyses (user_id, created_at DESC, id DESC)`,
+			`CREATE INDEX idx_analyses_domain_created_at ON analyses (domain, created_at DESC)`,
+			`CREATE INDEX idx_analyses_html_version ON analyses (html_version)`,
+			`CREATE INDEX idx_analyses_has_login_form ON analyses (has_login_form)`,
+		},
+		backfill: backfillListingColumns,
+	},
+	{
+		version: 3,
+		statements: []string{
+			`ALTER TABLE analyses ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE analyses ADD COLUMN anchor_text TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE analyses ADD COLUMN text_content TEXT NOT NULL DEFAULT ''`,
+		},
+		dialects: map[string][]string{
+			"sqlite": {
+				`CREATE VIRTUAL TABLE analyses_fts USING fts5(
+					id UNINDEXED, title, description, anchor_text, text_content,
+					tokenize = 'porter unicode61'
+				)`,
+				`INSERT INTO analyses_fts (id, title, description, anchor_text, text_content)
+					SELECT id, title, description, anchor_text, text_content FROM analyses`,
+			},
+			"postgres": {
+				`ALTER TABLE analyses ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('english', title), 'A') ||
+					setweight(to_tsvector('english', description), 'B') ||
+					setweight(to_tsvector('english', anchor_text), 'C') ||
+					setweight(to_tsvector('english', text_content), 'D')
+				) STORED`,
+				`CREATE INDEX idx_analyses_search_vector ON analyses USING GIN (search_vector)`,
+			},
+		},
+	},
+	{
+		version: 4,
+		statements: []string{
+			`DROP INDEX idx_deep_analyses_analysis_id`,
+			`ALTER TABLE deep_analyses ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+			`ALTER TABLE deep_analyses ADD COLUMN analyzer_version TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE deep_analyses ADD COLUMN rules_version TEXT NOT NULL DEFAULT ''`,
+			`CREATE UNIQUE INDEX idx_deep_analyses_analysis_id_version ON deep_analyses (analysis_id, version DESC)`,
+		},
+	},
+	{
+		version: 5,
+		statements: []string{
+			`CREATE TABLE api_keys (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				prefix TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				scopes {json} NOT NULL,
+				owner {json} NOT NULL,
+				expires_at {timestamp},
+				last_used_at {timestamp},
+				created_at {timestamp} NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX idx_api_keys_hash ON api_keys (hash)`,
+			`CREATE INDEX idx_api_keys_user_id ON api_keys (user_id, created_at DESC)`,
+		},
+	},
+	{
+		// Tenancy: organizations with member roles own projects, and
+		// analyses may belong to a project ('' for personal analyses)
+		version: 6,
+		statements: []string{
+			`ALTER TABLE analyses ADD COLUMN project_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_analyses_project_id ON analyses (project_id, created_at DESC, id DESC)`,
+			`CREATE TABLE organizations (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				created_by TEXT NOT NULL,
+				created_at {timestamp} NOT NULL
+			)`,
+			`CREATE TABLE projects (
+				id TEXT PRIMARY KEY,
+				organization_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				created_by TEXT NOT NULL,
+				created_at {timestamp} NOT NULL
+			)`,
+			`CREATE INDEX idx_projects_organization_id ON projects (organization_id, created_at)`,
+			`CREATE TABLE memberships (
+				organization_id TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				role TEXT NOT NULL,
+				created_at {timestamp} NOT NULL,
+				PRIMARY KEY (organization_id, user_id)
+			)`,
+			`CREATE INDEX idx_memberships_user_id ON memberships (user_id)`,
+		},
+	},
+	{
+		// Append-only audit log of security-relevant requests
+		version: 7,
+		statements: []string{
+			`CREATE TABLE audit_log (
+				id TEXT PRIMARY KEY,
+				actor_id TEXT NOT NULL,
+				api_key_id TEXT NOT NULL,
+				action TEXT NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id TEXT NOT NULL,
+				detail TEXT NOT NULL,
+				method TEXT NOT NULL,
+				path TEXT NOT NULL,
+				status INTEGER NOT NULL,
+				outcome TEXT NOT NULL,
+				ip TEXT NOT NULL,
+				user_agent TEXT NOT NULL,
+				created_at {timestamp} NOT NULL
+			)`,
+			`CREATE INDEX idx_audit_log_created_at ON audit_log (created_at DESC, id DESC)`,
+			`CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id, created_at DESC)`,
+			`CREATE INDEX idx_audit_log_action ON audit_log (action, created_at DESC)`,
+		},
+	},
+	{
+		version: 8,
+		statements: []string{
+			`CREATE TABLE user_plans (
+				user_id TEXT PRIMARY KEY,
+				plan TEXT NOT NULL,
+				updated_at {timestamp} NOT NULL
+			)`,
+			`CREATE TABLE quota_usage (
+				subject TEXT NOT NULL,
+				period TEXT NOT NULL,
+				count BIGINT NOT NULL,
+				PRIMARY KEY (subject, period)
+			)`,
+		},
+	},
+	{
+		// Privacy reports of deep analyses; earlier versions have none
+		version: 9,
+		statements: []string{
+			`ALTER TABLE deep_analyses ADD COLUMN privacy {json} NOT NULL DEFAULT '{}'`,
+		},
+	},
+}
+
+// NewSQLRepository creates a new database/sql repository for the given
+// backend ("postgres" or "sqlite") and applies pending migrations
+func NewSQLRepository(ctx context.Context, backend string, cfg config.SQLConfig) (*SQLRepository, error) {
+	var driver string
+	switch backend {
+	case "postgres":
+		driver = "pgx"
+	case "sqlite":
+		driver = "sqlite"
+	default:
+		return nil, fmt.Errorf("unsupported SQL backend: %s", backend)
+	}
+
+	db, err := sql.Open(driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if backend == "sqlite" {
+		// SQLite allows a single writer; serialise access through one connection
+		db.SetMaxOpenConns(1)
+	} else if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+
+	r := &SQLRepository{
+		db:      db,
+		dialect: backend,
+	}
+
+	// Check the connection
+	pingCtx := ctx
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		pingCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	if err := db.PingContext(pingCtx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := r.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return r, nil
+}
+
+// migrate applies all migrations that have not been recorded yet
+func (r *SQLRepository) migrate(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, r.schema(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at {timestamp} NOT NULL
+	)`)); err != nil {
+		return err
+	}
+
+	var current int
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range sqlMigrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range append(m.statements, m.dialects[r.dialect]...) {
+			if _, err := tx.ExecContext(ctx, r.schema(stmt)); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", m.version, err)
+			}
+		}
+
+		if m.backfill != nil {
+			if err := m.backfill(ctx, tx, r); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", m.version, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`),
+			m.version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", m.version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", m.version, err)
+		}
+	}
+
+	return nil
+}
+
+// schema substitutes dialect-specific column types into a DDL statement
+func (r *SQLRepository) schema(stmt string) string {
+	jsonType, timestampType := "TEXT", "TIMESTAMP"
+	if r.dialect == "postgres" {
+		jsonType, timestampType = "JSONB", "TIMESTAMPTZ"
+	}
+	return strings.NewReplacer("{json}", jsonType, "{timestamp}", timestampType).Replace(stmt)
+}
+
+// rebind converts ? placeholders into the dialect's positional form
+func (r *SQLRepository) rebind(query string) string {
+	if r.dialect != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// backfillListingColumns populates domain and broken_links on existing rows
+func backfillListingColumns(ctx context.Context, tx *sql.Tx, r *SQLRepository) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, url, internal_links, external_links FROM analyses`)
+	if err != nil {
+		return err
+	}
+
+	type listingColumns struct {
+		id, domain  string
+		brokenLinks int
+	}
+
+	var updates []listingColumns
+	for rows.Next() {
+		var (
+			id, rawURL               string
+			internalRaw, externalRaw []byte
+			internal, external       models.LinkStatus
+		)
+		if err := rows.Scan(&id, &rawURL, &internalRaw, &externalRaw); err != nil {
+			rows.Close()
+			return err
+		}
+		json.Unmarshal(internalRaw, &internal)
+		json.Unmarshal(externalRaw, &external)
+		updates = append(updates, listingColumns{
+			id:          id,
+			domain:      domainOf(rawURL),
+			brokenLinks: internal.Inaccessible + external.Inaccessible,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, r.rebind(`UPDATE analyses SET domain = ?, broken_links = ? WHERE id = ?`),
+			u.domain, u.brokenLinks, u.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const analysisColumns = `id, url, domain, html_version, title, headings, internal_links, external_links, has_login_form, user_id, created_at, description, anchor_text, text_content, project_id`
+
+// qualifiedColumns prefixes every column of a column list with a table alias
+func qualifiedColumns(alias, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, p := range parts {
+		parts[i] = alias + "." + p
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SaveAnalysis saves an analysis result to the database
+func (r *SQLRepository) SaveAnalysis(ctx context.Context, analysis *models.AnalysisResult) error {
+	// Set creation time if not set
+	if analysis.CreatedAt.IsZero() {
+		analysis.CreatedAt = time.Now()
+	}
+	if analysis.Domain == "" {
+		analysis.Domain = domainOf(analysis.URL)
+	}
+
+	id := analysis.ID
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+
+	headings, err := json.Marshal(analysis.Headings)
+	if err != nil {
+		return err
+	}
+	internalLinks, err := json.Marshal(analysis.InternalLinks)
+	if err != nil {
+		return err
+	}
+	externalLinks, err := json.Marshal(analysis.ExternalLinks)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO analyses (`+analysisColumns+`, broken_links) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		id.Hex(),
+		analysis.URL,
+		analysis.Domain,
+		analysis.HTMLVersion,
+		analysis.Title,
+		string(headings),
+		string(internalLinks),
+		string(externalLinks),
+		analysis.HasLoginForm,
+		nullString(analysis.UserID),
+		analysis.CreatedAt.UTC(),
+		analysis.Description,
+		analysis.AnchorText,
+		analysis.TextContent,
+		analysis.ProjectID,
+		analysis.InternalLinks.Inaccessible+analysis.ExternalLinks.Inaccessible,
+	)
+	if err != nil {
+		return err
+	}
+
+	// SQLite keeps the full-text index in a separate FTS5 table
+	if r.dialect == "sqlite" {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO analyses_fts (id, title, description, anchor_text, text_content) VALUES (?, ?, ?, ?, ?)`,
+			id.Hex(), analysis.Title, analysis.Description, analysis.AnchorText, analysis.TextContent); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Update ID in the analysis object
+	analysis.ID = id
+
+	return nil
+}
+
+// GetAnalysis retrieves an analysis by ID
+func (r *SQLRepository) GetAnalysis(ctx context.Context, id string) (*models.AnalysisResult, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+analysisColumns+` FROM analyses WHERE id = ?`), objectID.Hex())
+	analysis, err := scanAnalysis(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+// GetRecentAnalyses retrieves the most recent analyses
+func (r *SQLRepository) GetRecentAnalyses(ctx context.Context, limit int) ([]*models.AnalysisResult, error) {
+	return r.queryAnalyses(ctx, `SELECT `+analysisColumns+` FROM analyses ORDER BY created_at DESC LIMIT ?`, limit)
+}
+
+// GetUserAnalyses retrieves the personal analyses of a specific user
+func (r *SQLRepository) GetUserAnalyses(ctx context.Context, userID string, limit int) ([]*models.AnalysisResult, error) {
+	return r.queryAnalyses(ctx, `SELECT `+analysisColumns+` FROM analyses WHERE user_id = ? AND project_id = '' ORDER BY created_at DESC LIMIT ?`, userID, limit)
+}
+
+// ListAnalyses retrieves a filtered, sorted page of analyses
+func (r *SQLRepository) ListAnalyses(ctx context.Context, query models.AnalysisQuery) (*models.AnalysisPage, error) {
+	spec, err := parseSort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+	limit := listLimit(query.Limit)
+
+	direction := "ASC"
+	comparison := ">"
+	if spec.desc {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	conditions, args := sqlAnalysisFilter(query.Filter)
+
+	// Keyset pagination: continue strictly after the cursor's (field, id)
+	if query.Cursor != "" {
+		c, id, err := decodeCursor(query.Cursor, spec)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{} = c.Value
+		if spec.field == "created_at" {
+			t, _ := time.Parse(time.RFC3339Nano, c.Value)
+			value = t.UTC()
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", spec.field, comparison))
+		args = append(args, value, value, id.Hex())
+	}
+
+	q := `SELECT ` + analysisColumns + ` FROM analyses`
+	if len(conditions) > 0 {
+		q += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	q += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s LIMIT ?", spec.field, direction)
+	args = append(args, limit+1)
+
+	analyses, err := r.queryAnalyses(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAnalysisPage(analyses, limit, spec), nil
+}
+
+// sqlAnalysisFilter converts a listing filter into WHERE conditions and arguments
+func sqlAnalysisFilter(f models.AnalysisFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, f.ProjectID)
+	} else if f.UserID != "" && !f.AllProjects {
+		// Personal analyses are those outside any project
+		conditions = append(conditions, "project_id = ''")
+	}
+	if f.Domain != "" {
+		conditions = append(conditions, "domain = ?")
+		args = append(args, strings.ToLower(f.Domain))
+	}
+	if f.URLPrefix != "" {
+		escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+		conditions = append(conditions, `url LIKE ? ESCAPE '\'`)
+		args = append(args, escaper.Replace(f.URLPrefix)+"%")
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, f.To.UTC())
+	}
+	if f.HasLoginForm != nil {
+		conditions = append(conditions, "has_login_form = ?")
+		args = append(args, *f.HasLoginForm)
+	}
+	if f.HTMLVersion != "" {
+		conditions = append(conditions, "html_version = ?")
+		args = append(args, f.HTMLVersion)
+	}
+	if f.HasBrokenLinks != nil {
+		if *f.HasBrokenLinks {
+			conditions = append(conditions, "broken_links > 0")
+		} else {
+			conditions = append(conditions, "broken_links = 0")
+		}
+	}
+
+	return conditions, args
+}
+
+// SearchAnalyses performs a ranked full-text search over analyzed pages
+func (r *SQLRepository) SearchAnalyses(ctx context.Context, query models.SearchQuery) ([]*models.SearchHit, error) {
+	terms := searchTerms(query.Query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	var q string
+	var args []interface{}
+	if r.dialect == "postgres" {
+		q = `SELECT ` + analysisColumns + `, ts_rank(search_vector, plainto_tsquery('english', ?)) AS score
+			FROM analyses WHERE search_vector @@ plainto_tsquery('english', ?)`
+		text := strings.Join(terms, " ")
+		args = append(args, text, text)
+	} else {
+		// Quoting every term makes all of them required; bm25 is lower for better matches
+		phrases := make([]string, len(terms))
+		for i, t := range terms {
+			phrases[i] = `"` + t + `"`
+		}
+		q = `SELECT ` + qualifiedColumns("a", analysisColumns) + `, -bm25(analyses_fts, 0, 10.0, 5.0, 2.0, 1.0) AS score
+			FROM analyses_fts JOIN analyses a ON a.id = analyses_fts.id
+			WHERE analyses_fts MATCH ?`
+		args = append(args, strings.Join(phrases, " "))
+	}
+
+	// The scope columns only exist on analyses, so they need no table alias
+	conditions, scopeArgs := sqlAnalysisFilter(models.AnalysisFilter{UserID: query.UserID, ProjectID: query.ProjectID})
+	for _, condition := range conditions {
+		q += ` AND ` + condition
+	}
+	args = append(args, scopeArgs...)
+	q += ` ORDER BY score DESC LIMIT ?`
+	args = append(args, listLimit(query.Limit))
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []*models.SearchHit
+	for rows.Next() {
+		var score float64
+		analysis, err := scanAnalysis(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, &models.SearchHit{
+			Analysis:   analysis,
+			Score:      score,
+			Highlights: highlights(analysis, terms),
+		})
+	}
+
+	return hits, rows.Err()
+}
+
+// queryAnalyses runs a query returning analysis rows
+func (r *SQLRepository) queryAnalyses(ctx context.Context, query string, args ...interface{}) ([]*models.AnalysisResult, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analyses []*models.AnalysisResult
+	for rows.Next() {
+		analysis, err := scanAnalysis(rows)
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, rows.Err()
+}
+
+const deepAnalysisColumns = `id, analysis_id, url, created_at, version, analyzer_version, rules_version, performance, seo, accessibility, content, security, mobile, social, technology, media, schema_markup, cookies, links, privacy`
+
+// SaveDeepAnalysis stores a deep analysis result as the next version of its analysis
+func (r *SQLRepository) SaveDeepAnalysis(ctx context.Context, analysis *models.DeepAnalysisResult) error {
+	// Set creation time if not set
+	if analysis.CreatedAt.IsZero() {
+		analysis.CreatedAt = time.Now()
+	}
+
+	if analysis.ID.IsZero() {
+		analysis.ID = primitive.NewObjectID()
+	}
+
+	sections, err := marshalDeepSections(analysis)
+	if err != nil {
+		return err
+	}
+
+	// Concurrent runs may pick the same version; the unique index rejects
+	// the loser, which then retries with the following version
+	for attempt := 0; ; attempt++ {
+		err = r.insertDeepAnalysisVersion(ctx, analysis, sections)
+
+		var pgErr *pgconn.PgError
+		if attempt+1 < saveVersionAttempts && errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			continue
+		}
+		return err
+	}
+}
+
+// insertDeepAnalysisVersion inserts a deep analysis with the next free version
+func (r *SQLRepository) insertDeepAnalysisVersion(ctx context.Context, analysis *models.DeepAnalysisResult, sections []interface{}) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	err = tx.QueryRowContext(ctx, r.rebind(`SELECT COALESCE(MAX(version), 0) + 1 FROM deep_analyses WHERE analysis_id = ?`),
+		analysis.AnalysisID.Hex()).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{
+		analysis.ID.Hex(),
+		analysis.AnalysisID.Hex(),
+		analysis.URL,
+		analysis.CreatedAt.UTC(),
+		version,
+		analysis.AnalyzerVersion,
+		analysis.RulesVersion,
+	}
+	args = append(args, sections...)
+
+	_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO deep_analyses (`+deepAnalysisColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`), args...)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	analysis.Version = version
+	return nil
+}
+
+// GetDeepAnalysis retrieves the latest deep analysis by analysis ID
+func (r *SQLRepository) GetDeepAnalysis(ctx context.Context, analysisID string) (*models.DeepAnalysisResult, error) {
+	objectID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+deepAnalysisColumns+` FROM deep_analyses
+		WHERE analysis_id = ? ORDER BY version DESC LIMIT 1`), objectID.Hex())
+	analysis, err := scanDeepAnalysis(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+// GetDeepAnalysisVersion retrieves a specific deep analysis version
+func (r *SQLRepository) GetDeepAnalysisVersion(ctx context.Context, analysisID string, version int) (*models.DeepAnalysisResult, error) {
+	objectID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+deepAnalysisColumns+` FROM deep_analyses
+		WHERE analysis_id = ? AND version = ?`), objectID.Hex(), version)
+	analysis, err := scanDeepAnalysis(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+// ListDeepAnalysisVersions lists the stored deep analysis versions, newest first
+func (r *SQLRepository) ListDeepAnalysisVersions(ctx context.Context, analysisID string) ([]*models.DeepAnalysisVersion, error) {
+	objectID, err := primitive.ObjectIDFromHex(analysisID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT id, version, analyzer_version, rules_version, created_at
+		FROM deep_analyses WHERE analysis_id = ? ORDER BY version DESC`), objectID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*models.DeepAnalysisVersion{}
+	for rows.Next() {
+		var id string
+		v := &models.DeepAnalysisVersion{AnalysisID: objectID}
+		if err := rows.Scan(&id, &v.Version, &v.AnalyzerVersion, &v.RulesVersion, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if v.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// DeleteAnalysis deletes an analysis and its deep analyses
+func (r *SQLRepository) DeleteAnalysis(ctx context.Context, id string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := r.deleteAnalysesWhere(ctx, []string{"id = ?"}, []interface{}{objectID.Hex()})
+	return deleted > 0, err
+}
+
+// DisownProjectAnalyses removes the user ID from a user's project analyses
+func (r *SQLRepository) DisownProjectAnalyses(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.rebind(`UPDATE analyses SET user_id = NULL WHERE user_id = ? AND project_id <> ''`), userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteAnalyses deletes all analyses matching a filter and their deep analyses
+func (r *SQLRepository) DeleteAnalyses(ctx context.Context, filter models.AnalysisFilter) (int64, error) {
+	conditions, args := sqlAnalysisFilter(filter)
+	return r.deleteAnalysesWhere(ctx, conditions, args)
+}
+
+// deleteAnalysesWhere deletes matching analyses together with their deep
+// analyses and search index entries in one transaction
+func (r *SQLRepository) deleteAnalysesWhere(ctx context.Context, conditions []string, args []interface{}) (int64, error) {
+	where := ""
+	if len(conditions) > 0 {
+		where = ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, r.rebind(`DELETE FROM deep_analyses WHERE analysis_id IN (SELECT id FROM analyses`+where+`)`), args...); err != nil {
+		return 0, err
+	}
+
+	if r.dialect == "sqlite" {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM analyses_fts WHERE id IN (SELECT id FROM analyses`+where+`)`, args...); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, r.rebind(`DELETE FROM analyses`+where), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit()
+}
+
+// DeleteDeepAnalysesBefore deletes deep analyses created before the given time
+func (r *SQLRepository) DeleteDeepAnalysesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM deep_analyses WHERE created_at < ?`), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, hash, scopes, owner, expires_at, last_used_at, created_at`
+
+// SaveAPIKey stores a new API key
+func (r *SQLRepository) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	owner, err := json.Marshal(key.Owner)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, r.rebind(`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		key.ID.Hex(),
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		string(scopes),
+		string(owner),
+		nullTime(key.ExpiresAt),
+		nullTime(key.LastUsedAt),
+		key.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of its secret
+func (r *SQLRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = ?`), hash)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys lists a user's API keys, newest first
+func (r *SQLRepository) ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE user_id = ? ORDER BY created_at DESC`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey deletes one of a user's API keys
+func (r *SQLRepository) DeleteAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	// No key has a malformed ID
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+
+	result, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM api_keys WHERE id = ? AND user_id = ?`), objectID.Hex(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// DeleteUserAPIKeys deletes all of a user's API keys
+func (r *SQLRepository) DeleteUserAPIKeys(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM api_keys WHERE user_id = ?`), userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// TouchAPIKey records when an API key was last used
+func (r *SQLRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`), usedAt.UTC(), id)
+	return err
+}
+
+// scanAPIKey scans an api_keys row
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var (
+		id                  string
+		scopes, owner       string
+		expiresAt, lastUsed sql.NullTime
+		key                 models.APIKey
+	)
+
+	if err := row.Scan(&id, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &scopes, &owner, &expiresAt, &lastUsed, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if key.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(owner), &key.Owner); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+
+	return &key, nil
+}
+
+// SaveOrganization stores a new organization
+func (r *SQLRepository) SaveOrganization(ctx context.Context, org *models.Organization) error {
+	if org.CreatedAt.IsZero() {
+		org.CreatedAt = time.Now()
+	}
+	if org.ID.IsZero() {
+		org.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO organizations (id, name, created_by, created_at) VALUES (?, ?, ?, ?)`),
+		org.ID.Hex(), org.Name, org.CreatedBy, org.CreatedAt.UTC())
+	return err
+}
+
+// GetOrganization retrieves an organization by ID
+func (r *SQLRepository) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var org models.Organization
+	err = r.db.QueryRowContext(ctx, r.rebind(`SELECT name, created_by, created_at FROM organizations WHERE id = ?`), objectID.Hex()).
+		Scan(&org.Name, &org.CreatedBy, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+	org.ID = objectID
+
+	return &org, nil
+}
+
+// ListUserOrganizations lists the organizations a user is a member of,
+// together with the user's role in each
+func (r *SQLRepository) ListUserOrganizations(ctx context.Context, userID string) ([]*models.UserOrganization, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT o.id, o.name, o.created_by, o.created_at, m.role
+		FROM organizations o JOIN memberships m ON m.organization_id = o.id
+		WHERE m.user_id = ? ORDER BY o.created_at`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*models.UserOrganization{}
+	for rows.Next() {
+		var (
+			id  string
+			org = &models.UserOrganization{Organization: &models.Organization{}}
+		)
+		if err := rows.Scan(&id, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.Role); err != nil {
+			return nil, err
+		}
+		if org.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// SaveMembership adds a member to an organization or updates their role
+func (r *SQLRepository) SaveMembership(ctx context.Context, membership *models.Membership) error {
+	if membership.CreatedAt.IsZero() {
+		membership.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO memberships (organization_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role = excluded.role`),
+		membership.OrganizationID.Hex(), membership.UserID, membership.Role, membership.CreatedAt.UTC())
+	return err
+}
+
+// GetMembership retrieves a user's membership in an organization
+func (r *SQLRepository) GetMembership(ctx context.Context, orgID, userID string) (*models.Membership, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := models.Membership{OrganizationID: objectID, UserID: userID}
+	err = r.db.QueryRowContext(ctx, r.rebind(`SELECT role, created_at FROM memberships WHERE organization_id = ? AND user_id = ?`),
+		objectID.Hex(), userID).Scan(&membership.Role, &membership.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+// ListMemberships lists the members of an organization in the order they joined
+func (r *SQLRepository) ListMemberships(ctx context.Context, orgID string) ([]*models.Membership, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT user_id, role, created_at FROM memberships
+		WHERE organization_id = ? ORDER BY created_at`), objectID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []*models.Membership{}
+	for rows.Next() {
+		membership := &models.Membership{OrganizationID: objectID}
+		if err := rows.Scan(&membership.UserID, &membership.Role, &membership.CreatedAt); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, rows.Err()
+}
+
+// DeleteMembership removes a user from an organization
+func (r *SQLRepository) DeleteMembership(ctx context.Context, orgID, userID string) (bool, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM memberships WHERE organization_id = ? AND user_id = ?`), objectID.Hex(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// SaveProject stores a new project
+func (r *SQLRepository) SaveProject(ctx context.Context, project *models.Project) error {
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = time.Now()
+	}
+	if project.ID.IsZero() {
+		project.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO projects (`+projectColumns+`) VALUES (?, ?, ?, ?, ?)`),
+		project.ID.Hex(), project.OrganizationID.Hex(), project.Name, project.CreatedBy, project.CreatedAt.UTC())
+	return err
+}
+
+// GetProject retrieves a project by ID
+func (r *SQLRepository) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+projectColumns+` FROM projects WHERE id = ?`), objectID.Hex())
+	project, err := scanProject(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// ListProjects lists an organization's projects in creation order
+func (r *SQLRepository) ListProjects(ctx context.Context, orgID string) ([]*models.Project, error) {
+	objectID, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT `+projectColumns+` FROM projects
+		WHERE organization_id = ? ORDER BY created_at`), objectID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []*models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// projectColumns lists the projects columns in scan order
+const projectColumns = `id, organization_id, name, created_by, created_at`
+
+// scanProject scans a projects row
+func scanProject(row rowScanner) (*models.Project, error) {
+	var (
+		id, orgID string
+		project   models.Project
+	)
+
+	if err := row.Scan(&id, &orgID, &project.Name, &project.CreatedBy, &project.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if project.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+	if project.OrganizationID, err = primitive.ObjectIDFromHex(orgID); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+const auditColumns = `id, actor_id, api_key_id, action, target_type, target_id, detail, method, path, status, outcome, ip, user_agent, created_at`
+
+// SaveAuditEvents appends events to the audit log in a single transaction
+func (r *SQLRepository) SaveAuditEvents(ctx context.Context, events []*models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.rebind(`INSERT INTO audit_log (`+auditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range events {
+		if e.ID.IsZero() {
+			e.ID = primitive.NewObjectID()
+		}
+		if _, err := stmt.ExecContext(ctx,
+			e.ID.Hex(),
+			e.ActorID,
+			e.APIKeyID,
+			e.Action,
+			e.TargetType,
+			e.TargetID,
+			e.Detail,
+			e.Method,
+			e.Path,
+			e.Status,
+			e.Outcome,
+			e.IP,
+			e.UserAgent,
+			e.CreatedAt.UTC(),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListAuditEvents retrieves a page of the audit log, newest first
+func (r *SQLRepository) ListAuditEvents(ctx context.Context, query models.AuditQuery) (*models.AuditPage, error) {
+	limit := listLimit(query.Limit)
+	conditions, args := sqlAuditFilter(query.Filter)
+
+	// Keyset pagination: continue strictly after the cursor's (created_at, id)
+	if query.Cursor != "" {
+		c, id, err := decodeCursor(query.Cursor, auditSort)
+		if err != nil {
+			return nil, err
+		}
+
+		createdAt, _ := time.Parse(time.RFC3339Nano, c.Value)
+		conditions = append(conditions, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, createdAt.UTC(), createdAt.UTC(), id.Hex())
+	}
+
+	q := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		q += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	q += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		var (
+			id string
+			e  models.AuditEvent
+		)
+		if err := rows.Scan(&id, &e.ActorID, &e.APIKeyID, &e.Action, &e.TargetType, &e.TargetID, &e.Detail,
+			&e.Method, &e.Path, &e.Status, &e.Outcome, &e.IP, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if e.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newAuditPage(events, limit), nil
+}
+
+// sqlAuditFilter converts an audit filter into WHERE conditions and arguments
+func sqlAuditFilter(f models.AuditFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	for _, c := range []struct{ column, value string }{
+		{"actor_id", f.ActorID},
+		{"action", f.Action},
+		{"target_type", f.TargetType},
+		{"target_id", f.TargetID},
+		{"outcome", f.Outcome},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, f.To.UTC())
+	}
+
+	return conditions, args
+}
+
+// GetUserPlan returns the quota plan assigned to a user, or "" if none is
+func (r *SQLRepository) GetUserPlan(ctx context.Context, userID string) (string, error) {
+	var plan string
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT plan FROM user_plans WHERE user_id = ?`), userID).Scan(&plan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return plan, err
+}
+
+// SetUserPlan assigns a quota plan to a user
+func (r *SQLRepository) SetUserPlan(ctx context.Context, userID, plan string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO user_plans (user_id, plan, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET plan = excluded.plan, updated_at = excluded.updated_at`),
+		userID, plan, time.Now().UTC())
+	return err
+}
+
+// IncrementQuotaUsage counts one use against each period of a subject
+func (r *SQLRepository) IncrementQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	counts := make([]int64, len(periods))
+	for i, period := range periods {
+		err := tx.QueryRowContext(ctx, r.rebind(`INSERT INTO quota_usage (subject, period, count) VALUES (?, ?, 1)
+			ON CONFLICT (subject, period) DO UPDATE SET count = quota_usage.count + 1
+			RETURNING count`), subject, period).Scan(&counts[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, tx.Commit()
+}
+
+// GetQuotaUsage returns the usage of each period of a subject
+func (r *SQLRepository) GetQuotaUsage(ctx context.Context, subject string, periods []string) ([]int64, error) {
+	counts := make([]int64, len(periods))
+	for i, period := range periods {
+		err := r.db.QueryRowContext(ctx, r.rebind(`SELECT count FROM quota_usage WHERE subject = ? AND period = ?`),
+			subject, period).Scan(&counts[i])
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// GetStats retrieves application statistics
+func (r *SQLRepository) GetStats(ctx context.Context) (*models.Stats, error) {
+	now := time.Now().UTC()
+	stats := &models.Stats{
+		LastUpdated: now,
+	}
+
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT
+			COUNT(*),
+			COUNT(DISTINCT url),
+			COUNT(DISTINCT user_id),
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0)
+		FROM analyses`),
+		now.Add(-24*time.Hour),
+		now.Add(-7*24*time.Hour),
+		now.Add(-30*24*time.Hour),
+	).Scan(
+		&stats.TotalAnalyses,
+		&stats.UniqueURLs,
+		&stats.RegisteredUsers,
+		&stats.AnalysesLast24h,
+		&stats.AnalysesLast7d,
+		&stats.AnalysesLast30d,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aggregate per-URL counts into domains
+	rows, err := r.db.QueryContext(ctx, `SELECT url, COUNT(*) FROM analyses GROUP BY url`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := make(map[string]int)
+	for rows.Next() {
+		var rawURL string
+		var count int
+		if err := rows.Scan(&rawURL, &count); err != nil {
+			return nil, err
+		}
+		if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+			domains[parsed.Host] += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	best := 0
+	for domain, count := range domains {
+		if count > best || (count == best && domain < stats.MostAnalyzedDomain) {
+			best = count
+			stats.MostAnalyzedDomain = domain
+		}
+	}
+
+	return stats, nil
+}
+
+// Close closes the database connection
+func (r *SQLRepository) Close(ctx context.Context) error {
+	return r.db.Close()
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAnalysis reads an analysis row selected with analysisColumns, followed
+// by any extra columns
+func scanAnalysis(row rowScanner, extra ...interface{}) (*models.AnalysisResult, error) {
+	var (
+		analysis                               models.AnalysisResult
+		id                                     string
+		headings, internalLinks, externalLinks []byte
+		userID                                 sql.NullString
+	)
+
+	dest := []interface{}{
+		&id,
+		&analysis.URL,
+		&analysis.Domain,
+		&analysis.HTMLVersion,
+		&analysis.Title,
+		&headings,
+		&internalLinks,
+		&externalLinks,
+		&analysis.HasLoginForm,
+		&userID,
+		&analysis.CreatedAt,
+		&analysis.Description,
+		&analysis.AnchorText,
+		&analysis.TextContent,
+		&analysis.ProjectID,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	analysis.ID = objectID
+	analysis.UserID = userID.String
+
+	if err := json.Unmarshal(headings, &analysis.Headings); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(internalLinks, &analysis.InternalLinks); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(externalLinks, &analysis.ExternalLinks); err != nil {
+		return nil, err
+	}
+
+	return &analysis, nil
+}
+
+// deepSections lists the nested deep analysis structs stored as JSON columns,
+// in the same order as deepAnalysisColumns
+func deepSections(a *models.DeepAnalysisResult) []interface{} {
+	return []interface{}{
+		&a.Performance,
+		&a.SEO,
+		&a.Accessibility,
+		&a.Content,
+		&a.Security,
+		&a.Mobile,
+		&a.Social,
+		&a.Technology,
+		&a.Media,
+		&a.Schema,
+		&a.Cookies,
+		&a.Links,
+		&a.Privacy,
+	}
+}
+
+// marshalDeepSections encodes the nested deep analysis structs as JSON
+func marshalDeepSections(a *models.DeepAnalysisResult) ([]interface{}, error) {
+	sections := deepSections(a)
+	values := make([]interface{}, len(sections))
+	for i, section := range sections {
+		data, err := json.Marshal(section)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(data)
+	}
+	return values, nil
+}
+
+// scanDeepAnalysis reads a deep analysis row selected with deepAnalysisColumns
+func scanDeepAnalysis(row rowScanner) (*models.DeepAnalysisResult, error) {
+	var (
+		analysis       models.DeepAnalysisResult
+		id, analysisID string
+	)
+
+	sections := deepSections(&analysis)
+	raw := make([][]byte, len(sections))
+
+	dest := []interface{}{
+		&id,
+		&analysisID,
+		&analysis.URL,
+		&analysis.CreatedAt,
+		&analysis.Version,
+		&analysis.AnalyzerVersion,
+		&analysis.RulesVersion,
+	}
+	for i := range raw {
+		dest = append(dest, &raw[i])
+	}
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if analysis.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+	if analysis.AnalysisID, err = primitive.ObjectIDFromHex(analysisID); err != nil {
+		return nil, err
+	}
+
+	for i, section := range sections {
+		if err := json.Unmarshal(raw[i], section); err != nil {
+			return nil, err
+		}
+	}
+
+	return &analysis, nil
+}
+
+// nullString maps an empty string to SQL NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullTime converts an optional time to a nullable UTC column value
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
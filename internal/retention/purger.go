@@ -0,0 +1,85 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+	"webPageAnalyzerGO/internal/repository"
+)
+
+// Purger periodically deletes data older than the configured retention
+type Purger struct {
+	repo   repository.Repository
+	config config.RetentionConfig
+	logger *slog.Logger
+}
+
+// NewPurger creates a new retention purger
+func NewPurger(repo repository.Repository, cfg config.RetentionConfig, logger *slog.Logger) *Purger {
+	return &Purger{
+		repo:   repo,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Enabled reports whether any retention limit is configured
+func (p *Purger) Enabled() bool {
+	return p.config.AnalysesMaxAge > 0 || p.config.DeepAnalysesMaxAge > 0
+}
+
+// Run purges expired data immediately and then on every purge interval
+// until the context is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	interval := p.config.PurgeInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.PurgeOnce(ctx); err != nil {
+			p.logger.Error("Failed to purge expired data", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce deletes analyses and deep analyses older than their retention
+func (p *Purger) PurgeOnce(ctx context.Context) error {
+	now := time.Now()
+
+	if p.config.AnalysesMaxAge > 0 {
+		deleted, err := p.repo.DeleteAnalyses(ctx, models.AnalysisFilter{
+			To: now.Add(-p.config.AnalysesMaxAge),
+		})
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			p.logger.Info("Purged expired analyses", "count", deleted)
+		}
+	}
+
+	if p.config.DeepAnalysesMaxAge > 0 {
+		deleted, err := p.repo.DeleteDeepAnalysesBefore(ctx, now.Add(-p.config.DeepAnalysesMaxAge))
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			p.logger.Info("Purged expired deep analyses", "count", deleted)
+		}
+	}
+
+	return nil
+}
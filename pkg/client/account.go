@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// DeleteUserData deletes all of the caller's personal analyses and returns
+// how many were deleted. Analyses filed under a project are kept.
+func (c *Client) DeleteUserData(ctx context.Context) (int64, error) {
+	var resp DeleteResponse
+	if err := c.do(ctx, http.MethodDelete, "/user/data", nil, nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+// Quota returns the caller's quota plan and usage
+func (c *Client) Quota(ctx context.Context) (*QuotaStatus, error) {
+	var status QuotaStatus
+	if err := c.get(ctx, "/user/quota", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CreateAPIKey creates a personal API key. The plaintext key is only
+// returned here. Managing keys requires a bearer token.
+func (c *Client) CreateAPIKey(ctx context.Context, req APIKeyRequest) (*CreatedAPIKey, error) {
+	var key CreatedAPIKey
+	if err := c.do(ctx, http.MethodPost, "/user/api-keys", nil, req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys lists the caller's personal API keys
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	var resp APIKeyListResponse
+	if err := c.get(ctx, "/user/api-keys", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.APIKeys, nil
+}
+
+// RevokeAPIKey revokes a personal API key
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/user/api-keys/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// CreateOrganization creates an organization owned by the caller
+func (c *Client) CreateOrganization(ctx context.Context, name string) (*UserOrganization, error) {
+	var org UserOrganization
+	req := OrganizationRequest{Name: name}
+	if err := c.do(ctx, http.MethodPost, "/orgs", nil, req, &org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// ListOrganizations lists the organizations the caller belongs to
+func (c *Client) ListOrganizations(ctx context.Context) ([]*UserOrganization, error) {
+	var resp OrganizationListResponse
+	if err := c.get(ctx, "/orgs", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Organizations, nil
+}
+
+// ListMembers lists the members of an organization
+func (c *Client) ListMembers(ctx context.Context, orgID string) ([]*Membership, error) {
+	var resp MemberListResponse
+	if err := c.get(ctx, "/orgs/"+url.PathEscape(orgID)+"/members", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Members, nil
+}
+
+// SetMember adds a user to an organization or changes their role
+func (c *Client) SetMember(ctx context.Context, orgID, userID, role string) (*Membership, error) {
+	var member Membership
+	path := "/orgs/" + url.PathEscape(orgID) + "/members/" + url.PathEscape(userID)
+	if err := c.do(ctx, http.MethodPut, path, nil, MembershipRequest{Role: role}, &member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveMember removes a user from an organization
+func (c *Client) RemoveMember(ctx context.Context, orgID, userID string) error {
+	path := "/orgs/" + url.PathEscape(orgID) + "/members/" + url.PathEscape(userID)
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+// CreateProject creates a project in an organization
+func (c *Client) CreateProject(ctx context.Context, orgID, name string) (*Project, error) {
+	var project Project
+	req := ProjectRequest{Name: name}
+	if err := c.do(ctx, http.MethodPost, "/orgs/"+url.PathEscape(orgID)+"/projects", nil, req, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListProjects lists the projects of an organization
+func (c *Client) ListProjects(ctx context.Context, orgID string) ([]*Project, error) {
+	var resp ProjectListResponse
+	if err := c.get(ctx, "/orgs/"+url.PathEscape(orgID)+"/projects", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Projects, nil
+}
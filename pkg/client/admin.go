@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Health checks that the API is up. It is not retried.
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/health", nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAPIError(resp)
+	}
+	defer resp.Body.Close()
+
+	var health HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("decoding health response: %w", err)
+	}
+	return &health, nil
+}
+
+// Stats returns application statistics. Admins only.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	if err := c.get(ctx, "/admin/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// BulkDeleteAnalyses deletes the analyses matching the filters of opts and
+// returns how many were deleted; paging options are ignored. Without any
+// filter all must be set to delete every analysis. Admins only.
+func (c *Client) BulkDeleteAnalyses(ctx context.Context, opts ListOptions, all bool) (int64, error) {
+	opts.Limit, opts.Cursor, opts.Sort = 0, "", ""
+	v := opts.values()
+	if all {
+		v.Set("all", "true")
+	}
+
+	var resp DeleteResponse
+	if err := c.do(ctx, http.MethodDelete, "/admin/analyses", v, nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Deleted, nil
+}
+
+// SetUserPlan assigns a user's quota plan. Admins only.
+func (c *Client) SetUserPlan(ctx context.Context, userID, plan string) error {
+	return c.do(ctx, http.MethodPut, "/admin/users/"+url.PathEscape(userID)+"/plan", nil, PlanRequest{Plan: plan}, nil)
+}
+
+// AuditOptions selects a page of the audit log. Zero values are omitted.
+type AuditOptions struct {
+	Limit      int
+	Cursor     string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Outcome    string
+	From       time.Time
+	To         time.Time
+}
+
+// values encodes the options as query parameters
+func (o AuditOptions) values() url.Values {
+	v := url.Values{}
+	setInt(v, "limit", o.Limit)
+	setString(v, "cursor", o.Cursor)
+	setString(v, "actor_id", o.ActorID)
+	setString(v, "action", o.Action)
+	setString(v, "target_type", o.TargetType)
+	setString(v, "target_id", o.TargetID)
+	setString(v, "outcome", o.Outcome)
+	setTime(v, "from", o.From)
+	setTime(v, "to", o.To)
+	return v
+}
+
+// ListAuditEvents returns a page of the audit log, newest events first.
+// Admins only.
+func (c *Client) ListAuditEvents(ctx context.Context, opts AuditOptions) (*AuditEventListResponse, error) {
+	var resp AuditEventListResponse
+	if err := c.get(ctx, "/admin/audit", opts.values(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AuditEvents iterates over all audit events matching opts, fetching
+// further pages as needed. Iteration stops at the first error, which is
+// yielded. Admins only.
+func (c *Client) AuditEvents(ctx context.Context, opts AuditOptions) iter.Seq2[*AuditEvent, error] {
+	return func(yield func(*AuditEvent, error) bool) {
+		for {
+			page, err := c.ListAuditEvents(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, e := range page.Events {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+}
+
+// ExportAuditEvents streams every audit event matching opts as "csv" or
+// "jsonl"; paging options are ignored. The caller closes the returned
+// reader. Admins only.
+func (c *Client) ExportAuditEvents(ctx context.Context, format string, opts AuditOptions) (io.ReadCloser, error) {
+	opts.Limit, opts.Cursor = 0, ""
+	v := opts.values()
+	setString(v, "format", format)
+
+	resp, err := c.send(ctx, http.MethodGet, "/admin/audit/export", v, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ListOptions selects a page of analyses. Zero values are omitted.
+type ListOptions struct {
+	Limit          int
+	Cursor         string
+	Sort           string // e.g. "-created_at", "url" or "title"
+	Domain         string
+	URLPrefix      string
+	HTMLVersion    string
+	From           time.Time
+	To             time.Time
+	HasLoginForm   *bool
+	HasBrokenLinks *bool
+	ProjectID      string // list a project's analyses instead of personal ones
+	UserID         string // admins only: list another user's analyses
+}
+
+// values encodes the options as query parameters
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	setString(v, "cursor", o.Cursor)
+	setString(v, "sort", o.Sort)
+	setString(v, "domain", o.Domain)
+	setString(v, "url_prefix", o.URLPrefix)
+	setString(v, "html_version", o.HTMLVersion)
+	setString(v, "project_id", o.ProjectID)
+	setString(v, "user_id", o.UserID)
+	setInt(v, "limit", o.Limit)
+	setTime(v, "from", o.From)
+	setTime(v, "to", o.To)
+	setBool(v, "has_login_form", o.HasLoginForm)
+	setBool(v, "has_broken_links", o.HasBrokenLinks)
+	return v
+}
+
+// Analyze analyzes a web page
+func (c *Client) Analyze(ctx context.Context, req AnalysisRequest) (*AnalysisResult, error) {
+	var result AnalysisResult
+	if err := c.do(ctx, http.MethodPost, "/analyze", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BatchResult is the outcome of analyzing one URL of a batch
+type BatchResult struct {
+	URL    string
+	Result *AnalysisResult
+	Err    error
+}
+
+// AnalyzeBatch analyzes several pages with at most concurrency requests in
+// flight and returns the outcomes in the order of urls. A failed URL does
+// not stop the others.
+func (c *Client) AnalyzeBatch(ctx context.Context, urls []string, projectID string, concurrency int) []BatchResult {
+	results := make([]BatchResult, len(urls))
+	sem := make(chan struct{}, max(concurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := c.Analyze(ctx, AnalysisRequest{URL: u, ProjectID: projectID})
+			results[i] = BatchResult{URL: u, Result: result, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// GetAnalysis retrieves an analysis
+func (c *Client) GetAnalysis(ctx context.Context, id string) (*AnalysisResult, error) {
+	var result AnalysisResult
+	if err := c.get(ctx, "/analysis/"+url.PathEscape(id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteAnalysis deletes an analysis together with its deep analyses
+func (c *Client) DeleteAnalysis(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/analysis/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DeepAnalysisOptions controls whether a stored deep analysis is reused
+type DeepAnalysisOptions struct {
+	MaxAge time.Duration // reuse a stored deep analysis at most this old
+	Force  bool          // always run a new deep analysis
+}
+
+// DeepAnalysis returns the deep analysis of an analysis, running a new one
+// if none is stored or it is too old
+func (c *Client) DeepAnalysis(ctx context.Context, id string, opts DeepAnalysisOptions) (*DeepAnalysisResult, error) {
+	v := url.Values{}
+	if opts.MaxAge > 0 {
+		v.Set("max_age", opts.MaxAge.String())
+	}
+	if opts.Force {
+		v.Set("force", "true")
+	}
+
+	var result DeepAnalysisResult
+	if err := c.get(ctx, "/analysis/"+url.PathEscape(id)+"/deep", v, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListDeepAnalysisVersions lists the stored deep analysis versions of an analysis
+func (c *Client) ListDeepAnalysisVersions(ctx context.Context, id string) ([]*DeepAnalysisVersion, error) {
+	var resp DeepAnalysisVersionsResponse
+	if err := c.get(ctx, "/analysis/"+url.PathEscape(id)+"/deep/versions", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Versions, nil
+}
+
+// GetDeepAnalysisVersion retrieves one stored deep analysis version
+func (c *Client) GetDeepAnalysisVersion(ctx context.Context, id string, version int) (*DeepAnalysisResult, error) {
+	var result DeepAnalysisResult
+	path := "/analysis/" + url.PathEscape(id) + "/deep/versions/" + strconv.Itoa(version)
+	if err := c.get(ctx, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListAnalyses returns a page of the caller's personal analyses, or of a
+// project's analyses if opts.ProjectID is set
+func (c *Client) ListAnalyses(ctx context.Context, opts ListOptions) (*AnalysisListResponse, error) {
+	var resp AnalysisListResponse
+	if err := c.get(ctx, "/analyses", opts.values(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListUserAnalyses returns a page of the caller's personal analyses
+func (c *Client) ListUserAnalyses(ctx context.Context, opts ListOptions) (*AnalysisListResponse, error) {
+	var resp AnalysisListResponse
+	if err := c.get(ctx, "/user/analyses", opts.values(), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Analyses iterates over all analyses matching opts, fetching further
+// pages as needed. Iteration stops at the first error, which is yielded.
+func (c *Client) Analyses(ctx context.Context, opts ListOptions) iter.Seq2[*AnalysisResult, error] {
+	return paginate(ctx, opts, c.ListAnalyses)
+}
+
+// UserAnalyses iterates over all of the caller's personal analyses
+func (c *Client) UserAnalyses(ctx context.Context, opts ListOptions) iter.Seq2[*AnalysisResult, error] {
+	return paginate(ctx, opts, c.ListUserAnalyses)
+}
+
+// paginate iterates over the analyses of all pages returned by list
+func paginate(ctx context.Context, opts ListOptions, list func(context.Context, ListOptions) (*AnalysisListResponse, error)) iter.Seq2[*AnalysisResult, error] {
+	return func(yield func(*AnalysisResult, error) bool) {
+		for {
+			page, err := list(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, a := range page.Analyses {
+				if !yield(a, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+}
+
+// SearchOptions describes a full-text search
+type SearchOptions struct {
+	Query     string
+	Limit     int
+	ProjectID string
+	UserID    string // admins only
+}
+
+// Search searches the analyzed pages
+func (c *Client) Search(ctx context.Context, opts SearchOptions) (*SearchResponse, error) {
+	v := url.Values{"q": {opts.Query}}
+	setInt(v, "limit", opts.Limit)
+	setString(v, "project_id", opts.ProjectID)
+	setString(v, "user_id", opts.UserID)
+
+	var resp SearchResponse
+	if err := c.get(ctx, "/search", v, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// setString sets a query parameter unless value is empty
+func setString(v url.Values, name, value string) {
+	if value != "" {
+		v.Set(name, value)
+	}
+}
+
+// setInt sets a query parameter unless value is zero
+func setInt(v url.Values, name string, value int) {
+	if value != 0 {
+		v.Set(name, strconv.Itoa(value))
+	}
+}
+
+// setTime sets a query parameter unless value is zero
+func setTime(v url.Values, name string, value time.Time) {
+	if !value.IsZero() {
+		v.Set(name, value.Format(time.RFC3339))
+	}
+}
+
+// setBool sets a query parameter unless value is nil
+func setBool(v url.Values, name string, value *bool) {
+	if value != nil {
+		v.Set(name, strconv.FormatBool(*value))
+	}
+}
@@ -0,0 +1,258 @@
+// Package client is a Go client for the web page analyzer API.
+//
+// A Client authenticates with either a personal API key or a bearer token,
+// retries rate-limited and transiently failing requests, and returns API
+// failures as *APIError values carrying the stable error code.
+//
+// The request and response types are aliases of the server's own models
+// rather than separate wire types, so the client always matches the server
+// it is built with. The flip side is that any change to those models changes
+// this package's API; the client is versioned together with the server and
+// only speaks the API version it was built for.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default retry settings
+const (
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 30 * time.Second
+)
+
+// apiPrefix is the path of the API version the client speaks
+const apiPrefix = "/api/v1"
+
+// Client calls the analyzer API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	token      string
+	userAgent  string
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with a personal API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBearerToken authenticates requests with an access token
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries sets how often a request is retried and the initial delay
+// between attempts, which doubles with every retry. Zero retries disables
+// retrying.
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+// New creates a client for the API at baseURL, e.g. "https://analyzer.example.com"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		userAgent:  "webPageAnalyzerGO-client/1.0",
+		maxRetries: DefaultMaxRetries,
+		retryDelay: DefaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is an error response of the API
+type APIError struct {
+	StatusCode int
+	Code       string // stable error code, e.g. "upstream_status"; may be empty
+	Message    string
+	Detail     string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("analyzer API: %d %s", e.StatusCode, e.Message)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// ErrorCode returns the API error code of err, or "" if err is not an
+// *APIError
+func ErrorCode(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// get sends a GET request and decodes the JSON response into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// do sends a request with a JSON body, if any, and decodes the JSON
+// response into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request, retrying it while the API is rate limiting or
+// failing transiently, and returns the successful response. The caller
+// closes its body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encoding %s %s request: %w", method, path, err)
+		}
+	}
+
+	target := c.baseURL + apiPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		c.authorize(req)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		apiErr := readAPIError(resp)
+		if attempt >= c.maxRetries || !retryable(method, apiErr) {
+			return nil, apiErr
+		}
+
+		delay := c.retryDelay << attempt
+		delay += time.Duration(rand.Int64N(int64(delay)/2 + 1))
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			delay = time.Duration(seconds) * time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(delay, maxRetryDelay)):
+		}
+	}
+}
+
+// authorize adds the configured credentials to a request
+func (c *Client) authorize(req *http.Request) {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// retryable reports whether a failed request may succeed when retried.
+// Rate-limited requests were not processed and are always retried. Server
+// errors are retried for idempotent methods unless they carry an error
+// code: coded errors such as an unreachable analyzed site are not transient
+// failures of the API.
+func retryable(method string, apiErr *APIError) bool {
+	if apiErr.StatusCode == http.StatusTooManyRequests {
+		return apiErr.Code != CodeQuotaExceeded
+	}
+	if apiErr.StatusCode < http.StatusInternalServerError || apiErr.Code != "" {
+		return false
+	}
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+// readAPIError reads and closes an error response
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil {
+		apiErr.Code, apiErr.Detail = body.Code, body.Error
+		if body.Message != "" {
+			apiErr.Message = body.Message
+		}
+	}
+	return apiErr
+}
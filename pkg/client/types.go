@@ -0,0 +1,66 @@
+package client
+
+import "webPageAnalyzerGO/internal/models"
+
+// Request and response types of the API, shared with the server (see the
+// package documentation)
+type (
+	AnalysisRequest              = models.AnalysisRequest
+	AnalysisResult               = models.AnalysisResult
+	AnalysisListResponse         = models.AnalysisListResponse
+	DeepAnalysisResult           = models.DeepAnalysisResult
+	DeepAnalysisVersion          = models.DeepAnalysisVersion
+	DeepAnalysisVersionsResponse = models.DeepAnalysisVersionsResponse
+	SearchResponse               = models.SearchResponse
+	SearchHit                    = models.SearchHit
+	DeleteResponse               = models.DeleteResponse
+	QuotaStatus                  = models.QuotaStatus
+	APIKey                       = models.APIKey
+	APIKeyRequest                = models.APIKeyRequest
+	APIKeyListResponse           = models.APIKeyListResponse
+	CreatedAPIKey                = models.CreatedAPIKey
+	Organization                 = models.Organization
+	OrganizationRequest          = models.OrganizationRequest
+	OrganizationListResponse     = models.OrganizationListResponse
+	MembershipRequest            = models.MembershipRequest
+	MemberListResponse           = models.MemberListResponse
+	ProjectRequest               = models.ProjectRequest
+	ProjectListResponse          = models.ProjectListResponse
+	PlanRequest                  = models.PlanRequest
+	UserOrganization             = models.UserOrganization
+	Membership                   = models.Membership
+	Project                      = models.Project
+	AuditEvent                   = models.AuditEvent
+	AuditEventListResponse       = models.AuditEventListResponse
+	Stats                        = models.Stats
+	HealthResponse               = models.HealthResponse
+)
+
+// API key scopes
+const (
+	ScopeAnalyze = models.ScopeAnalyze
+	ScopeRead    = models.ScopeRead
+	ScopeAdmin   = models.ScopeAdmin
+)
+
+// Organization member roles
+const (
+	RoleViewer  = models.RoleViewer
+	RoleAnalyst = models.RoleAnalyst
+	RoleOwner   = models.RoleOwner
+)
+
+// Error codes reported in APIError.Code
+const (
+	CodeInvalidURL        = models.CodeInvalidURL
+	CodeDNSFailure        = models.CodeDNSFailure
+	CodeConnectionRefused = models.CodeConnectionRefused
+	CodeTLSError          = models.CodeTLSError
+	CodeTimeout           = models.CodeTimeout
+	CodeNotHTML           = models.CodeNotHTML
+	CodeTooLarge          = models.CodeTooLarge
+	CodeUpstreamStatus    = models.CodeUpstreamStatus
+	CodeFetchFailed       = models.CodeFetchFailed
+	CodeRateLimited       = models.CodeRateLimited
+	CodeQuotaExceeded     = models.CodeQuotaExceeded
+)
@@ -2,6 +2,8 @@ package analyzer_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -99,7 +101,16 @@ func getTestAnalyzer() *analyzer.Analyzer {
 		RequestTimeout: 5 * time.Second,
 		UserAgent:      "WebPageAnalyzer-Test/1.0",
 	}
-	return analyzer.New(cfg, logger)
+	return newTestAnalyzer(cfg, logger)
+}
+
+// newTestAnalyzer creates an analyzer, panicking on an invalid configuration
+func newTestAnalyzer(cfg config.AnalyzerConfig, logger *slog.Logger) *analyzer.Analyzer {
+	a, err := analyzer.New(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+	return a
 }
 
 // TestAnalyzeURL tests basic URL analysis functionality
@@ -223,7 +234,7 @@ func TestErrorHandling(t *testing.T) {
 			RequestTimeout: 1 * time.Millisecond, // Extremely short timeout
 			UserAgent:      "WebPageAnalyzer-Test/1.0",
 		}
-		timeoutAnalyzer := analyzer.New(cfg, logger)
+		timeoutAnalyzer := newTestAnalyzer(cfg, logger)
 
 		// Create a test server that delays response
 		slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -480,3 +491,151 @@ func mockAnalysisResult() *models.AnalysisResult {
 		CreatedAt: time.Now(),
 	}
 }
+
+// TestSearchableTextExtraction tests the text stored for full-text search
+func TestSearchableTextExtraction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head>
+			<title>Search Page</title>
+			<meta name="description" content=" Old product name everywhere ">
+			<script>var hidden = "not indexed";</script>
+		</head><body>
+			<h1>Welcome</h1>
+			<p>Visible   text
+			content</p>
+			<a href="#top">Back <b>to top</b></a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	result, err := a.AnalyzeURL(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Description != "Old product name everywhere" {
+		t.Errorf("Expected meta description, got %q", result.Description)
+	}
+	if result.AnchorText != "Back to top" {
+		t.Errorf("Expected anchor text 'Back to top', got %q", result.AnchorText)
+	}
+	if result.TextContent != "Welcome Visible text content Back to top" {
+		t.Errorf("Unexpected text content %q", result.TextContent)
+	}
+}
+
+// TestFetchErrors tests that fetch failures wrap the matching sentinel error
+func TestFetchErrors(t *testing.T) {
+	a := getTestAnalyzer()
+	ctx := context.Background()
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"page": false}`))
+		case "/large":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>" + strings.Repeat("x", 4096) + "</body></html>"))
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>ok</body></html>"))
+		}
+	}))
+	defer page.Close()
+
+	tlsPage := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsPage.Close()
+
+	// A port that was just released refuses connections
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	limited := newTestAnalyzer(config.AnalyzerConfig{
+		RequestTimeout: 50 * time.Millisecond,
+		UserAgent:      "WebPageAnalyzer-Test/1.0",
+		MaxPageSize:    1024,
+	}, logger)
+
+	tests := []struct {
+		name     string
+		analyzer *analyzer.Analyzer
+		url      string
+		want     error
+	}{
+		{"InvalidURL", a, "ftp://example.com/file", analyzer.ErrInvalidURL},
+		{"DNS", a, "http://does-not-exist.invalid", analyzer.ErrDNS},
+		{"ConnectionRefused", a, closedURL, analyzer.ErrConnectionRefused},
+		{"TLS", a, tlsPage.URL, analyzer.ErrTLS},
+		{"Timeout", limited, slow.URL, analyzer.ErrTimeout},
+		{"NotHTML", a, page.URL + "/json", analyzer.ErrNotHTML},
+		{"TooLarge", limited, page.URL + "/large", analyzer.ErrTooLarge},
+		{"UpstreamStatus", a, page.URL + "/missing", analyzer.ErrUpstreamStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.analyzer.AnalyzeURL(ctx, tt.url)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("StatusCode", func(t *testing.T) {
+		_, err := a.FetchPage(ctx, page.URL+"/missing")
+		var statusErr *analyzer.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected a 404 status error, got %v", err)
+		}
+	})
+}
+
+// TestAnalyzeErrorCodes tests that the API reports analyzer failures with a
+// matching status and error code
+func TestAnalyzeErrorCodes(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	handler := newTestAPIServer(t, idp).Handler()
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image.png" {
+			w.Header().Set("Content-Type", "image/png")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer page.Close()
+
+	tests := []struct {
+		url    string
+		status int
+		code   string
+	}{
+		{page.URL + "/missing", http.StatusBadGateway, models.CodeUpstreamStatus},
+		{page.URL + "/image.png", http.StatusUnprocessableEntity, models.CodeNotHTML},
+		{"ftp://example.com/file", http.StatusBadRequest, models.CodeInvalidURL},
+	}
+
+	for _, tt := range tests {
+		w := apiRequest(t, handler, http.MethodPost, "/api/v1/analyze", models.AnalysisRequest{URL: tt.url}, nil)
+		var resp models.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if w.Code != tt.status || resp.StatusCode != tt.status || resp.Code != tt.code {
+			t.Errorf("Expected %d %s for %s, got %d: %s", tt.status, tt.code, tt.url, w.Code, w.Body.String())
+		}
+	}
+}
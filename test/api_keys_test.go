@@ -0,0 +1,318 @@
+package analyzer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/api"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/middleware"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// newTestAPIServer creates the API server backed by SQLite and a stub provider
+func newTestAPIServer(t *testing.T, idp *stubIdentityProvider) *api.Server {
+	t.Helper()
+	return newConfiguredTestAPIServer(t, idp, nil)
+}
+
+// newConfiguredTestAPIServer is like newTestAPIServer but lets the test
+// adjust the configuration first
+func newConfiguredTestAPIServer(t *testing.T, idp *stubIdentityProvider, configure func(cfg *config.Config)) *api.Server {
+	t.Helper()
+
+	cfg := &config.Config{
+		Auth: config.AuthConfig{Provider: "keycloak"},
+		Keycloak: config.KeycloakConfig{
+			URL:   idp.server.URL,
+			Realm: "test",
+		},
+		Analyzer: config.AnalyzerConfig{
+			RequestTimeout:     5 * time.Second,
+			UserAgent:          "WebAnalyzer-Test/1.0",
+			DeepAnalysisMaxAge: time.Hour,
+		},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	gin.SetMode(gin.TestMode)
+	repo := newTestSQLRepository(t)
+	server, err := api.NewServer(cfg, repo, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return server
+}
+
+// apiRequest performs a JSON request against the API router
+func apiRequest(t *testing.T, handler http.Handler, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// TestAPIKeys tests creating, using and revoking API keys
+func TestAPIKeys(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newTestAPIServer(t, idp)
+	handler := server.Handler()
+
+	bearer := func(roles ...string) map[string]string {
+		token := signingKey.sign(t, map[string]interface{}{
+			"sub":                "user-1",
+			"preferred_username": "ci-bot",
+			"email":              "ci@example.com",
+			"iss":                idp.issuer,
+			"exp":                time.Now().Add(5 * time.Minute).Unix(),
+			"realm_access":       map[string]interface{}{"roles": roles},
+		})
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	createKey := func(headers map[string]string, scopes ...string) *httptest.ResponseRecorder {
+		return apiRequest(t, handler, http.MethodPost, "/api/user/api-keys",
+			models.APIKeyRequest{Name: "ci", Scopes: scopes}, headers)
+	}
+
+	w := createKey(bearer("user"), models.ScopeRead)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating key, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID     string   `json:"id"`
+		Key    string   `json:"key"`
+		Prefix string   `json:"prefix"`
+		Scopes []string `json:"scopes"`
+		Hash   string   `json:"hash"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Key == "" || created.Hash != "" || created.Prefix != created.Key[:len(created.Prefix)] {
+		t.Fatalf("Unexpected created key: %+v", created)
+	}
+	readKey := map[string]string{middleware.APIKeyHeader: created.Key}
+
+	t.Run("ReadScope", func(t *testing.T) {
+		if w := apiRequest(t, handler, http.MethodGet, "/api/user/analyses", nil, readKey); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with read key, got %d: %s", w.Code, w.Body.String())
+		}
+		bearerKey := map[string]string{"Authorization": "Bearer " + created.Key}
+		if w := apiRequest(t, handler, http.MethodGet, "/api/search?q=test", nil, bearerKey); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with key as bearer token, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("MissingScope", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodPost, "/api/analyze", models.AnalysisRequest{URL: "https://example.com"}, readKey)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 analyzing with read key, got %d", w.Code)
+		}
+		if w := apiRequest(t, handler, http.MethodGet, "/api/admin/stats", nil, readKey); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 on admin route, got %d", w.Code)
+		}
+	})
+
+	t.Run("AdminScopeRequiresAdmin", func(t *testing.T) {
+		if w := createKey(bearer("user"), models.ScopeAdmin); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for admin scope as non-admin, got %d", w.Code)
+		}
+		if w := createKey(bearer("user"), "delete-everything"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for unknown scope, got %d", w.Code)
+		}
+	})
+
+	t.Run("AdminKeyDropsRoleWithoutScope", func(t *testing.T) {
+		w := createKey(bearer("admin"), models.ScopeRead)
+		var key struct {
+			Key string `json:"key"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &key)
+		headers := map[string]string{middleware.APIKeyHeader: key.Key}
+		if w := apiRequest(t, handler, http.MethodGet, "/api/admin/stats", nil, headers); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for admin's read-only key, got %d", w.Code)
+		}
+	})
+
+	t.Run("AdminKeyLifetime", func(t *testing.T) {
+		tooLate := time.Now().Add(models.MaxAdminAPIKeyLifetime + time.Hour)
+		w := apiRequest(t, handler, http.MethodPost, "/api/user/api-keys",
+			models.APIKeyRequest{Name: "ops", Scopes: []string{models.ScopeAdmin}, ExpiresAt: &tooLate}, bearer("admin"))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a long-lived admin key, got %d", w.Code)
+		}
+	})
+
+	t.Run("AdminKeyRevokedWithRole", func(t *testing.T) {
+		// Another user, so that earlier requests have not been checked yet
+		as := func(roles ...string) map[string]string {
+			token := signingKey.sign(t, map[string]interface{}{
+				"sub":          "user-2",
+				"iss":          idp.issuer,
+				"exp":          time.Now().Add(5 * time.Minute).Unix(),
+				"realm_access": map[string]interface{}{"roles": roles},
+			})
+			return map[string]string{"Authorization": "Bearer " + token}
+		}
+
+		w := createKey(as("admin"), models.ScopeAdmin)
+		var key struct {
+			Key       string     `json:"key"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &key)
+		if w.Code != http.StatusCreated || key.ExpiresAt == nil || key.ExpiresAt.After(time.Now().Add(models.MaxAdminAPIKeyLifetime)) {
+			t.Fatalf("Expected an admin key expiring within the maximum lifetime, got %d: %s", w.Code, w.Body.String())
+		}
+		headers := map[string]string{middleware.APIKeyHeader: key.Key}
+		if w := apiRequest(t, handler, http.MethodGet, "/api/admin/stats", nil, headers); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 with the admin key, got %d", w.Code)
+		}
+
+		// The identity provider no longer grants the admin role
+		apiRequest(t, handler, http.MethodGet, "/api/user/analyses", nil, as("user"))
+		if w := apiRequest(t, handler, http.MethodGet, "/api/admin/stats", nil, headers); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with the revoked admin key, got %d", w.Code)
+		}
+	})
+
+	t.Run("CORS", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/api/user/analyses", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", middleware.APIKeyHeader)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if !strings.Contains(strings.ToLower(w.Header().Get("Access-Control-Allow-Headers")), "x-api-key") {
+			t.Errorf("Expected browsers to be allowed to send %s, got %v", middleware.APIKeyHeader, w.Header())
+		}
+	})
+
+	t.Run("KeysCannotManageKeys", func(t *testing.T) {
+		if w := createKey(readKey, models.ScopeRead); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 creating a key with a key, got %d", w.Code)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodGet, "/api/user/api-keys", nil, bearer("user"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 listing keys, got %d", w.Code)
+		}
+		var list struct {
+			Count   int                      `json:"count"`
+			APIKeys []map[string]interface{} `json:"api_keys"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &list)
+		if list.Count != 2 {
+			t.Fatalf("Expected 2 keys for user-1, got %d", list.Count)
+		}
+		if _, ok := list.APIKeys[0]["key"]; ok {
+			t.Error("Expected plaintext key to be omitted from listing")
+		}
+		if list.APIKeys[1]["id"] != created.ID || list.APIKeys[1]["last_used_at"] == nil {
+			t.Error("Expected last_used_at to be recorded")
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodDelete, "/api/user/api-keys/"+created.ID, nil, bearer("user"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 revoking key, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := apiRequest(t, handler, http.MethodGet, "/api/user/analyses", nil, readKey); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with revoked key, got %d", w.Code)
+		}
+		if w := apiRequest(t, handler, http.MethodDelete, "/api/user/api-keys/"+created.ID, nil, bearer("user")); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 revoking twice, got %d", w.Code)
+		}
+		if w := apiRequest(t, handler, http.MethodDelete, "/api/v1/user/api-keys/not-an-id", nil, bearer("user")); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 revoking a malformed ID, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("DeleteUserData", func(t *testing.T) {
+		w := createKey(bearer("user"), models.ScopeRead)
+		var key struct {
+			Key string `json:"key"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &key)
+
+		if w := apiRequest(t, handler, http.MethodDelete, "/api/user/data", nil, bearer("user")); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 deleting user data, got %d: %s", w.Code, w.Body.String())
+		}
+		w = apiRequest(t, handler, http.MethodGet, "/api/user/api-keys", nil, bearer("user"))
+		var list struct {
+			Count int `json:"count"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &list)
+		if list.Count != 0 {
+			t.Errorf("Expected the user's API keys to be deleted, got %d", list.Count)
+		}
+		headers := map[string]string{middleware.APIKeyHeader: key.Key}
+		if w := apiRequest(t, handler, http.MethodGet, "/api/user/analyses", nil, headers); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with a deleted key, got %d", w.Code)
+		}
+	})
+}
+
+// TestExpiredAPIKey tests that expired API keys are rejected
+func TestExpiredAPIKey(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx := context.Background()
+
+	key, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	expired := time.Now().Add(-time.Minute)
+	if err := repo.SaveAPIKey(ctx, &models.APIKey{
+		UserID:    "user-1",
+		Name:      "old",
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    []string{models.ScopeRead},
+		ExpiresAt: &expired,
+	}); err != nil {
+		t.Fatalf("Failed to save key: %v", err)
+	}
+
+	auth := newKeycloakTestAuth(newStubIdentityProvider(t), "")
+	auth.UseAPIKeys(repo)
+	router := newAuthTestRouter(auth)
+
+	if w := doAuthRequest(router, "/me", key); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for expired key, got %d", w.Code)
+	}
+}
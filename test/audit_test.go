@@ -0,0 +1,195 @@
+package analyzer_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestAuditLog tests that security-relevant requests are recorded and can be
+// queried and exported by admins
+func TestAuditLog(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newTestAPIServer(t, idp)
+	handler := server.Handler()
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Audited</title></head><body><h1>Audited</h1></body></html>`))
+	}))
+	defer page.Close()
+
+	as := func(userID string, roles ...string) map[string]string {
+		token := signingKey.sign(t, map[string]interface{}{
+			"sub":          userID,
+			"iss":          idp.issuer,
+			"exp":          time.Now().Add(5 * time.Minute).Unix(),
+			"realm_access": map[string]interface{}{"roles": roles},
+		})
+		// Spoofed forwarding headers must not change the recorded IP
+		return map[string]string{"Authorization": "Bearer " + token, "User-Agent": "audit-test", "X-Forwarded-For": "6.6.6.6"}
+	}
+	alice, bob, admin := as("alice", "user"), as("bob", "user"), as("root", "admin")
+
+	w := apiRequest(t, handler, http.MethodPost, "/api/analyze", models.AnalysisRequest{URL: page.URL}, alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 analyzing, got %d: %s", w.Code, w.Body.String())
+	}
+	var analysis models.AnalysisResult
+	if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	analysisID := analysis.ID.Hex()
+
+	apiRequest(t, handler, http.MethodGet, "/api/analysis/"+analysisID, nil, alice)
+	apiRequest(t, handler, http.MethodGet, "/api/analysis/"+analysisID, nil, bob)
+	apiRequest(t, handler, http.MethodGet, "/api/admin/stats", nil, bob)
+	// Listings are audited with their filter; the user agent is a formula
+	// that exports must not let spreadsheets evaluate
+	listing := map[string]string{"User-Agent": "=HYPERLINK(\"http://evil.test\")"}
+	for k, v := range alice {
+		if k != "User-Agent" {
+			listing[k] = v
+		}
+	}
+	apiRequest(t, handler, http.MethodGet, "/api/user/analyses?limit=5", nil, listing)
+
+	query := func(params string) []models.AuditEvent {
+		t.Helper()
+		w := apiRequest(t, handler, http.MethodGet, "/api/admin/audit?"+params, nil, admin)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 querying audit log, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Events     []models.AuditEvent `json:"events"`
+			NextCursor string              `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Events
+	}
+
+	t.Run("Actor", func(t *testing.T) {
+		events := query("actor_id=alice")
+		if len(events) != 3 {
+			t.Fatalf("Expected 3 events for alice, got %d: %+v", len(events), events)
+		}
+		// Newest first
+		list, view, analyze := events[0], events[1], events[2]
+		if list.Action != models.AuditList || list.TargetType != "analysis" || list.Detail != "limit=5" {
+			t.Errorf("Unexpected list event: %+v", list)
+		}
+		if analyze.Action != models.AuditAnalyze || analyze.TargetID != analysisID || analyze.Detail != page.URL {
+			t.Errorf("Unexpected analyze event: %+v", analyze)
+		}
+		if view.Action != models.AuditView || view.TargetType != "analysis" || view.TargetID != analysisID {
+			t.Errorf("Unexpected view event: %+v", view)
+		}
+		if view.Outcome != models.AuditSuccess || view.Status != http.StatusOK || view.UserAgent != "audit-test" || view.IP != "192.0.2.1" {
+			t.Errorf("Unexpected request details: %+v", view)
+		}
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		events := query("outcome=denied")
+		if len(events) != 2 {
+			t.Fatalf("Expected 2 denied events, got %d: %+v", len(events), events)
+		}
+		if events[0].Action != models.AuditAdminAccess || events[0].ActorID != "bob" || events[0].Status != http.StatusForbidden {
+			t.Errorf("Unexpected admin access event: %+v", events[0])
+		}
+		if events[1].Action != models.AuditView || events[1].ActorID != "bob" {
+			t.Errorf("Unexpected denied view event: %+v", events[1])
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodGet, "/api/admin/audit?action=view&limit=1", nil, admin)
+		var first struct {
+			Events     []models.AuditEvent `json:"events"`
+			NextCursor string              `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &first); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(first.Events) != 1 || first.NextCursor == "" {
+			t.Fatalf("Expected one event and a cursor, got %s", w.Body.String())
+		}
+
+		rest := query("action=view&cursor=" + first.NextCursor)
+		if len(rest) != 1 || rest[0].ID == first.Events[0].ID {
+			t.Errorf("Expected the other view event on the next page, got %+v", rest)
+		}
+	})
+
+	t.Run("ExportCSV", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodGet, "/api/admin/audit/export?actor_id=alice", nil, admin)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment") {
+			t.Fatalf("Expected a CSV attachment, got %d %v", w.Code, w.Header())
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to parse CSV: %v", err)
+		}
+		if len(records) != 4 || records[0][0] != "id" || records[1][4] != models.AuditList || records[2][4] != models.AuditView {
+			t.Fatalf("Unexpected CSV export: %v", records)
+		}
+		if ua := records[1][13]; ua != `'=HYPERLINK("http://evil.test")` {
+			t.Errorf("Expected the formula to be neutralised, got %q", ua)
+		}
+	})
+
+	t.Run("ExportJSONLines", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodGet, "/api/admin/audit/export?format=jsonl&action=admin_access", nil, admin)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		lines := 0
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var e models.AuditEvent
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatalf("Failed to decode line %q: %v", scanner.Text(), err)
+			}
+			if e.Action != models.AuditAdminAccess {
+				t.Errorf("Unexpected action %q", e.Action)
+			}
+			lines++
+		}
+		// Bob's attempt plus the admin's own audit queries
+		if lines < 2 {
+			t.Errorf("Expected at least 2 admin access events, got %d", lines)
+		}
+	})
+
+	t.Run("NonAdmin", func(t *testing.T) {
+		if w := apiRequest(t, handler, http.MethodGet, "/api/admin/audit", nil, alice); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for non-admin, got %d", w.Code)
+		}
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		if w := apiRequest(t, handler, http.MethodGet, "/api/v1/analysis/"+analysisID, nil, nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 without credentials, got %d", w.Code)
+		}
+
+		// Rejected before authentication completes, so without an actor
+		events := query("target_id=" + analysisID + "&outcome=denied")
+		if len(events) != 2 {
+			t.Fatalf("Expected 2 denied events on the analysis, got %d: %+v", len(events), events)
+		}
+		if e := events[0]; e.Action != models.AuditView || e.ActorID != "" || e.Status != http.StatusUnauthorized ||
+			e.Path != "/api/v1/analysis/"+analysisID {
+			t.Errorf("Unexpected unauthenticated view event: %+v", e)
+		}
+	})
+}
@@ -0,0 +1,476 @@
+package analyzer_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/middleware"
+)
+
+// testSigningKey is a key the stub identity provider signs tokens with
+type testSigningKey struct {
+	kid string
+	alg string
+	key crypto.Signer
+}
+
+// newRSASigningKey generates an RS256 signing key
+func newRSASigningKey(t *testing.T, kid string) *testSigningKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return &testSigningKey{kid: kid, alg: "RS256", key: key}
+}
+
+// newECSigningKey generates an ES256 signing key
+func newECSigningKey(t *testing.T, kid string) *testSigningKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	return &testSigningKey{kid: kid, alg: "ES256", key: key}
+}
+
+// jwk returns the public JWK of the signing key
+func (k *testSigningKey) jwk() map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch pub := k.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA", "kid": k.kid, "use": "sig", "alg": k.alg,
+			"n": encode(pub.N.Bytes()),
+			"e": encode(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		return map[string]string{
+			"kty": "EC", "kid": k.kid, "use": "sig", "alg": k.alg, "crv": "P-256",
+			"x": encode(pub.X.FillBytes(make([]byte, 32))),
+			"y": encode(pub.Y.FillBytes(make([]byte, 32))),
+		}
+	}
+	return nil
+}
+
+// sign creates a compact JWS for the given claims
+func (k *testSigningKey) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": k.alg, "kid": k.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := k.key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// stubIdentityProvider serves OIDC discovery and a JWKS for a single realm
+type stubIdentityProvider struct {
+	server *httptest.Server
+	issuer string
+
+	mu          sync.Mutex
+	keys        []*testSigningKey
+	jwksFetches int
+	jwksDelay   time.Duration
+}
+
+// newStubIdentityProvider starts a stub provider publishing the given keys
+func newStubIdentityProvider(t *testing.T, keys ...*testSigningKey) *stubIdentityProvider {
+	t.Helper()
+
+	idp := &stubIdentityProvider{keys: keys}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/realms/test/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   idp.issuer,
+				"jwks_uri": idp.issuer + "/protocol/openid-connect/certs",
+			})
+		case "/realms/test/protocol/openid-connect/certs":
+			idp.mu.Lock()
+			delay := idp.jwksDelay
+			idp.mu.Unlock()
+			time.Sleep(delay)
+
+			idp.mu.Lock()
+			defer idp.mu.Unlock()
+			idp.jwksFetches++
+			jwks := make([]map[string]string, len(idp.keys))
+			for i, k := range idp.keys {
+				jwks[i] = k.jwk()
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": jwks})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	idp.issuer = idp.server.URL + "/realms/test"
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// setKeys replaces the published keys, simulating key rotation
+func (idp *stubIdentityProvider) setKeys(keys ...*testSigningKey) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys = keys
+}
+
+// setDelay makes the provider answer JWKS requests slowly
+func (idp *stubIdentityProvider) setDelay(delay time.Duration) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.jwksDelay = delay
+}
+
+// fetches returns how often the JWKS was downloaded
+func (idp *stubIdentityProvider) fetches() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksFetches
+}
+
+// newKeycloakTestAuth creates Keycloak-preset auth against the stub provider
+func newKeycloakTestAuth(idp *stubIdentityProvider, audience string) *middleware.OIDCAuth {
+	return middleware.NewKeycloakAuth(&config.KeycloakConfig{
+		URL:      idp.server.URL,
+		Realm:    "test",
+		Audience: audience,
+	}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+}
+
+// newAuthTestRouter creates a router with a protected and an admin-only route
+func newAuthTestRouter(auth *middleware.OIDCAuth) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	whoami := func(c *gin.Context) {
+		userInfo, _ := c.Get("userInfo")
+		c.String(http.StatusOK, userInfo.(*middleware.UserInfo).Sub)
+	}
+	router.GET("/me", auth.Authenticate(), whoami)
+	router.GET("/admin", auth.Authenticate(), auth.RequireRoles("admin"), whoami)
+
+	return router
+}
+
+// doAuthRequest performs a request with a bearer token
+func doAuthRequest(router http.Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestLocalTokenVerification tests JWT validation against a stub JWKS
+func TestLocalTokenVerification(t *testing.T) {
+	rsaKey := newRSASigningKey(t, "rsa-1")
+	ecKey := newECSigningKey(t, "ec-1")
+	idp := newStubIdentityProvider(t, rsaKey, ecKey)
+	router := newAuthTestRouter(newKeycloakTestAuth(idp, "web-analyzer-backend"))
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":                "user-1",
+			"preferred_username": "alice",
+			"iss":                idp.issuer,
+			"aud":                []string{"web-analyzer-backend", "account"},
+			"exp":                time.Now().Add(5 * time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+			"realm_access":       map[string]interface{}{"roles": []string{"user"}},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	forged := newRSASigningKey(t, "rsa-1")
+
+	tests := []struct {
+		name     string
+		path     string
+		token    string
+		expected int
+	}{
+		{"RS256", "/me", rsaKey.sign(t, claims(nil)), http.StatusOK},
+		{"ES256", "/me", ecKey.sign(t, claims(nil)), http.StatusOK},
+		{"AuthorizedParty", "/me", rsaKey.sign(t, claims(map[string]interface{}{"aud": "account", "azp": "web-analyzer-backend"})), http.StatusOK},
+		{"MissingToken", "/me", "", http.StatusUnauthorized},
+		{"Malformed", "/me", "not-a-token", http.StatusUnauthorized},
+		{"ForgedSignature", "/me", forged.sign(t, claims(nil)), http.StatusUnauthorized},
+		{"Expired", "/me", rsaKey.sign(t, claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})), http.StatusUnauthorized},
+		{"MissingExpiry", "/me", rsaKey.sign(t, claims(map[string]interface{}{"exp": nil})), http.StatusUnauthorized},
+		{"NotYetValid", "/me", rsaKey.sign(t, claims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})), http.StatusUnauthorized},
+		{"WrongIssuer", "/me", rsaKey.sign(t, claims(map[string]interface{}{"iss": "https://evil.example.com/realms/test"})), http.StatusUnauthorized},
+		{"WrongAudience", "/me", rsaKey.sign(t, claims(map[string]interface{}{"aud": "other-client"})), http.StatusUnauthorized},
+		{"AdminWithoutRole", "/admin", rsaKey.sign(t, claims(nil)), http.StatusForbidden},
+		{"AdminWithRole", "/admin", rsaKey.sign(t, claims(map[string]interface{}{
+			"realm_access": map[string]interface{}{"roles": []string{"admin"}},
+		})), http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doAuthRequest(router, tc.path, tc.token)
+			if w.Code != tc.expected {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expected, w.Code, w.Body.String())
+			}
+			if tc.expected == http.StatusOK && w.Body.String() != "user-1" {
+				t.Errorf("Expected subject user-1, got %q", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("UnsignedToken", func(t *testing.T) {
+		token := rsaKey.sign(t, claims(nil))
+		parts := strings.Split(token, ".")
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa-1"}`))
+		w := doAuthRequest(router, "/me", header+"."+parts[1]+".")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for alg none, got %d", w.Code)
+		}
+	})
+
+	// All requests above were served from a single JWKS download
+	if fetches := idp.fetches(); fetches != 1 {
+		t.Errorf("Expected JWKS to be fetched once, got %d", fetches)
+	}
+}
+
+// TestDefaultAudience tests that the default Keycloak configuration only
+// accepts tokens issued to the backend's client
+func TestDefaultAudience(t *testing.T) {
+	rsaKey := newRSASigningKey(t, "rsa-1")
+	idp := newStubIdentityProvider(t, rsaKey)
+	t.Setenv("KEYCLOAK_URL", idp.server.URL)
+	t.Setenv("KEYCLOAK_REALM", "test")
+	t.Setenv("KEYCLOAK_AUDIENCE", "")
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	router := newAuthTestRouter(middleware.NewKeycloakAuth(&cfg.Keycloak, slog.New(slog.NewTextHandler(os.Stdout, nil))))
+
+	token := func(aud, azp string) string {
+		return rsaKey.sign(t, map[string]interface{}{
+			"sub": "user-1",
+			"iss": idp.issuer,
+			"aud": aud,
+			"azp": azp,
+			"exp": time.Now().Add(5 * time.Minute).Unix(),
+		})
+	}
+	if w := doAuthRequest(router, "/me", token("web-analyzer-backend", "web-analyzer-frontend")); w.Code != http.StatusOK {
+		t.Errorf("Expected a token for the backend to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(router, "/me", token("account", "other-client")); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a token for another client to be rejected, got %d", w.Code)
+	}
+}
+
+// TestJWKSKeyRotation tests that an unknown kid triggers a JWKS refetch
+func TestJWKSKeyRotation(t *testing.T) {
+	oldKey := newRSASigningKey(t, "old")
+	idp := newStubIdentityProvider(t, oldKey)
+	router := newAuthTestRouter(newKeycloakTestAuth(idp, ""))
+
+	claims := map[string]interface{}{
+		"sub": "user-1",
+		"iss": idp.issuer,
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+
+	if w := doAuthRequest(router, "/me", oldKey.sign(t, claims)); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 with the old key, got %d", w.Code)
+	}
+
+	// The provider rotates to a new key
+	newKey := newECSigningKey(t, "new")
+	idp.setKeys(newKey)
+
+	if w := doAuthRequest(router, "/me", newKey.sign(t, claims)); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 after rotation, got %d", w.Code)
+	}
+	if fetches := idp.fetches(); fetches != 2 {
+		t.Errorf("Expected JWKS to be refetched once after rotation, got %d fetches", fetches)
+	}
+
+	// Further unknown kids do not force another download right away
+	unknown := newRSASigningKey(t, "unknown")
+	if w := doAuthRequest(router, "/me", unknown.sign(t, claims)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 for an unknown key, got %d", w.Code)
+	}
+	if fetches := idp.fetches(); fetches != 2 {
+		t.Errorf("Expected refetches to be rate limited, got %d fetches", fetches)
+	}
+}
+
+// TestJWKSConcurrentRefresh tests that concurrent requests share one JWKS
+// download that a disconnecting client cannot cancel
+func TestJWKSConcurrentRefresh(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	idp.setDelay(200 * time.Millisecond)
+	router := newAuthTestRouter(newKeycloakTestAuth(idp, ""))
+
+	token := signingKey.sign(t, map[string]interface{}{
+		"sub": "user-1",
+		"iss": idp.issuer,
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	})
+
+	// The first client gives up while the download is running
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	abandoned := httptest.NewRecorder()
+	router.ServeHTTP(abandoned, req)
+	if abandoned.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for the abandoned request, got %d", abandoned.Code)
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = doAuthRequest(router, "/me", token).Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200, got %d", i, code)
+		}
+	}
+	if fetches := idp.fetches(); fetches != 1 {
+		t.Errorf("Expected a single JWKS download, got %d", fetches)
+	}
+}
+
+// TestOIDCClaimPaths tests generic OIDC providers with configurable claims
+func TestOIDCClaimPaths(t *testing.T) {
+	key := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, key)
+
+	tests := []struct {
+		name        string
+		userIDClaim string
+		rolesClaim  string
+		claims      map[string]interface{}
+		expected    int
+	}{
+		{
+			name:        "AzureAD",
+			userIDClaim: "oid",
+			rolesClaim:  "roles",
+			claims:      map[string]interface{}{"oid": "user-1", "roles": []string{"admin"}},
+			expected:    http.StatusOK,
+		},
+		{
+			name:        "Auth0NamespacedClaim",
+			userIDClaim: "sub",
+			rolesClaim:  "https://web-analyzer.example.com/roles",
+			claims:      map[string]interface{}{"sub": "user-1", "https://web-analyzer.example.com/roles": []string{"admin"}},
+			expected:    http.StatusOK,
+		},
+		{
+			name:        "NestedClientRoles",
+			userIDClaim: "sub",
+			rolesClaim:  "resource_access.web-analyzer.roles",
+			claims: map[string]interface{}{
+				"sub": "user-1",
+				"resource_access": map[string]interface{}{
+					"web-analyzer": map[string]interface{}{"roles": []string{"admin"}},
+				},
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name:        "OktaGroupsWithoutAdmin",
+			userIDClaim: "sub",
+			rolesClaim:  "groups",
+			claims:      map[string]interface{}{"sub": "user-1", "groups": []string{"Everyone"}},
+			expected:    http.StatusForbidden,
+		},
+		{
+			name:        "MissingUserIDClaim",
+			userIDClaim: "email",
+			rolesClaim:  "roles",
+			claims:      map[string]interface{}{"sub": "user-1", "roles": []string{"admin"}},
+			expected:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			auth := middleware.NewOIDCAuth(&config.OIDCConfig{
+				IssuerURL:   idp.issuer,
+				UserIDClaim: tc.userIDClaim,
+				RolesClaim:  tc.rolesClaim,
+			}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+			router := newAuthTestRouter(auth)
+
+			tc.claims["iss"] = idp.issuer
+			tc.claims["exp"] = time.Now().Add(5 * time.Minute).Unix()
+
+			w := doAuthRequest(router, "/admin", key.sign(t, tc.claims))
+			if w.Code != tc.expected {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expected, w.Code, w.Body.String())
+			}
+			if tc.expected == http.StatusOK && w.Body.String() != "user-1" {
+				t.Errorf("Expected user ID user-1, got %q", w.Body.String())
+			}
+		})
+	}
+}
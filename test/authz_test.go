@@ -0,0 +1,258 @@
+package analyzer_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"webPageAnalyzerGO/internal/authz"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// memoryTenancyStore is an in-memory authz.Store
+type memoryTenancyStore struct {
+	orgs        map[string]*models.Organization
+	projects    map[string]*models.Project
+	memberships map[string]*models.Membership // keyed by org ID + "/" + user ID
+}
+
+func (s *memoryTenancyStore) GetOrganization(ctx context.Context, id string) (*models.Organization, error) {
+	return s.orgs[id], nil
+}
+
+func (s *memoryTenancyStore) GetMembership(ctx context.Context, orgID, userID string) (*models.Membership, error) {
+	return s.memberships[orgID+"/"+userID], nil
+}
+
+func (s *memoryTenancyStore) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	return s.projects[id], nil
+}
+
+// TestPolicy tests the authorization decisions for every resource kind
+func TestPolicy(t *testing.T) {
+	org := &models.Organization{ID: primitive.NewObjectID(), Name: "Acme"}
+	project := &models.Project{ID: primitive.NewObjectID(), OrganizationID: org.ID, Name: "Website"}
+	orgID, projectID := org.ID.Hex(), project.ID.Hex()
+
+	store := &memoryTenancyStore{
+		orgs:        map[string]*models.Organization{orgID: org},
+		projects:    map[string]*models.Project{projectID: project},
+		memberships: map[string]*models.Membership{},
+	}
+	for user, role := range map[string]string{"viewer": models.RoleViewer, "analyst": models.RoleAnalyst, "owner": models.RoleOwner} {
+		store.memberships[orgID+"/"+user] = &models.Membership{OrganizationID: org.ID, UserID: user, Role: role}
+	}
+	policy := authz.NewPolicy(store)
+
+	alice := authz.Subject{UserID: "alice"}
+	bob := authz.Subject{UserID: "bob"}
+	admin := authz.Subject{UserID: "root", Admin: true}
+	anonymous := authz.Subject{}
+	readKey := authz.Subject{UserID: "alice", Scopes: []string{models.ScopeRead}}
+	viewer := authz.Subject{UserID: "viewer"}
+	analyst := authz.Subject{UserID: "analyst"}
+	owner := authz.Subject{UserID: "owner"}
+
+	personal := authz.Resource{Kind: authz.KindAnalysis, ID: "a1", OwnerID: "alice"}
+	anonymousAnalysis := authz.Resource{Kind: authz.KindAnalysis, ID: "a2"}
+	shared := authz.Resource{Kind: authz.KindAnalysis, ID: "a3", OwnerID: "owner", ProjectID: projectID}
+	missingProject := authz.Project(primitive.NewObjectID().Hex())
+
+	tests := []struct {
+		name     string
+		subject  authz.Subject
+		action   authz.Action
+		resource authz.Resource
+		want     error
+	}{
+		// Personal analyses
+		{"OwnerReads", alice, authz.ActionRead, personal, nil},
+		{"OwnerAnalyzes", alice, authz.ActionAnalyze, personal, nil},
+		{"OwnerDeletes", alice, authz.ActionDelete, personal, nil},
+		{"OtherUserReads", bob, authz.ActionRead, personal, authz.ErrForbidden},
+		{"OtherUserAnalyzes", bob, authz.ActionAnalyze, personal, authz.ErrForbidden},
+		{"OtherUserDeletes", bob, authz.ActionDelete, personal, authz.ErrForbidden},
+		{"AnonymousReads", anonymous, authz.ActionRead, personal, authz.ErrUnauthenticated},
+		{"AdminReads", admin, authz.ActionRead, personal, nil},
+		{"AdminDeletes", admin, authz.ActionDelete, personal, nil},
+		{"ReadKeyReads", readKey, authz.ActionRead, personal, nil},
+		{"ReadKeyAnalyzes", readKey, authz.ActionAnalyze, personal, authz.ErrForbidden},
+
+		// Anonymous analyses
+		{"AnyoneReadsAnonymous", bob, authz.ActionRead, anonymousAnalysis, nil},
+		{"UserAnalyzesAnonymous", bob, authz.ActionAnalyze, anonymousAnalysis, nil},
+		{"AnonymousAnalyzesAnonymous", anonymous, authz.ActionAnalyze, anonymousAnalysis, authz.ErrUnauthenticated},
+		{"UserDeletesAnonymous", bob, authz.ActionDelete, anonymousAnalysis, authz.ErrForbidden},
+		{"AdminDeletesAnonymous", admin, authz.ActionDelete, anonymousAnalysis, nil},
+
+		// Project analyses
+		{"ViewerReadsShared", viewer, authz.ActionRead, shared, nil},
+		{"ViewerAnalyzesShared", viewer, authz.ActionAnalyze, shared, authz.ErrForbidden},
+		{"AnalystAnalyzesShared", analyst, authz.ActionAnalyze, shared, nil},
+		{"AnalystDeletesShared", analyst, authz.ActionDelete, shared, nil},
+		{"NonMemberReadsShared", bob, authz.ActionRead, shared, authz.ErrForbidden},
+		{"AnonymousReadsShared", anonymous, authz.ActionRead, shared, authz.ErrUnauthenticated},
+
+		// Projects and organizations
+		{"ViewerReadsProject", viewer, authz.ActionRead, authz.Project(projectID), nil},
+		{"ViewerAnalyzesIntoProject", viewer, authz.ActionAnalyze, authz.Project(projectID), authz.ErrForbidden},
+		{"AnalystAnalyzesIntoProject", analyst, authz.ActionAnalyze, authz.Project(projectID), nil},
+		{"NonMemberReadsProject", bob, authz.ActionRead, authz.Project(projectID), authz.ErrNotFound},
+		{"MissingProject", owner, authz.ActionRead, missingProject, authz.ErrNotFound},
+		{"InvalidProjectID", owner, authz.ActionRead, authz.Project("not-an-id"), authz.ErrNotFound},
+		{"AnalystManagesOrganization", analyst, authz.ActionManage, authz.Organization(orgID), authz.ErrForbidden},
+		{"OwnerManagesOrganization", owner, authz.ActionManage, authz.Organization(orgID), nil},
+		{"AdminManagesOrganization", admin, authz.ActionManage, authz.Organization(orgID), nil},
+		{"AdminManagesMissingOrganization", admin, authz.ActionManage, authz.Organization(primitive.NewObjectID().Hex()), authz.ErrNotFound},
+		{"APIKeyManagesOrganization", authz.Subject{UserID: "owner", Scopes: models.APIKeyScopes}, authz.ActionManage, authz.Organization(orgID), authz.ErrForbidden},
+		{"AnonymousReadsOrganization", anonymous, authz.ActionRead, authz.Organization(orgID), authz.ErrUnauthenticated},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Authorize(context.Background(), tc.subject, tc.action, tc.resource)
+			if !errors.Is(err, tc.want) || (tc.want == nil && err != nil) {
+				t.Errorf("Expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	t.Run("AnalysisScope", func(t *testing.T) {
+		scopes := []struct {
+			name      string
+			subject   authz.Subject
+			projectID string
+			userID    string
+			want      models.AnalysisFilter
+			wantErr   error
+		}{
+			{"UserGetsPersonal", bob, "", "", models.AnalysisFilter{UserID: "bob"}, nil},
+			{"UserCannotPickUser", bob, "", "alice", models.AnalysisFilter{UserID: "bob"}, nil},
+			{"AdminGetsEverything", admin, "", "", models.AnalysisFilter{}, nil},
+			{"AdminPicksUser", admin, "", "alice", models.AnalysisFilter{UserID: "alice"}, nil},
+			{"ViewerGetsProject", viewer, projectID, "", models.AnalysisFilter{ProjectID: projectID}, nil},
+			{"NonMemberGetsNoProject", bob, projectID, "", models.AnalysisFilter{}, authz.ErrNotFound},
+			{"AnonymousGetsNothing", anonymous, "", "", models.AnalysisFilter{}, authz.ErrUnauthenticated},
+		}
+
+		for _, tc := range scopes {
+			got, err := policy.AnalysisScope(context.Background(), tc.subject, tc.projectID, tc.userID)
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Errorf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: expected scope %+v, got %+v", tc.name, tc.want, got)
+			}
+		}
+	})
+}
+
+// TestAnalysisOwnershipEnforced tests that no endpoint lets a non-admin read
+// or trigger work on another user's analysis
+func TestAnalysisOwnershipEnforced(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newTestAPIServer(t, idp)
+	handler := server.Handler()
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Private</title></head><body><h1>Private</h1></body></html>`))
+	}))
+	defer page.Close()
+
+	as := func(userID string, roles ...string) map[string]string {
+		token := signingKey.sign(t, map[string]interface{}{
+			"sub":          userID,
+			"iss":          idp.issuer,
+			"exp":          time.Now().Add(5 * time.Minute).Unix(),
+			"realm_access": map[string]interface{}{"roles": roles},
+		})
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+	alice, bob, admin := as("alice", "user"), as("bob", "user"), as("root", "admin")
+
+	w := apiRequest(t, handler, http.MethodPost, "/api/analyze", models.AnalysisRequest{URL: page.URL}, alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 analyzing, got %d: %s", w.Code, w.Body.String())
+	}
+	var analysis models.AnalysisResult
+	if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	path := "/api/analysis/" + analysis.ID.Hex()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"GetAnalysis", http.MethodGet, path},
+		{"DeepAnalysis", http.MethodGet, path + "/deep"},
+		{"ForcedDeepAnalysis", http.MethodGet, path + "/deep?force=true"},
+		{"DeepAnalysisVersions", http.MethodGet, path + "/deep/versions"},
+		{"DeepAnalysisVersion", http.MethodGet, path + "/deep/versions/1"},
+		{"DeleteAnalysis", http.MethodDelete, path},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if w := apiRequest(t, handler, tc.method, tc.path, nil, bob); w.Code != http.StatusForbidden {
+				t.Errorf("Expected status 403 for another user, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// None of the denied requests ran a deep analysis or deleted anything
+	w = apiRequest(t, handler, http.MethodGet, path+"/deep/versions", nil, alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for the owner, got %d: %s", w.Code, w.Body.String())
+	}
+	var versions struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &versions); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if versions.Count != 0 {
+		t.Errorf("Expected no deep analysis versions, got %d", versions.Count)
+	}
+
+	t.Run("Listings", func(t *testing.T) {
+		listings := []struct {
+			name    string
+			headers map[string]string
+			path    string
+			want    int
+		}{
+			{"RecentAsOther", bob, "/api/analyses", 0},
+			{"RecentAsOtherForUser", bob, "/api/analyses?user_id=alice", 0},
+			{"SearchAsOther", bob, "/api/search?q=private", 0},
+			{"SearchAsOtherForUser", bob, "/api/search?q=private&user_id=alice", 0},
+			{"RecentAsOwner", alice, "/api/analyses", 1},
+			{"RecentAsAdmin", admin, "/api/analyses?user_id=alice", 1},
+		}
+
+		for _, tc := range listings {
+			w := apiRequest(t, handler, http.MethodGet, tc.path, nil, tc.headers)
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected status 200, got %d: %s", tc.name, w.Code, w.Body.String())
+				continue
+			}
+			var resp struct {
+				Count int `json:"count"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Count != tc.want {
+				t.Errorf("%s: expected %d results, got %d", tc.name, tc.want, resp.Count)
+			}
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package analyzer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/pkg/client"
+)
+
+// handlerTransport serves client requests with an in-process handler
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// failingTransport answers the first failures requests with status and
+// passes the rest on
+type failingTransport struct {
+	next     http.RoundTripper
+	status   int
+	failures int32
+	calls    atomic.Int32
+}
+
+func (t *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.calls.Add(1) <= t.failures {
+		w := httptest.NewRecorder()
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(t.status)
+		return w.Result(), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// TestClient tests the Go client against the API server
+func TestClient(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	handler := newTestAPIServer(t, idp).Handler()
+	transport := handlerTransport{handler: handler}
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<!DOCTYPE html><html><head><title>Page %s</title></head><body><h1>Client</h1><a href="/">Home</a></body></html>`, r.URL.Path)
+	}))
+	defer page.Close()
+
+	token := signingKey.sign(t, map[string]interface{}{
+		"sub":                "user-1",
+		"preferred_username": "ci-bot",
+		"iss":                idp.issuer,
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"realm_access":       map[string]interface{}{"roles": []string{"user"}},
+	})
+	newClient := func(rt http.RoundTripper, opts ...client.Option) *client.Client {
+		opts = append([]client.Option{
+			client.WithHTTPClient(&http.Client{Transport: rt}),
+			client.WithRetries(3, time.Millisecond),
+		}, opts...)
+		return client.New("http://api.test/", opts...)
+	}
+	c := newClient(transport, client.WithBearerToken(token))
+	ctx := context.Background()
+
+	var first *client.AnalysisResult
+	t.Run("AnalyzeAndGet", func(t *testing.T) {
+		var err error
+		first, err = c.Analyze(ctx, client.AnalysisRequest{URL: page.URL + "/first"})
+		if err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		if first.Title != "Page /first" {
+			t.Errorf("Expected title 'Page /first', got %q", first.Title)
+		}
+
+		got, err := c.GetAnalysis(ctx, first.ID.Hex())
+		if err != nil {
+			t.Fatalf("GetAnalysis failed: %v", err)
+		}
+		if got.ID != first.ID || got.URL != first.URL {
+			t.Errorf("Expected analysis %s, got %+v", first.ID.Hex(), got)
+		}
+	})
+
+	t.Run("APIError", func(t *testing.T) {
+		_, err := c.Analyze(ctx, client.AnalysisRequest{URL: page.URL + "/missing"})
+		var apiErr *client.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+			t.Fatalf("Expected a 502 API error, got %v", err)
+		}
+		if client.ErrorCode(err) != client.CodeUpstreamStatus {
+			t.Errorf("Expected code %s, got %q", client.CodeUpstreamStatus, client.ErrorCode(err))
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		urls := []string{page.URL + "/a", page.URL + "/b", page.URL + "/missing", page.URL + "/c"}
+		results := c.AnalyzeBatch(ctx, urls, "", 2)
+		if len(results) != len(urls) {
+			t.Fatalf("Expected %d results, got %d", len(urls), len(results))
+		}
+		for i, r := range results {
+			if r.URL != urls[i] {
+				t.Errorf("Expected result %d for %s, got %s", i, urls[i], r.URL)
+			}
+			if failed := r.Err != nil; failed != strings.HasSuffix(r.URL, "/missing") {
+				t.Errorf("Unexpected outcome for %s: %v", r.URL, r.Err)
+			}
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		seen := map[string]bool{}
+		for a, err := range c.UserAnalyses(ctx, client.ListOptions{Limit: 2}) {
+			if err != nil {
+				t.Fatalf("Iterating analyses failed: %v", err)
+			}
+			if seen[a.ID.Hex()] {
+				t.Errorf("Analysis %s returned twice", a.ID.Hex())
+			}
+			seen[a.ID.Hex()] = true
+		}
+		if len(seen) != 4 {
+			t.Errorf("Expected 4 analyses across pages, got %d", len(seen))
+		}
+
+		page, err := c.ListUserAnalyses(ctx, client.ListOptions{Limit: 2})
+		if err != nil || page.Count != 2 || page.NextCursor == "" {
+			t.Errorf("Expected a first page of 2 with a cursor, got %+v, %v", page, err)
+		}
+	})
+
+	t.Run("DeepAnalysis", func(t *testing.T) {
+		deep, err := c.DeepAnalysis(ctx, first.ID.Hex(), client.DeepAnalysisOptions{MaxAge: time.Hour})
+		if err != nil {
+			t.Fatalf("DeepAnalysis failed: %v", err)
+		}
+		versions, err := c.ListDeepAnalysisVersions(ctx, first.ID.Hex())
+		if err != nil || len(versions) != 1 {
+			t.Fatalf("Expected 1 deep analysis version, got %d, %v", len(versions), err)
+		}
+		stored, err := c.GetDeepAnalysisVersion(ctx, first.ID.Hex(), versions[0].Version)
+		if err != nil || stored.ID != deep.ID {
+			t.Errorf("Expected stored version %s, got %+v, %v", deep.ID.Hex(), stored, err)
+		}
+	})
+
+	t.Run("APIKey", func(t *testing.T) {
+		created, err := c.CreateAPIKey(ctx, client.APIKeyRequest{Name: "client", Scopes: []string{client.ScopeRead}})
+		if err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+
+		keyClient := newClient(transport, client.WithAPIKey(created.Key))
+		if _, err := keyClient.GetAnalysis(ctx, first.ID.Hex()); err != nil {
+			t.Errorf("GetAnalysis with API key failed: %v", err)
+		}
+		_, err = keyClient.Analyze(ctx, client.AnalysisRequest{URL: page.URL})
+		var apiErr *client.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected 403 analyzing with a read key, got %v", err)
+		}
+
+		if err := c.RevokeAPIKey(ctx, created.ID.Hex()); err != nil {
+			t.Fatalf("RevokeAPIKey failed: %v", err)
+		}
+		if _, err := keyClient.GetAnalysis(ctx, first.ID.Hex()); err == nil {
+			t.Error("Expected revoked API key to be rejected")
+		}
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+			flaky := &failingTransport{next: transport, status: status, failures: 2}
+			if _, err := newClient(flaky, client.WithBearerToken(token)).GetAnalysis(ctx, first.ID.Hex()); err != nil {
+				t.Errorf("Expected request to succeed after %d retries, got %v", status, err)
+			}
+			if calls := flaky.calls.Load(); calls != 3 {
+				t.Errorf("Expected 3 attempts after %d, got %d", status, calls)
+			}
+		}
+
+		// Non-idempotent requests are not retried on server errors
+		flaky := &failingTransport{next: transport, status: http.StatusServiceUnavailable, failures: 2}
+		_, err := newClient(flaky, client.WithBearerToken(token)).Analyze(ctx, client.AnalysisRequest{URL: page.URL})
+		if err == nil || flaky.calls.Load() != 1 {
+			t.Errorf("Expected analyze to fail without retrying, got %v after %d attempts", err, flaky.calls.Load())
+		}
+
+		// Retries stop once the budget is spent
+		flaky = &failingTransport{next: transport, status: http.StatusTooManyRequests, failures: 10}
+		if _, err := newClient(flaky, client.WithBearerToken(token)).GetAnalysis(ctx, first.ID.Hex()); err == nil || flaky.calls.Load() != 4 {
+			t.Errorf("Expected failure after 4 attempts, got %v after %d", err, flaky.calls.Load())
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := c.GetAnalysis(cancelled, first.ID.Hex()); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
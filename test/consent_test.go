@@ -0,0 +1,110 @@
+package analyzer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestConsentVerification tests detecting consent managers and the tracking
+// that happens before visitors interact with them
+func TestConsentVerification(t *testing.T) {
+	pages := map[string]string{
+		// Trackers are blocked until consent, except the cookieless Plausible
+		"/compliant": `<html><head>
+			<script id="Cookiebot" src="https://consent.cookiebot.com/uc.js" data-cbid="abc"></script>
+			<script type="text/plain" data-cookieconsent="statistics" src="https://www.googletagmanager.com/gtag/js?id=G-ABC123"></script>
+			<script type="text/plain" data-cookieconsent="marketing">fbq('init', '123');</script>
+			<script defer src="https://plausible.io/js/script.js"></script>
+		</head><body>
+			<noscript><img src="https://www.facebook.com/tr?id=123&ev=PageView&noscript=1"></noscript>
+		</body></html>`,
+		// A TCF stub, but Google Analytics runs and sets its cookie right away
+		"/leaky": `<html><head>
+			<script>window.__tcfapi = function() { (window.__tcfapi.a = window.__tcfapi.a || []).push(arguments) };</script>
+			<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123"></script>
+		</head><body><div id="onetrust-banner-sdk"></div></body></html>`,
+		"/unmanaged": `<html><head><script src="https://static.hotjar.com/c/hotjar-1.js"></script></head><body></body></html>`,
+		"/clean":     `<html><body><p>Nothing to consent to</p></body></html>`,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/leaky" {
+			http.SetCookie(w, &http.Cookie{Name: "_ga", Value: "GA1.1.1", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "OptanonConsent", Value: "isGpcEnabled=0", Path: "/"})
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Path]))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	fetch := func(t *testing.T, path string) (models.ConsentAnalysis, bool) {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		return page.Privacy.Consent, page.Cookies.HasConsent
+	}
+	platforms := func(consent models.ConsentAnalysis) []string {
+		var names []string
+		for _, platform := range consent.Platforms {
+			names = append(names, platform.Name)
+		}
+		return names
+	}
+
+	t.Run("BlockedUntilConsent", func(t *testing.T) {
+		consent, hasConsent := fetch(t, "/compliant")
+		if !hasConsent || !slices.Equal(platforms(consent), []string{"Cookiebot"}) || consent.TCF {
+			t.Errorf("Expected Cookiebot without TCF, got %v (TCF %v)", platforms(consent), consent.TCF)
+		}
+		if len(consent.TrackersBeforeConsent) != 0 || len(consent.CookiesBeforeConsent) != 0 || len(consent.Findings) != 0 {
+			t.Errorf("Expected no tracking before consent, got %+v", consent)
+		}
+	})
+
+	t.Run("TrackingBeforeConsent", func(t *testing.T) {
+		consent, hasConsent := fetch(t, "/leaky")
+		if !hasConsent || !consent.TCF || !slices.Equal(platforms(consent), []string{"OneTrust"}) {
+			t.Errorf("Expected OneTrust with TCF, got %v (TCF %v)", platforms(consent), consent.TCF)
+		}
+		if !slices.Equal(consent.TrackersBeforeConsent, []string{"Google Analytics"}) {
+			t.Errorf("Expected Google Analytics before consent, got %v", consent.TrackersBeforeConsent)
+		}
+		// The consent manager's own cookie is not tracking
+		if !slices.Equal(consent.CookiesBeforeConsent, []string{"_ga"}) {
+			t.Errorf("Expected the _ga cookie before consent, got %v", consent.CookiesBeforeConsent)
+		}
+		if len(consent.Findings) != 2 {
+			t.Fatalf("Expected 2 findings, got %+v", consent.Findings)
+		}
+		for _, finding := range consent.Findings {
+			if finding.Verdict != models.VerdictFail {
+				t.Errorf("Expected failed findings, got %+v", finding)
+			}
+		}
+	})
+
+	t.Run("NoConsentManager", func(t *testing.T) {
+		consent, hasConsent := fetch(t, "/unmanaged")
+		if hasConsent || len(consent.Platforms) != 0 {
+			t.Errorf("Expected no consent manager, got %v", platforms(consent))
+		}
+		if !slices.Equal(consent.TrackersBeforeConsent, []string{"Hotjar"}) || len(consent.Findings) != 1 {
+			t.Errorf("Expected a Hotjar finding, got %+v", consent)
+		}
+	})
+
+	t.Run("NothingToConsentTo", func(t *testing.T) {
+		consent, hasConsent := fetch(t, "/clean")
+		if hasConsent || len(consent.Platforms) != 0 || len(consent.Findings) != 0 {
+			t.Errorf("Expected an empty consent report, got %+v", consent)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package analyzer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestCookieAudit tests auditing the attributes of cookies set by a page
+func TestCookieAudit(t *testing.T) {
+	setCookies := []string{
+		"sessionid=abc",
+		"__Host-id=1; Secure; HttpOnly; Path=/; SameSite=Strict",
+		"__Host-bad=1; Secure; Path=/app",
+		"__Secure-x=1",
+		"prefs=dark; SameSite=None",
+		"tracker=1; Domain=.example.co.uk; Secure; Max-Age=40000000",
+		"psl=1; Domain=co.uk; Secure",
+		"part=1; Partitioned",
+		"big=" + strings.Repeat("x", 5000) + "; Secure",
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range setCookies {
+			w.Header().Add("Set-Cookie", c)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Cookies</p></body></html>`))
+	}))
+	defer server.Close()
+
+	page, err := getTestAnalyzer().FetchPage(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("FetchPage failed: %v", err)
+	}
+
+	cookies := page.Cookies
+	if cookies.TotalCount != len(setCookies) || cookies.ThirdParty != 2 || cookies.FirstParty != len(setCookies)-2 {
+		t.Errorf("Unexpected counts: total %d, first-party %d, third-party %d",
+			cookies.TotalCount, cookies.FirstParty, cookies.ThirdParty)
+	}
+	if cookies.MaxAgeDays != 462 {
+		t.Errorf("Expected a max age of 462 days, got %d", cookies.MaxAgeDays)
+	}
+
+	records := map[string]models.CookieRecord{}
+	for _, record := range cookies.Records {
+		records[record.Name] = record
+	}
+	findings := func(name string) map[string]string {
+		found := map[string]string{}
+		for _, f := range records[name].Findings {
+			if f.Explanation == "" {
+				t.Errorf("Expected an explanation for %s of %s", f.Name, name)
+			}
+			found[f.Name] = f.Verdict
+		}
+		return found
+	}
+
+	tests := []struct {
+		name string
+		want map[string]string
+	}{
+		{"sessionid", map[string]string{"HttpOnly": models.VerdictFail, "Secure": models.VerdictFail, "SameSite": models.VerdictWarn}},
+		{"__Host-id", map[string]string{}},
+		{"__Host-bad", map[string]string{"Prefix": models.VerdictFail}},
+		{"__Secure-x", map[string]string{"Prefix": models.VerdictFail, "Secure": models.VerdictWarn}},
+		{"prefs", map[string]string{"SameSite": models.VerdictFail, "Secure": models.VerdictWarn}},
+		{"tracker", map[string]string{"Domain": models.VerdictFail, "Expires": models.VerdictWarn}},
+		{"psl", map[string]string{"Domain": models.VerdictFail}},
+		{"part", map[string]string{"Partitioned": models.VerdictFail, "Secure": models.VerdictWarn}},
+		{"big", map[string]string{"Size": models.VerdictFail}},
+	}
+	for _, tt := range tests {
+		got := findings(tt.name)
+		if len(got) != len(tt.want) {
+			t.Errorf("Expected findings %v for %s, got %v", tt.want, tt.name, got)
+			continue
+		}
+		for attr, verdict := range tt.want {
+			if got[attr] != verdict {
+				t.Errorf("Expected %s of %s to %s, got %v", attr, tt.name, verdict, got)
+			}
+		}
+	}
+
+	host := records["__Host-id"]
+	if !host.Secure || !host.HttpOnly || host.SameSite != "Strict" || host.Path != "/" || host.Prefix != "__Host-" ||
+		!host.PrefixValid || !host.Session || host.Expires != nil || host.ThirdParty {
+		t.Errorf("Unexpected __Host-id record: %+v", host)
+	}
+	if records["__Host-bad"].PrefixValid || records["sessionid"].Prefix != "" || !records["sessionid"].PrefixValid {
+		t.Error("Unexpected prefix validity")
+	}
+	if tracker := records["tracker"]; tracker.Domain != "example.co.uk" || !tracker.ThirdParty || tracker.Session {
+		t.Errorf("Unexpected tracker record: %+v", tracker)
+	}
+	if !records["part"].Partitioned || records["big"].Size != 5003 {
+		t.Errorf("Unexpected attributes: %+v, size %d", records["part"], records["big"].Size)
+	}
+}
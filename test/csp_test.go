@@ -0,0 +1,149 @@
+package analyzer_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestContentSecurityPolicy tests parsing and evaluating the content
+// security policies of a page
+func TestContentSecurityPolicy(t *testing.T) {
+	hashed := `console.log("hashed")`
+	sum := sha256.Sum256([]byte(hashed))
+	hashSource := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/strict":
+			w.Header().Set("Content-Security-Policy",
+				"Script-Src 'self' 'nonce-abc' "+hashSource+" https://cdn.example.com/js/; object-src 'none'; base-uri 'self'; script-src *")
+			w.Write([]byte(`<html><head>
+				<script nonce="abc">console.log("nonce")</script>
+				<script>console.log("injected")</script>
+				<script>` + hashed + `</script>
+				<script src="/app.js"></script>
+				<script src="https://cdn.example.com/js/lib.js"></script>
+				<script src="https://cdn.example.com/other.js"></script>
+				<script src="https://evil.example.org/x.js"></script>
+				<script type="application/ld+json">{"@type": "Thing"}</script>
+			</head><body></body></html>`))
+		case "/report-only":
+			w.Header().Set("Content-Security-Policy-Report-Only",
+				"default-src * 'unsafe-inline' 'unsafe-eval'; img-src http://img.example.com")
+			w.Write([]byte(`<html><body><script>console.log("inline")</script></body></html>`))
+		case "/meta":
+			w.Write([]byte(`<html><head>
+				<meta http-equiv="content-security-policy" content="script-src 'nonce-abc' 'strict-dynamic' https:; frame-ancestors 'none'">
+				<script nonce="abc" src="https://cdn.example.com/loader.js"></script>
+				<script src="https://cdn.example.com/plain.js"></script>
+			</head><body></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	fetch := func(t *testing.T, path string) (models.CSPAnalysis, map[string]models.SecurityCheck) {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		checks := map[string]models.SecurityCheck{}
+		for _, check := range page.Security.Headers {
+			checks[check.Name] = check
+		}
+		return page.Security.CSP, checks
+	}
+	weaknesses := func(policy models.CSPPolicy) map[string]string {
+		found := map[string]string{}
+		for _, w := range policy.Weaknesses {
+			found[w.Name+" "+w.Value] = w.Verdict
+		}
+		return found
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		csp, checks := fetch(t, "/strict")
+		if len(csp.Policies) != 1 {
+			t.Fatalf("Expected 1 policy, got %+v", csp.Policies)
+		}
+		policy := csp.Policies[0]
+		// Directive names are case-insensitive and the first occurrence wins
+		if sources := policy.Directives["script-src"]; len(sources) != 4 || sources[0] != "'self'" {
+			t.Errorf("Unexpected script-src: %v", sources)
+		}
+		if len(policy.Weaknesses) != 0 {
+			t.Errorf("Expected no weaknesses, got %+v", policy.Weaknesses)
+		}
+		if checks["Content-Security-Policy"].Verdict != models.VerdictPass {
+			t.Errorf("Expected CSP check to pass, got %+v", checks["Content-Security-Policy"])
+		}
+
+		allowed := []bool{true, false, true, true, true, false, false}
+		if len(csp.Scripts) != len(allowed) {
+			t.Fatalf("Expected %d scripts, got %+v", len(allowed), csp.Scripts)
+		}
+		for i, script := range csp.Scripts {
+			if script.Allowed != allowed[i] {
+				t.Errorf("Expected script %d (%s) allowed=%v, got %+v", i, script.URL, allowed[i], script)
+			}
+			if !script.Allowed && script.Reason == "" {
+				t.Errorf("Expected a reason for blocked script %d", i)
+			}
+		}
+		if !csp.Scripts[0].Inline || csp.Scripts[3].URL != server.URL+"/app.js" {
+			t.Errorf("Unexpected scripts: %+v", csp.Scripts)
+		}
+	})
+
+	t.Run("ReportOnly", func(t *testing.T) {
+		csp, checks := fetch(t, "/report-only")
+		if len(csp.Policies) != 1 || !csp.Policies[0].ReportOnly {
+			t.Fatalf("Expected 1 report-only policy, got %+v", csp.Policies)
+		}
+		found := weaknesses(csp.Policies[0])
+		for key, verdict := range map[string]string{
+			"policy ":                                    models.VerdictWarn,
+			"default-src 'unsafe-inline'":                models.VerdictFail,
+			"default-src 'unsafe-eval'":                  models.VerdictWarn,
+			"default-src *":                              models.VerdictFail,
+			"object-src * 'unsafe-inline' 'unsafe-eval'": models.VerdictWarn,
+			"base-uri ":                                  models.VerdictWarn,
+			"img-src http://img.example.com":             models.VerdictWarn,
+		} {
+			if found[key] != verdict {
+				t.Errorf("Expected %q to %s, got %v", key, verdict, found)
+			}
+		}
+		if checks["Content-Security-Policy"].Verdict != models.VerdictWarn {
+			t.Errorf("Expected CSP check to warn, got %+v", checks["Content-Security-Policy"])
+		}
+		if len(csp.Scripts) != 1 || !csp.Scripts[0].Allowed {
+			t.Errorf("Expected inline script to be allowed, got %+v", csp.Scripts)
+		}
+	})
+
+	t.Run("MetaStrictDynamic", func(t *testing.T) {
+		csp, checks := fetch(t, "/meta")
+		if len(csp.Policies) != 1 || csp.Policies[0].Source != "meta" {
+			t.Fatalf("Expected 1 meta policy, got %+v", csp.Policies)
+		}
+		found := weaknesses(csp.Policies[0])
+		if found["frame-ancestors "] != models.VerdictWarn || found["script-src https:"] != "" {
+			t.Errorf("Unexpected weaknesses: %v", found)
+		}
+		if checks["X-Frame-Options"].Verdict != models.VerdictFail {
+			t.Errorf("Expected frame-ancestors in meta to be ignored, got %+v", checks["X-Frame-Options"])
+		}
+		if len(csp.Scripts) != 2 || !csp.Scripts[0].Allowed || csp.Scripts[1].Allowed {
+			t.Errorf("Expected only the nonced script to be allowed, got %+v", csp.Scripts)
+		}
+	})
+}
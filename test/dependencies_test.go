@@ -0,0 +1,192 @@
+package analyzer_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestDependencyInventory tests listing the scripts and stylesheets of a page
+// with their Subresource Integrity protection
+func TestDependencyInventory(t *testing.T) {
+	const library = "console.log('library');"
+	sha256Sum := sha256.Sum256([]byte(library))
+	sha384Sum := sha512.Sum384([]byte(library))
+	valid := "sha384-" + base64.StdEncoding.EncodeToString(sha384Sum[:])
+	// Browsers only compare the strongest algorithm, so the bad sha384 wins
+	mismatch := "sha256-" + base64.StdEncoding.EncodeToString(sha256Sum[:]) + " sha384-" +
+		base64.StdEncoding.EncodeToString(make([]byte, 48))
+
+	var page string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lib.js", "/tampered.js":
+			w.Header().Set("Content-Type", "text/javascript")
+			w.Write([]byte(library))
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// localhost is another site than the 127.0.0.1 the page is fetched from
+	cdn := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	page = `<html><head>
+		<script src="/app.js"></script>
+		<script src="/app.js"></script>
+		<script src="` + cdn + `/lib.js" integrity="` + valid + `" crossorigin="anonymous"></script>
+		<script src="` + cdn + `/tampered.js" integrity="` + mismatch + `" crossorigin></script>
+		<script src="` + cdn + `/nocors.js" integrity="` + valid + `"></script>
+		<script src="https://tracker.example.net/t.js"></script>
+		<script src="https://tracker.example.net/broken.js" integrity="md5-abc"></script>
+		<script type="text/template" src="/template.html"></script>
+		<link rel="stylesheet" href="https://fonts.example.org/font.css">
+		<link rel="modulepreload" href="/module.js">
+		<link rel="canonical" href="https://example.com/">
+		<script>inline()</script>
+	</head><body></body></html>`
+
+	fetch := func(t *testing.T, verify bool) models.DependencyAnalysis {
+		t.Helper()
+		a := newTestAnalyzer(config.AnalyzerConfig{
+			RequestTimeout:  5 * time.Second,
+			UserAgent:       "WebPageAnalyzer-Test/1.0",
+			VerifyIntegrity: verify,
+		}, slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
+		page, err := a.FetchPage(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		return page.Security.Dependencies
+	}
+	findings := func(dep models.Dependency) []string {
+		var verdicts []string
+		for _, finding := range dep.Findings {
+			verdicts = append(verdicts, finding.Verdict)
+		}
+		return verdicts
+	}
+
+	t.Run("Inventory", func(t *testing.T) {
+		deps := fetch(t, false)
+		type item = struct {
+			url, kind      string
+			thirdParty     bool
+			integrityValid bool
+			findings       []string
+		}
+		want := []item{
+			{server.URL + "/app.js", models.DependencyScript, false, false, nil},
+			{cdn + "/lib.js", models.DependencyScript, true, true, nil},
+			{cdn + "/tampered.js", models.DependencyScript, true, true, nil},
+			{cdn + "/nocors.js", models.DependencyScript, true, true, []string{models.VerdictFail}},
+			{"https://tracker.example.net/t.js", models.DependencyScript, true, false, []string{models.VerdictWarn}},
+			{"https://tracker.example.net/broken.js", models.DependencyScript, true, false, []string{models.VerdictFail}},
+			{"https://fonts.example.org/font.css", models.DependencyStylesheet, true, false, []string{models.VerdictWarn}},
+			{server.URL + "/module.js", models.DependencyScript, false, false, nil},
+		}
+		if len(deps.Items) != len(want) {
+			t.Fatalf("Expected %d dependencies, got %d: %+v", len(want), len(deps.Items), deps.Items)
+		}
+		for i, w := range want {
+			got := deps.Items[i]
+			if got.URL != w.url || got.Type != w.kind || got.ThirdParty != w.thirdParty ||
+				got.IntegrityValid != w.integrityValid || !slices.Equal(findings(got), w.findings) {
+				t.Errorf("Dependency %d: expected %+v, got %+v", i, w, got)
+			}
+			if got.Verification != "" {
+				t.Errorf("Dependency %d: expected no verification, got %q", i, got.Verification)
+			}
+		}
+
+		if deps.FirstParty != 2 || deps.ThirdParty != 6 || deps.WithoutIntegrity != 5 {
+			t.Errorf("Unexpected counts: %d first-party, %d third-party, %d without integrity",
+				deps.FirstParty, deps.ThirdParty, deps.WithoutIntegrity)
+		}
+		hosts := []string{"fonts.example.org", "localhost", "tracker.example.net"}
+		if !slices.Equal(deps.ThirdPartyHosts, hosts) {
+			t.Errorf("Expected third-party hosts %v, got %v", hosts, deps.ThirdPartyHosts)
+		}
+		if deps.Items[1].CrossOrigin != "anonymous" || deps.Items[2].CrossOrigin != "anonymous" {
+			t.Errorf("Expected anonymous CORS, got %q and %q", deps.Items[1].CrossOrigin, deps.Items[2].CrossOrigin)
+		}
+	})
+
+	t.Run("Verification", func(t *testing.T) {
+		deps := fetch(t, true)
+		want := map[string]string{
+			cdn + "/lib.js":      models.IntegrityMatch,
+			cdn + "/tampered.js": models.IntegrityMismatch,
+			cdn + "/nocors.js":   models.IntegrityUnavailable,
+		}
+		for _, dep := range deps.Items {
+			if dep.Verification != want[dep.URL] {
+				t.Errorf("%s: expected verification %q, got %q", dep.URL, want[dep.URL], dep.Verification)
+			}
+		}
+		if tampered := deps.Items[2]; !slices.Equal(findings(tampered), []string{models.VerdictFail}) {
+			t.Errorf("Expected a failed integrity check, got %+v", tampered.Findings)
+		}
+	})
+}
+
+// TestDependencyVerificationDeadline tests that integrity checks share one
+// request timeout instead of each getting their own
+func TestDependencyVerificationDeadline(t *testing.T) {
+	integrity := "sha256-" + base64.StdEncoding.EncodeToString(make([]byte, 32))
+	var page strings.Builder
+	page.WriteString("<html><head>")
+	for i := range 12 {
+		fmt.Fprintf(&page, `<script src="/slow%d.js" integrity="%s"></script>`, i, integrity)
+	}
+	page.WriteString("</head><body></body></html>")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page.String()))
+	}))
+	defer server.Close()
+
+	a := newTestAnalyzer(config.AnalyzerConfig{
+		RequestTimeout:  300 * time.Millisecond,
+		UserAgent:       "WebPageAnalyzer-Test/1.0",
+		VerifyIntegrity: true,
+	}, slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	start := time.Now()
+	result, err := a.FetchPage(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("FetchPage failed: %v", err)
+	}
+	// Checked one after another, the downloads would take 3.6s
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the checks to share a deadline, took %v", elapsed)
+	}
+	for _, dep := range result.Security.Dependencies.Items {
+		if dep.Verification != models.IntegrityUnavailable {
+			t.Errorf("%s: expected verification %q, got %q", dep.URL, models.IntegrityUnavailable, dep.Verification)
+		}
+	}
+}
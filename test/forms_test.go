@@ -0,0 +1,129 @@
+package analyzer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestFormAudit tests reporting how the forms of a page submit what users enter
+func TestFormAudit(t *testing.T) {
+	pages := map[string]string{
+		"/forms": `<html><head><base href="/app/"></head><body>
+			<form id="login" method="post" action="session">
+				<input type="hidden" name="csrf_token" value="x">
+				<input type="text" name="username">
+				<input type="password" name="password" autocomplete="current-password">
+			</form>
+			<form name="search"><input type="search" name="q"></form>
+			<form method="get" action="http://other.example.com/signin">
+				<input type="email" name="email">
+				<input type="password" name="password" autocomplete="off">
+			</form>
+			<form method="POST" action="/newsletter"><input type="email" name="email"></form>
+			<form method="dialog"><button>Close</button></form>
+			<div><input type="password" name="pin"></div>
+		</body></html>`,
+		"/clean": `<html><body><p>No forms</p></body></html>`,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Path]))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	fetch := func(t *testing.T, url string) models.FormAnalysis {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), url)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		return page.Security.Forms
+	}
+	findings := func(record models.FormRecord) []string {
+		var names []string
+		for _, finding := range record.Findings {
+			names = append(names, finding.Name+":"+finding.Verdict)
+		}
+		return names
+	}
+
+	forms := fetch(t, server.URL+"/forms")
+	if len(forms.Forms) != 5 {
+		t.Fatalf("Expected 5 forms, got %d", len(forms.Forms))
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		login := forms.Forms[0]
+		if login.ID != "login" || login.Method != "POST" || login.Action != server.URL+"/app/session" {
+			t.Errorf("Unexpected login form: %+v", login)
+		}
+		if !login.Login || login.PasswordFields != 1 || !login.CSRFToken || login.CrossOrigin {
+			t.Errorf("Unexpected login form: %+v", login)
+		}
+		// The test server is plain HTTP, so the credentials are posted insecurely
+		if !login.InsecureAction {
+			t.Error("Expected the action to be flagged as insecure")
+		}
+		if got, want := findings(login), []string{"action:fail"}; !slices.Equal(got, want) {
+			t.Errorf("Expected findings %v, got %v", want, got)
+		}
+	})
+
+	t.Run("DefaultsToGETAndPageURL", func(t *testing.T) {
+		search := forms.Forms[1]
+		if search.Name != "search" || search.Method != "GET" || search.Action != server.URL+"/forms" {
+			t.Errorf("Unexpected search form: %+v", search)
+		}
+		if search.Login || len(search.Findings) != 0 {
+			t.Errorf("Expected no findings, got %v", findings(search))
+		}
+	})
+
+	t.Run("CredentialsWithGETCrossOrigin", func(t *testing.T) {
+		signin := forms.Forms[2]
+		if !signin.CrossOrigin || !slices.Equal(signin.PasswordAutocomplete, []string{"off"}) {
+			t.Errorf("Unexpected sign-in form: %+v", signin)
+		}
+		want := []string{"action:fail", "action:warn", "method:fail", "autocomplete:warn"}
+		if got := findings(signin); !slices.Equal(got, want) {
+			t.Errorf("Expected findings %v, got %v", want, got)
+		}
+	})
+
+	t.Run("MissingCSRFToken", func(t *testing.T) {
+		newsletter := forms.Forms[3]
+		if newsletter.CSRFToken {
+			t.Error("Expected no CSRF token")
+		}
+		if !slices.Contains(findings(newsletter), "csrf:warn") {
+			t.Errorf("Expected a CSRF finding, got %v", findings(newsletter))
+		}
+	})
+
+	t.Run("DialogNeverSubmits", func(t *testing.T) {
+		if dialog := forms.Forms[4]; dialog.Method != "DIALOG" || len(dialog.Findings) != 0 {
+			t.Errorf("Unexpected dialog form: %+v", dialog)
+		}
+	})
+
+	t.Run("PasswordOutsideForms", func(t *testing.T) {
+		if forms.PasswordFieldsOutsideForms != 1 || len(forms.Findings) != 1 {
+			t.Errorf("Expected 1 password field outside forms with a finding, got %d and %v",
+				forms.PasswordFieldsOutsideForms, forms.Findings)
+		}
+	})
+
+	t.Run("NoForms", func(t *testing.T) {
+		clean := fetch(t, server.URL+"/clean")
+		if len(clean.Forms) != 0 || clean.PasswordFieldsOutsideForms != 0 || len(clean.Findings) != 0 {
+			t.Errorf("Expected an empty audit, got %+v", clean)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package analyzer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestMixedContent tests detecting subresources HTTPS pages load over plain HTTP
+func TestMixedContent(t *testing.T) {
+	pages := map[string]string{
+		"/mixed": `<html><head>
+			<link rel="stylesheet" href="http://cdn.example.com/site.css">
+			<link rel="icon" href="http://cdn.example.com/favicon.ico">
+			<link rel="canonical" href="http://example.com/">
+			<script src="http://cdn.example.com/app.js"></script>
+			<script src="https://cdn.example.com/safe.js"></script>
+			<style>@import url("http://cdn.example.com/more.css"); body { background: url(http://cdn.example.com/bg.png) }</style>
+		</head><body>
+			<img src="/logo.png" srcset="http://cdn.example.com/a.png 1x, https://cdn.example.com/b.png 2x">
+			<video src="http://media.example.com/clip.mp4" poster="http://media.example.com/poster.jpg"></video>
+			<iframe src="http://widgets.example.com/"></iframe>
+			<div style="background-image: url('http://cdn.example.com/div.png')"></div>
+			<form action="http://example.com/login"><input type="image" src="http://cdn.example.com/go.png"></form>
+			<a href="http://example.com/">Plain link</a>
+		</body></html>`,
+		"/passive": `<html><body><img src="http://cdn.example.com/a.png"><img src="//cdn.example.com/b.png"></body></html>`,
+		"/clean":   `<html><body><img src="https://cdn.example.com/a.png"></body></html>`,
+		"/base":    `<html><head><base href="http://cdn.example.com/"><script src="app.js"></script></head><body></body></html>`,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Path]))
+	})
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	fetch := func(t *testing.T, url string) models.MixedContentAnalysis {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), url)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		return page.Security.MixedContent
+	}
+	verdict := func(analysis models.MixedContentAnalysis) string {
+		if len(analysis.Checks) != 1 {
+			return ""
+		}
+		return analysis.Checks[0].Verdict
+	}
+
+	t.Run("ActiveAndPassive", func(t *testing.T) {
+		mixed := fetch(t, server.URL+"/mixed")
+		type item = struct{ url, element, attribute, kind string }
+		want := []item{
+			{"http://cdn.example.com/site.css", "link", "href", models.MixedContentActive},
+			{"http://cdn.example.com/favicon.ico", "link", "href", models.MixedContentPassive},
+			{"http://cdn.example.com/app.js", "script", "src", models.MixedContentActive},
+			{"http://cdn.example.com/more.css", "style", "url()", models.MixedContentActive},
+			{"http://cdn.example.com/bg.png", "style", "url()", models.MixedContentPassive},
+			{"http://cdn.example.com/a.png", "img", "srcset", models.MixedContentPassive},
+			{"http://media.example.com/clip.mp4", "video", "src", models.MixedContentPassive},
+			{"http://media.example.com/poster.jpg", "video", "poster", models.MixedContentPassive},
+			{"http://widgets.example.com/", "iframe", "src", models.MixedContentActive},
+			{"http://cdn.example.com/div.png", "div", "style", models.MixedContentPassive},
+			{"http://example.com/login", "form", "action", models.MixedContentActive},
+			{"http://cdn.example.com/go.png", "input", "src", models.MixedContentPassive},
+		}
+		if len(mixed.Items) != len(want) {
+			t.Fatalf("Expected %d items, got %+v", len(want), mixed.Items)
+		}
+		for i, got := range mixed.Items {
+			if (item{got.URL, got.Element, got.Attribute, got.Type}) != want[i] {
+				t.Errorf("Expected item %d to be %+v, got %+v", i, want[i], got)
+			}
+		}
+		if mixed.Active != 5 || mixed.Passive != 7 || verdict(mixed) != models.VerdictFail {
+			t.Errorf("Expected 5 active and 7 passive items failing the check, got %d, %d, %+v",
+				mixed.Active, mixed.Passive, mixed.Checks)
+		}
+	})
+
+	t.Run("PassiveOnly", func(t *testing.T) {
+		mixed := fetch(t, server.URL+"/passive")
+		if mixed.Active != 0 || mixed.Passive != 1 || verdict(mixed) != models.VerdictWarn {
+			t.Errorf("Expected one passive item and a warning, got %+v", mixed)
+		}
+	})
+
+	t.Run("InsecureBase", func(t *testing.T) {
+		mixed := fetch(t, server.URL+"/base")
+		if len(mixed.Items) != 1 || mixed.Items[0].URL != "http://cdn.example.com/app.js" || mixed.Active != 1 {
+			t.Errorf("Expected the relative script to resolve to plain HTTP, got %+v", mixed.Items)
+		}
+	})
+
+	t.Run("Grade", func(t *testing.T) {
+		mixed, err := a.FetchPage(context.Background(), server.URL+"/mixed")
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		clean, err := a.FetchPage(context.Background(), server.URL+"/clean")
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		if mixed.Security.Score >= clean.Security.Score {
+			t.Errorf("Expected mixed content to lower the score, got %d vs %d", mixed.Security.Score, clean.Security.Score)
+		}
+	})
+
+	t.Run("PlainHTTP", func(t *testing.T) {
+		plain := httptest.NewServer(handler)
+		defer plain.Close()
+
+		mixed := fetch(t, plain.URL+"/mixed")
+		if len(mixed.Items) != 0 || len(mixed.Checks) != 0 {
+			t.Errorf("Expected no mixed content analysis for plain HTTP, got %+v", mixed)
+		}
+	})
+}
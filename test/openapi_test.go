@@ -0,0 +1,111 @@
+package analyzer_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestOpenAPI tests that the OpenAPI specification documents every
+// versioned route and the schemas of its models
+func TestOpenAPI(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newTestAPIServer(t, idp)
+	handler := server.Handler()
+
+	w := apiRequest(t, handler, http.MethodGet, "/api/v1/openapi.json", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var spec struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string                   `json:"required"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to decode specification: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	t.Run("Routes", func(t *testing.T) {
+		param := regexp.MustCompile(`:([A-Za-z_]+)`)
+		for _, route := range handler.(*gin.Engine).Routes() {
+			if !strings.HasPrefix(route.Path, "/api/v1/") || route.Path == "/api/v1/openapi.json" {
+				continue
+			}
+			path := param.ReplaceAllString(route.Path, "{$1}")
+			if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+				t.Errorf("Route %s %s is not documented", route.Method, route.Path)
+			}
+		}
+	})
+
+	t.Run("Schemas", func(t *testing.T) {
+		request, ok := spec.Components.Schemas["AnalysisRequest"]
+		if !ok || !slices.Contains(request.Required, "url") {
+			t.Errorf("Expected AnalysisRequest with required url, got %+v", request)
+		}
+		for _, name := range []string{"AnalysisResult", "ErrorResponse", "DeepAnalysisResult", "AnalysisListResponse"} {
+			if _, ok := spec.Components.Schemas[name]; !ok {
+				t.Errorf("Expected schema %s", name)
+			}
+		}
+		// Embedded structs are inlined
+		if _, ok := spec.Components.Schemas["UserOrganization"].Properties["name"]; !ok {
+			t.Errorf("Expected UserOrganization to include the organization fields")
+		}
+	})
+}
+
+// TestDeprecatedAliases tests that unversioned paths keep working but are
+// marked as deprecated
+func TestDeprecatedAliases(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newTestAPIServer(t, idp)
+	handler := server.Handler()
+
+	token := signingKey.sign(t, map[string]interface{}{
+		"sub":          "alice",
+		"iss":          idp.issuer,
+		"exp":          time.Now().Add(5 * time.Minute).Unix(),
+		"realm_access": map[string]interface{}{"roles": []string{"user"}},
+	})
+	alice := map[string]string{"Authorization": "Bearer " + token}
+
+	w := apiRequest(t, handler, http.MethodGet, "/api/user/analyses?limit=5", nil, alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on the deprecated path, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Link") != `</api/v1/user/analyses>; rel="successor-version"` {
+		t.Errorf("Unexpected deprecation headers: %v", w.Header())
+	}
+
+	w = apiRequest(t, handler, http.MethodGet, "/api/v1/user/analyses", nil, alice)
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+		t.Errorf("Expected the versioned path without deprecation, got %d %v", w.Code, w.Header())
+	}
+
+	// Errors use the shared error response
+	w = apiRequest(t, handler, http.MethodGet, "/api/v1/user/analyses", nil, nil)
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil || errResp.StatusCode != http.StatusUnauthorized || errResp.Message == "" {
+		t.Errorf("Expected a 401 error response, got %d: %s", w.Code, w.Body.String())
+	}
+}
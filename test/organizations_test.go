@@ -0,0 +1,151 @@
+package analyzer_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestOrganizations tests sharing analyses through organization projects and
+// the member role gates
+func TestOrganizations(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newTestAPIServer(t, idp)
+	handler := server.Handler()
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Shared</title></head><body><h1>Shared</h1></body></html>`))
+	}))
+	defer page.Close()
+
+	as := func(userID string) map[string]string {
+		token := signingKey.sign(t, map[string]interface{}{
+			"sub":          userID,
+			"iss":          idp.issuer,
+			"exp":          time.Now().Add(5 * time.Minute).Unix(),
+			"realm_access": map[string]interface{}{"roles": []string{"user"}},
+		})
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+	alice, bob, carol := as("alice"), as("bob"), as("carol")
+
+	decode := func(w *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	expect := func(w *httptest.ResponseRecorder, status int, what string) {
+		t.Helper()
+		if w.Code != status {
+			t.Fatalf("Expected status %d %s, got %d: %s", status, what, w.Code, w.Body.String())
+		}
+	}
+
+	// Alice creates an organization with a project
+	w := apiRequest(t, handler, http.MethodPost, "/api/orgs", models.OrganizationRequest{Name: "Acme"}, alice)
+	expect(w, http.StatusCreated, "creating organization")
+	var org models.UserOrganization
+	decode(w, &org)
+	if org.Role != models.RoleOwner {
+		t.Fatalf("Expected creator to be owner, got %q", org.Role)
+	}
+	orgPath := "/api/orgs/" + org.ID.Hex()
+
+	w = apiRequest(t, handler, http.MethodPost, orgPath+"/projects", models.ProjectRequest{Name: "Website"}, alice)
+	expect(w, http.StatusCreated, "creating project")
+	var project models.Project
+	decode(w, &project)
+	projectID := project.ID.Hex()
+
+	// Bob is not a member yet and cannot see the organization
+	expect(apiRequest(t, handler, http.MethodGet, orgPath+"/projects", nil, bob), http.StatusNotFound, "listing projects as non-member")
+
+	w = apiRequest(t, handler, http.MethodPut, orgPath+"/members/bob", models.MembershipRequest{Role: models.RoleViewer}, alice)
+	expect(w, http.StatusOK, "adding viewer")
+	expect(apiRequest(t, handler, http.MethodGet, orgPath+"/projects", nil, bob), http.StatusOK, "listing projects as viewer")
+
+	// Viewers cannot analyze into the project, analysts can
+	analyze := models.AnalysisRequest{URL: page.URL, ProjectID: projectID}
+	expect(apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, bob), http.StatusForbidden, "analyzing as viewer")
+	expect(apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, carol), http.StatusNotFound, "analyzing as non-member")
+
+	w = apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, alice)
+	expect(w, http.StatusOK, "analyzing as owner")
+	var analysis models.AnalysisResult
+	decode(w, &analysis)
+	if analysis.ProjectID != projectID {
+		t.Fatalf("Expected analysis in project %s, got %q", projectID, analysis.ProjectID)
+	}
+	analysisPath := "/api/analysis/" + analysis.ID.Hex()
+
+	t.Run("Read", func(t *testing.T) {
+		expect(apiRequest(t, handler, http.MethodGet, analysisPath, nil, bob), http.StatusOK, "reading as viewer")
+		expect(apiRequest(t, handler, http.MethodGet, analysisPath, nil, carol), http.StatusForbidden, "reading as non-member")
+		expect(apiRequest(t, handler, http.MethodGet, analysisPath+"/deep/versions", nil, carol), http.StatusForbidden, "listing deep versions as non-member")
+	})
+
+	t.Run("Listings", func(t *testing.T) {
+		count := func(headers map[string]string, path string) int {
+			t.Helper()
+			w := apiRequest(t, handler, http.MethodGet, path, nil, headers)
+			expect(w, http.StatusOK, "listing "+path)
+			var resp struct {
+				Count int `json:"count"`
+			}
+			decode(w, &resp)
+			return resp.Count
+		}
+
+		if n := count(bob, "/api/analyses?project_id="+projectID); n != 1 {
+			t.Errorf("Expected 1 project analysis, got %d", n)
+		}
+		// Project analyses are not personal, not even for their creator
+		if n := count(alice, "/api/user/analyses"); n != 0 {
+			t.Errorf("Expected no personal analyses, got %d", n)
+		}
+		if n := count(bob, "/api/analyses"); n != 0 {
+			t.Errorf("Expected no analyses outside the project, got %d", n)
+		}
+		expect(apiRequest(t, handler, http.MethodGet, "/api/analyses?project_id="+projectID, nil, carol), http.StatusNotFound, "listing project as non-member")
+		expect(apiRequest(t, handler, http.MethodGet, "/api/search?q=shared&project_id="+projectID, nil, carol), http.StatusNotFound, "searching project as non-member")
+	})
+
+	t.Run("Members", func(t *testing.T) {
+		expect(apiRequest(t, handler, http.MethodPut, orgPath+"/members/carol", models.MembershipRequest{Role: models.RoleViewer}, bob),
+			http.StatusForbidden, "adding member as viewer")
+		expect(apiRequest(t, handler, http.MethodPut, orgPath+"/members/bob", models.MembershipRequest{Role: "admin"}, alice),
+			http.StatusBadRequest, "setting unknown role")
+		expect(apiRequest(t, handler, http.MethodDelete, orgPath+"/members/alice", nil, alice),
+			http.StatusConflict, "removing the last owner")
+
+		w := apiRequest(t, handler, http.MethodGet, orgPath+"/members", nil, bob)
+		expect(w, http.StatusOK, "listing members")
+		var members struct {
+			Members []models.Membership `json:"members"`
+		}
+		decode(w, &members)
+		if len(members.Members) != 2 {
+			t.Errorf("Expected 2 members, got %d", len(members.Members))
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		expect(apiRequest(t, handler, http.MethodDelete, analysisPath, nil, bob), http.StatusForbidden, "deleting as viewer")
+
+		w := apiRequest(t, handler, http.MethodPut, orgPath+"/members/bob", models.MembershipRequest{Role: models.RoleAnalyst}, alice)
+		expect(w, http.StatusOK, "promoting to analyst")
+		expect(apiRequest(t, handler, http.MethodDelete, analysisPath, nil, bob), http.StatusOK, "deleting as analyst")
+	})
+
+	t.Run("Leave", func(t *testing.T) {
+		expect(apiRequest(t, handler, http.MethodDelete, orgPath+"/members/bob", nil, bob), http.StatusOK, "leaving the organization")
+		expect(apiRequest(t, handler, http.MethodGet, orgPath+"/members", nil, bob), http.StatusNotFound, "listing members after leaving")
+	})
+}
@@ -0,0 +1,237 @@
+package analyzer_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestRateLimit tests that callers are throttled per route with their own
+// token buckets
+func TestRateLimit(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newConfiguredTestAPIServer(t, idp, func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{
+			Enabled: true,
+			Default: config.RateLimit{Rate: 100, Burst: 100},
+			Routes: map[string]config.RateLimit{
+				"GET /api/v1/user/analyses": {Rate: 0.001, Burst: 2},
+			},
+		}
+	})
+	handler := server.Handler()
+
+	as := func(userID string) map[string]string {
+		token := signingKey.sign(t, map[string]interface{}{
+			"sub":          userID,
+			"iss":          idp.issuer,
+			"exp":          time.Now().Add(5 * time.Minute).Unix(),
+			"realm_access": map[string]interface{}{"roles": []string{"user"}},
+		})
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+	alice, bob := as("alice"), as("bob")
+
+	// The deprecated unversioned path shares the bucket of its successor
+	for i, path := range []string{"/api/v1/user/analyses", "/api/user/analyses"} {
+		w := apiRequest(t, handler, http.MethodGet, path, nil, alice)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 within the burst, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != strconv.Itoa(1-i) {
+			t.Errorf("Expected %d requests remaining, got %q", 1-i, got)
+		}
+	}
+
+	w := apiRequest(t, handler, http.MethodGet, "/api/user/analyses", nil, alice)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 after the burst, got %d", w.Code)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Reset") == "" {
+		t.Errorf("Unexpected rate limit headers: %v", w.Header())
+	}
+
+	// Other callers and routes have their own buckets
+	if w := apiRequest(t, handler, http.MethodGet, "/api/user/analyses", nil, bob); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for another user, got %d", w.Code)
+	}
+	if w := apiRequest(t, handler, http.MethodGet, "/api/analyses", nil, alice); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 on another route, got %d", w.Code)
+	}
+}
+
+// TestQuotas tests the daily analysis quotas of anonymous callers and
+// users on different plans
+func TestQuotas(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	server := newConfiguredTestAPIServer(t, idp, func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{
+			Enabled: true,
+			Default: config.RateLimit{Rate: 100, Burst: 100},
+			Plans: map[string]config.QuotaPlan{
+				config.AnonymousPlan: {Daily: 1, Monthly: 10},
+				"free":               {Daily: 2, Monthly: 10},
+				"unlimited":          {},
+			},
+			DefaultPlan: "free",
+		}
+	})
+	handler := server.Handler()
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Quota</title></head><body></body></html>`))
+	}))
+	defer page.Close()
+
+	as := func(userID string, roles ...string) map[string]string {
+		token := signingKey.sign(t, map[string]interface{}{
+			"sub":          userID,
+			"iss":          idp.issuer,
+			"exp":          time.Now().Add(5 * time.Minute).Unix(),
+			"realm_access": map[string]interface{}{"roles": roles},
+		})
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+	alice, admin := as("alice", "user"), as("root", "admin")
+	analyze := models.AnalysisRequest{URL: page.URL}
+
+	t.Run("Anonymous", func(t *testing.T) {
+		w := apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Quota-Limit-Day") != "1" || w.Header().Get("X-Quota-Remaining-Day") != "0" {
+			t.Errorf("Unexpected quota headers: %v", w.Header())
+		}
+
+		w = apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, nil)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status 429 over quota, got %d", w.Code)
+		}
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		if err != nil || retryAfter < 1 || retryAfter > 24*60*60 {
+			t.Errorf("Expected Retry-After until the end of the day, got %q", w.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("User", func(t *testing.T) {
+		// Users have their own quota, not the anonymous one of their IP
+		for i := 0; i < 2; i++ {
+			if w := apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, alice); w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200 within quota, got %d: %s", w.Code, w.Body.String())
+			}
+		}
+		if w := apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, alice); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status 429 over quota, got %d", w.Code)
+		}
+
+		w := apiRequest(t, handler, http.MethodGet, "/api/user/quota", nil, alice)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var status models.QuotaStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if status.Plan != "free" || status.Daily.Used != 3 || status.Daily.Limit != 2 || status.Monthly.Limit != 10 {
+			t.Errorf("Unexpected quota status: %+v", status)
+		}
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		if w := apiRequest(t, handler, http.MethodPut, "/api/admin/users/alice/plan", models.PlanRequest{Plan: "gold"}, admin); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for an unknown plan, got %d", w.Code)
+		}
+		if w := apiRequest(t, handler, http.MethodPut, "/api/admin/users/alice/plan", models.PlanRequest{Plan: "unlimited"}, alice); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for non-admin, got %d", w.Code)
+		}
+
+		w := apiRequest(t, handler, http.MethodPut, "/api/admin/users/alice/plan", models.PlanRequest{Plan: "unlimited"}, admin)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 setting plan, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := apiRequest(t, handler, http.MethodPost, "/api/analyze", analyze, alice); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 on the unlimited plan, got %d", w.Code)
+		}
+	})
+}
+
+// TestRateLimitClientIP tests that anonymous callers are limited by their
+// remote address unless X-Forwarded-For comes from a trusted proxy
+func TestRateLimitClientIP(t *testing.T) {
+	signingKey := newRSASigningKey(t, "key-1")
+	idp := newStubIdentityProvider(t, signingKey)
+	newHandler := func(trustedProxies []string) http.Handler {
+		return newConfiguredTestAPIServer(t, idp, func(cfg *config.Config) {
+			cfg.Server.TrustedProxies = trustedProxies
+			cfg.RateLimit = config.RateLimitConfig{
+				Enabled: true,
+				Default: config.RateLimit{Rate: 100, Burst: 100},
+				Routes: map[string]config.RateLimit{
+					"POST /api/v1/analyze": {Rate: 0.001, Burst: 1},
+				},
+			}
+		}).Handler()
+	}
+	// Requests without a body are rejected, but only after being counted
+	analyze := func(handler http.Handler, forwardedFor string) int {
+		return apiRequest(t, handler, http.MethodPost, "/api/v1/analyze", nil,
+			map[string]string{"X-Forwarded-For": forwardedFor}).Code
+	}
+
+	t.Run("SpoofedHeader", func(t *testing.T) {
+		handler := newHandler(nil)
+		if code := analyze(handler, "9.9.9.1"); code == http.StatusTooManyRequests {
+			t.Fatalf("Expected the first request to pass the limiter, got %d", code)
+		}
+		if code := analyze(handler, "9.9.9.2"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected a spoofed X-Forwarded-For to share the bucket, got %d", code)
+		}
+	})
+
+	t.Run("TrustedProxy", func(t *testing.T) {
+		// httptest requests come from 192.0.2.1
+		handler := newHandler([]string{"192.0.2.0/24"})
+		for _, ip := range []string{"9.9.9.1", "9.9.9.2"} {
+			if code := analyze(handler, ip); code == http.StatusTooManyRequests {
+				t.Errorf("Expected %s behind a trusted proxy to have its own bucket, got %d", ip, code)
+			}
+		}
+		if code := analyze(handler, "9.9.9.1"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected status 429 for a repeated client, got %d", code)
+		}
+	})
+}
+
+// TestRouteRateLimitConfig tests that route limits keyed by a deprecated
+// /api path apply to its /api/v1 successor
+func TestRouteRateLimitConfig(t *testing.T) {
+	t.Setenv("RATE_LIMIT_ROUTES", "post /api/analyze=10/m:5; GET /api/v1/search=60/m:20;GET /api=1/s")
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	want := map[string]config.RateLimit{
+		"POST /api/v1/analyze": {Rate: 10.0 / 60, Burst: 5},
+		"GET /api/v1/search":   {Rate: 1, Burst: 20},
+		"GET /api":             {Rate: 1, Burst: 1},
+	}
+	if len(cfg.RateLimit.Routes) != len(want) {
+		t.Errorf("Expected routes %v, got %v", want, cfg.RateLimit.Routes)
+	}
+	for route, limit := range want {
+		if got, ok := cfg.RateLimit.Routes[route]; !ok || got != limit {
+			t.Errorf("%s: expected %+v, got %+v", route, limit, got)
+		}
+	}
+}
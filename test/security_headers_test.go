@@ -0,0 +1,116 @@
+package analyzer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestSecurityHeaders tests the security header audit and grade of a deep analysis
+func TestSecurityHeaders(t *testing.T) {
+	hardened := map[string]string{
+		"Content-Security-Policy":      "default-src 'self'; frame-ancestors 'none'",
+		"X-Content-Type-Options":       "nosniff",
+		"Referrer-Policy":              "no-referrer, strict-origin-when-cross-origin",
+		"Permissions-Policy":           "camera=(), geolocation=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": `require-corp; report-to="coep"`,
+		"Cross-Origin-Resource-Policy": "same-origin",
+		"Cache-Control":                "no-store",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/hardened":
+			for k, v := range hardened {
+				w.Header().Set(k, v)
+			}
+			w.Write([]byte(`<html><body><form><input type="password" name="password"></form></body></html>`))
+		case "/weak":
+			w.Header().Set("X-Frame-Options", "ALLOW-FROM https://example.com")
+			w.Header().Set("Referrer-Policy", "unsafe-url")
+			w.Header().Set("X-XSS-Protection", "1; mode=block")
+			w.Header().Set("Cross-Origin-Opener-Policy", "unsafe-none")
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			w.Write([]byte(`<html><body><p>Weak</p></body></html>`))
+		default:
+			w.Write([]byte(`<html><body><p>Plain</p></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	audit := func(t *testing.T, path string) (map[string]models.SecurityCheck, int, string) {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		checks := map[string]models.SecurityCheck{}
+		for _, check := range page.Security.Headers {
+			if check.Explanation == "" {
+				t.Errorf("Expected an explanation for %s", check.Name)
+			}
+			checks[check.Name] = check
+		}
+		return checks, page.Security.Score, page.Security.Grade
+	}
+	expect := func(t *testing.T, checks map[string]models.SecurityCheck, verdicts map[string]string) {
+		t.Helper()
+		for name, verdict := range verdicts {
+			if got := checks[name]; got.Verdict != verdict {
+				t.Errorf("Expected %s to %s, got %+v", name, verdict, got)
+			}
+		}
+	}
+
+	t.Run("Hardened", func(t *testing.T) {
+		checks, score, grade := audit(t, "/hardened")
+		expect(t, checks, map[string]string{
+			"Strict-Transport-Security":    models.VerdictFail, // plain HTTP
+			"Content-Security-Policy":      models.VerdictPass,
+			"X-Frame-Options":              models.VerdictPass, // via frame-ancestors
+			"X-Content-Type-Options":       models.VerdictPass,
+			"Referrer-Policy":              models.VerdictPass,
+			"Permissions-Policy":           models.VerdictPass,
+			"Cross-Origin-Opener-Policy":   models.VerdictPass,
+			"Cross-Origin-Embedder-Policy": models.VerdictPass,
+			"Cross-Origin-Resource-Policy": models.VerdictPass,
+			"Cache-Control":                models.VerdictPass,
+			"X-XSS-Protection":             models.VerdictPass,
+		})
+		if score != 81 || grade != "B" {
+			t.Errorf("Expected score 81 and grade B without HTTPS, got %d %s", score, grade)
+		}
+	})
+
+	t.Run("Weak", func(t *testing.T) {
+		checks, _, grade := audit(t, "/weak")
+		expect(t, checks, map[string]string{
+			"Content-Security-Policy":    models.VerdictFail,
+			"X-Frame-Options":            models.VerdictWarn,
+			"X-Content-Type-Options":     models.VerdictFail,
+			"Referrer-Policy":            models.VerdictFail,
+			"Permissions-Policy":         models.VerdictWarn,
+			"Cross-Origin-Opener-Policy": models.VerdictWarn,
+			"Cache-Control":              models.VerdictFail, // sets a cookie
+			"X-XSS-Protection":           models.VerdictWarn,
+		})
+		if grade != "F" {
+			t.Errorf("Expected grade F, got %s", grade)
+		}
+	})
+
+	t.Run("NotSensitive", func(t *testing.T) {
+		checks, _, _ := audit(t, "/plain")
+		expect(t, checks, map[string]string{
+			"Cache-Control":   models.VerdictPass,
+			"Referrer-Policy": models.VerdictWarn,
+			"X-Frame-Options": models.VerdictFail,
+		})
+	})
+}
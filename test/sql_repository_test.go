@@ -0,0 +1,499 @@
+package analyzer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+	"webPageAnalyzerGO/internal/repository"
+	"webPageAnalyzerGO/internal/retention"
+)
+
+// newTestSQLRepository creates a SQLite-backed repository in a temporary directory
+func newTestSQLRepository(t *testing.T) *repository.SQLRepository {
+	t.Helper()
+
+	cfg := config.SQLConfig{
+		DSN:     "file:" + filepath.Join(t.TempDir(), "analyzer.db"),
+		Timeout: 5 * time.Second,
+	}
+
+	repo, err := repository.NewSQLRepository(context.Background(), "sqlite", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close(context.Background()) })
+
+	return repo
+}
+
+// TestSQLRepositoryAnalyses tests saving and loading analyses with SQLite
+func TestSQLRepositoryAnalyses(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx := context.Background()
+
+	first := mockAnalysisResult()
+	first.UserID = "user-1"
+	first.CreatedAt = time.Now().Add(-time.Hour)
+	if err := repo.SaveAnalysis(ctx, first); err != nil {
+		t.Fatalf("Expected no error saving analysis, got %v", err)
+	}
+	if first.ID.IsZero() {
+		t.Fatal("Expected ID to be assigned on save")
+	}
+
+	second := mockAnalysisResult()
+	second.URL = "https://example.org/page"
+	if err := repo.SaveAnalysis(ctx, second); err != nil {
+		t.Fatalf("Expected no error saving analysis, got %v", err)
+	}
+
+	t.Run("GetAnalysis", func(t *testing.T) {
+		result, err := repo.GetAnalysis(ctx, first.ID.Hex())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result == nil {
+			t.Fatal("Expected analysis, got nil")
+		}
+		if result.URL != first.URL || result.Title != first.Title || result.UserID != "user-1" {
+			t.Errorf("Unexpected analysis: %+v", result)
+		}
+		if result.Headings != first.Headings {
+			t.Errorf("Expected headings %+v, got %+v", first.Headings, result.Headings)
+		}
+		if result.ExternalLinks != first.ExternalLinks {
+			t.Errorf("Expected external links %+v, got %+v", first.ExternalLinks, result.ExternalLinks)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		result, err := repo.GetAnalysis(ctx, primitive.NewObjectID().Hex())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result != nil {
+			t.Errorf("Expected nil result, got %+v", result)
+		}
+	})
+
+	t.Run("RecentAnalyses", func(t *testing.T) {
+		results, err := repo.GetRecentAnalyses(ctx, 10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 analyses, got %d", len(results))
+		}
+		if results[0].ID != second.ID {
+			t.Errorf("Expected newest analysis first, got %s", results[0].URL)
+		}
+	})
+
+	t.Run("UserAnalyses", func(t *testing.T) {
+		results, err := repo.GetUserAnalyses(ctx, "user-1", 10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(results) != 1 || results[0].ID != first.ID {
+			t.Errorf("Expected only the user's analysis, got %d results", len(results))
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		stats, err := repo.GetStats(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stats.TotalAnalyses != 2 || stats.UniqueURLs != 2 || stats.RegisteredUsers != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if stats.AnalysesLast24h != 2 {
+			t.Errorf("Expected 2 analyses in the last 24h, got %d", stats.AnalysesLast24h)
+		}
+	})
+}
+
+// TestSQLRepositoryDeepAnalysis tests deep analysis versioning with SQLite
+func TestSQLRepositoryDeepAnalysis(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx := context.Background()
+
+	analysis := mockAnalysisResult()
+	if err := repo.SaveAnalysis(ctx, analysis); err != nil {
+		t.Fatalf("Expected no error saving analysis, got %v", err)
+	}
+
+	deep := &models.DeepAnalysisResult{
+		AnalysisID: analysis.ID,
+		URL:        analysis.URL,
+		Performance: models.PerformanceMetrics{
+			LoadTime: 1.5,
+			Requests: 3,
+		},
+		Technology: models.TechnologyAnalysis{
+			Server:     "nginx",
+			Frameworks: []string{"react"},
+		},
+		Links: models.LinkAnalysis{
+			AnchorText: map[string]int{"Home": 2},
+		},
+		Privacy: models.PrivacyAnalysis{
+			Categories: map[string]int{models.TrackerAnalytics: 1},
+		},
+	}
+	if err := repo.SaveDeepAnalysis(ctx, deep); err != nil {
+		t.Fatalf("Expected no error saving deep analysis, got %v", err)
+	}
+
+	result, err := repo.GetDeepAnalysis(ctx, analysis.ID.Hex())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result == nil {
+		t.Fatal("Expected deep analysis, got nil")
+	}
+	if result.Performance.LoadTime != 1.5 || result.Technology.Server != "nginx" || result.Links.AnchorText["Home"] != 2 ||
+		result.Privacy.Categories[models.TrackerAnalytics] != 1 {
+		t.Errorf("Unexpected deep analysis: %+v", result)
+	}
+	if result.Version != 1 {
+		t.Errorf("Expected version 1, got %d", result.Version)
+	}
+
+	// Saving again for the same analysis stores a new version
+	deep.ID = primitive.NewObjectID()
+	deep.Technology.Server = "apache"
+	deep.AnalyzerVersion = "2.0.0"
+	if err := repo.SaveDeepAnalysis(ctx, deep); err != nil {
+		t.Fatalf("Expected no error saving second deep analysis, got %v", err)
+	}
+	if deep.Version != 2 {
+		t.Errorf("Expected version 2 to be assigned, got %d", deep.Version)
+	}
+
+	result, err = repo.GetDeepAnalysis(ctx, analysis.ID.Hex())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Technology.Server != "apache" || result.Version != 2 || result.AnalyzerVersion != "2.0.0" {
+		t.Errorf("Expected latest deep analysis, got %+v", result)
+	}
+
+	// Earlier versions remain available
+	first, err := repo.GetDeepAnalysisVersion(ctx, analysis.ID.Hex(), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first == nil || first.Technology.Server != "nginx" {
+		t.Errorf("Expected first version to be kept, got %+v", first)
+	}
+
+	missing, err := repo.GetDeepAnalysisVersion(ctx, analysis.ID.Hex(), 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if missing != nil {
+		t.Errorf("Expected nil for unknown version, got %+v", missing)
+	}
+
+	versions, err := repo.ListDeepAnalysisVersions(ctx, analysis.ID.Hex())
+	if err != nil {
+		t.Fatalf("Expected no error listing versions, got %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+		t.Fatalf("Expected versions [2 1], got %+v", versions)
+	}
+	if versions[0].AnalyzerVersion != "2.0.0" || versions[0].AnalysisID != analysis.ID {
+		t.Errorf("Unexpected version summary: %+v", versions[0])
+	}
+}
+
+// TestSQLRepositoryListAnalyses tests cursor pagination and filters with SQLite
+func TestSQLRepositoryListAnalyses(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		analysis := mockAnalysisResult()
+		analysis.URL = fmt.Sprintf("https://example.com/page%d", i)
+		analysis.UserID = "user-1"
+		analysis.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		analysis.HasLoginForm = i%2 == 0
+		analysis.InternalLinks.Inaccessible = 0
+		analysis.ExternalLinks.Inaccessible = i % 2
+		if err := repo.SaveAnalysis(ctx, analysis); err != nil {
+			t.Fatalf("Expected no error saving analysis, got %v", err)
+		}
+	}
+	other := mockAnalysisResult()
+	other.URL = "https://www.example.org/"
+	other.UserID = "user-2"
+	if err := repo.SaveAnalysis(ctx, other); err != nil {
+		t.Fatalf("Expected no error saving analysis, got %v", err)
+	}
+
+	t.Run("PaginatesWithCursor", func(t *testing.T) {
+		query := models.AnalysisQuery{Filter: models.AnalysisFilter{UserID: "user-1"}, Limit: 2}
+		var urls []string
+		for pages := 0; pages < 5; pages++ {
+			page, err := repo.ListAnalyses(ctx, query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for _, a := range page.Analyses {
+				urls = append(urls, a.URL)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		expected := []string{
+			"https://example.com/page4",
+			"https://example.com/page3",
+			"https://example.com/page2",
+			"https://example.com/page1",
+			"https://example.com/page0",
+		}
+		if strings.Join(urls, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v, got %v", expected, urls)
+		}
+	})
+
+	t.Run("SortsByURL", func(t *testing.T) {
+		page, err := repo.ListAnalyses(ctx, models.AnalysisQuery{Sort: models.SortURLAsc, Limit: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(page.Analyses) != 1 || page.Analyses[0].URL != "https://example.com/page0" {
+			t.Errorf("Unexpected first page: %+v", page.Analyses)
+		}
+
+		_, err = repo.ListAnalyses(ctx, models.AnalysisQuery{Sort: models.SortCreatedAtDesc, Cursor: page.NextCursor})
+		if !errors.Is(err, repository.ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for a cursor of another sort, got %v", err)
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		yes, no := true, false
+		tests := []struct {
+			name     string
+			filter   models.AnalysisFilter
+			expected int
+		}{
+			{"Domain", models.AnalysisFilter{Domain: "www.example.org"}, 1},
+			{"URLPrefix", models.AnalysisFilter{URLPrefix: "https://example.com/page"}, 5},
+			{"HasLoginForm", models.AnalysisFilter{HasLoginForm: &yes}, 4},
+			{"HasBrokenLinks", models.AnalysisFilter{HasBrokenLinks: &yes, UserID: "user-1"}, 2},
+			{"NoBrokenLinks", models.AnalysisFilter{HasBrokenLinks: &no, UserID: "user-1"}, 3},
+			{"DateRange", models.AnalysisFilter{From: base.Add(90 * time.Second), To: base.Add(210 * time.Second)}, 2},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				page, err := repo.ListAnalyses(ctx, models.AnalysisQuery{Filter: tt.filter, Limit: 100})
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if len(page.Analyses) != tt.expected {
+					t.Errorf("Expected %d analyses, got %d", tt.expected, len(page.Analyses))
+				}
+			})
+		}
+	})
+}
+
+// TestSQLRepositorySearch tests full-text search with SQLite FTS5
+func TestSQLRepositorySearch(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx := context.Background()
+
+	checkout := mockAnalysisResult()
+	checkout.URL = "https://shop.example.com/checkout"
+	checkout.Title = "Secure Checkout"
+	checkout.Description = "Pay for your order"
+	checkout.TextContent = "Review your cart and complete the checkout with AcmeWidget Pro."
+	checkout.UserID = "user-1"
+
+	blog := mockAnalysisResult()
+	blog.URL = "https://blog.example.com/post"
+	blog.Title = "Release notes"
+	blog.TextContent = "AcmeWidget Pro has been renamed. Checkout our new product line."
+	blog.UserID = "user-2"
+
+	for _, a := range []*models.AnalysisResult{checkout, blog} {
+		if err := repo.SaveAnalysis(ctx, a); err != nil {
+			t.Fatalf("Expected no error saving analysis, got %v", err)
+		}
+	}
+
+	t.Run("RanksTitleMatchesFirst", func(t *testing.T) {
+		hits, err := repo.SearchAnalyses(ctx, models.SearchQuery{Query: "checkout"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(hits) != 2 {
+			t.Fatalf("Expected 2 hits, got %d", len(hits))
+		}
+		if hits[0].Analysis.ID != checkout.ID {
+			t.Errorf("Expected title match to rank first, got %s", hits[0].Analysis.URL)
+		}
+		if hits[0].Highlights["title"] != "Secure <mark>Checkout</mark>" {
+			t.Errorf("Unexpected title highlight: %q", hits[0].Highlights["title"])
+		}
+	})
+
+	t.Run("RequiresAllTerms", func(t *testing.T) {
+		hits, err := repo.SearchAnalyses(ctx, models.SearchQuery{Query: "acmewidget renamed"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(hits) != 1 || hits[0].Analysis.ID != blog.ID {
+			t.Fatalf("Expected only the blog post, got %d hits", len(hits))
+		}
+		if !strings.Contains(hits[0].Highlights["text"], "<mark>renamed.</mark>") {
+			t.Errorf("Unexpected text highlight: %q", hits[0].Highlights["text"])
+		}
+	})
+
+	t.Run("ScopesToUser", func(t *testing.T) {
+		hits, err := repo.SearchAnalyses(ctx, models.SearchQuery{Query: "acmewidget", UserID: "user-1"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(hits) != 1 || hits[0].Analysis.ID != checkout.ID {
+			t.Errorf("Expected only user-1's analysis, got %d hits", len(hits))
+		}
+	})
+
+	t.Run("EmptyQuery", func(t *testing.T) {
+		if _, err := repo.SearchAnalyses(ctx, models.SearchQuery{Query: "  !! "}); !errors.Is(err, repository.ErrEmptySearch) {
+			t.Errorf("Expected ErrEmptySearch, got %v", err)
+		}
+	})
+}
+
+// TestSQLRepositoryDeletion tests cascading deletes and retention purging with SQLite
+func TestSQLRepositoryDeletion(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx := context.Background()
+
+	var analyses []*models.AnalysisResult
+	for i, userID := range []string{"user-1", "user-1", "user-2"} {
+		analysis := mockAnalysisResult()
+		analysis.URL = fmt.Sprintf("https://example.com/%d", i)
+		analysis.UserID = userID
+		analysis.CreatedAt = time.Now().Add(-time.Duration(i) * 48 * time.Hour)
+		if err := repo.SaveAnalysis(ctx, analysis); err != nil {
+			t.Fatalf("Expected no error saving analysis, got %v", err)
+		}
+		deep := &models.DeepAnalysisResult{AnalysisID: analysis.ID, URL: analysis.URL, CreatedAt: analysis.CreatedAt}
+		if err := repo.SaveDeepAnalysis(ctx, deep); err != nil {
+			t.Fatalf("Expected no error saving deep analysis, got %v", err)
+		}
+		analyses = append(analyses, analysis)
+	}
+
+	t.Run("DeleteAnalysisCascades", func(t *testing.T) {
+		deleted, err := repo.DeleteAnalysis(ctx, analyses[0].ID.Hex())
+		if err != nil || !deleted {
+			t.Fatalf("Expected analysis to be deleted, got %v, %v", deleted, err)
+		}
+		if result, _ := repo.GetAnalysis(ctx, analyses[0].ID.Hex()); result != nil {
+			t.Error("Expected analysis to be gone")
+		}
+		if deep, _ := repo.GetDeepAnalysis(ctx, analyses[0].ID.Hex()); deep != nil {
+			t.Error("Expected deep analysis to be deleted with its analysis")
+		}
+		if hits, _ := repo.SearchAnalyses(ctx, models.SearchQuery{Query: "example"}); len(hits) != 2 {
+			t.Errorf("Expected deleted analysis to leave the search index, got %d hits", len(hits))
+		}
+	})
+
+	t.Run("DeleteByFilter", func(t *testing.T) {
+		deleted, err := repo.DeleteAnalyses(ctx, models.AnalysisFilter{UserID: "user-2"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("Expected 1 deleted analysis, got %d", deleted)
+		}
+		if deep, _ := repo.GetDeepAnalysis(ctx, analyses[2].ID.Hex()); deep != nil {
+			t.Error("Expected deep analysis to be deleted with its analysis")
+		}
+		if result, _ := repo.GetAnalysis(ctx, analyses[1].ID.Hex()); result == nil {
+			t.Error("Expected other users' analyses to remain")
+		}
+	})
+
+	t.Run("ProjectAnalyses", func(t *testing.T) {
+		save := func(userID, projectID string) *models.AnalysisResult {
+			analysis := mockAnalysisResult()
+			analysis.UserID = userID
+			analysis.ProjectID = projectID
+			if err := repo.SaveAnalysis(ctx, analysis); err != nil {
+				t.Fatalf("Expected no error saving analysis, got %v", err)
+			}
+			return analysis
+		}
+		users := func() int {
+			t.Helper()
+			stats, err := repo.GetStats(ctx)
+			if err != nil {
+				t.Fatalf("Expected no error getting stats, got %v", err)
+			}
+			return stats.RegisteredUsers
+		}
+		before := users()
+		save("user-3", "")
+		kept := save("user-3", "project-1")
+		save("user-4", "")
+		save("user-4", "project-1")
+
+		// Deleting a user's data disowns their project analyses
+		if deleted, err := repo.DeleteAnalyses(ctx, models.AnalysisFilter{UserID: "user-3"}); err != nil || deleted != 1 {
+			t.Fatalf("Expected the personal analysis to be deleted, got %d, %v", deleted, err)
+		}
+		if disowned, err := repo.DisownProjectAnalyses(ctx, "user-3"); err != nil || disowned != 1 {
+			t.Fatalf("Expected the project analysis to be disowned, got %d, %v", disowned, err)
+		}
+		if result, _ := repo.GetAnalysis(ctx, kept.ID.Hex()); result == nil || result.UserID != "" || result.ProjectID != "project-1" {
+			t.Errorf("Expected the project analysis to stay without its user, got %+v", result)
+		}
+		// The disowned analysis counts as no user at all
+		if got := users(); got != before+1 {
+			t.Errorf("Expected %d users after disowning, got %d", before+1, got)
+		}
+
+		filter := models.AnalysisFilter{UserID: "user-4", AllProjects: true}
+		if deleted, err := repo.DeleteAnalyses(ctx, filter); err != nil || deleted != 2 {
+			t.Errorf("Expected personal and project analyses to be deleted, got %d, %v", deleted, err)
+		}
+	})
+
+	t.Run("RetentionPurge", func(t *testing.T) {
+		purger := retention.NewPurger(repo, config.RetentionConfig{DeepAnalysesMaxAge: 24 * time.Hour}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		if err := purger.PurgeOnce(ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if deep, _ := repo.GetDeepAnalysis(ctx, analyses[1].ID.Hex()); deep != nil {
+			t.Error("Expected expired deep analysis to be purged")
+		}
+		if result, _ := repo.GetAnalysis(ctx, analyses[1].ID.Hex()); result == nil {
+			t.Error("Expected analysis to be kept when only deep analyses expire")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package analyzer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestTechnologyFingerprinting tests detecting technologies, their versions
+// and the technologies they imply from the bundled fingerprints
+func TestTechnologyFingerprinting(t *testing.T) {
+	pages := map[string]string{
+		"/wordpress": `<html><head>
+			<meta name="generator" content="WordPress 6.4.2">
+			<link rel="stylesheet" href="/wp-content/themes/twenty/style.css">
+			<script src="/wp-includes/js/jquery/jquery.min.js"></script>
+			<script src="https://code.jquery.com/jquery-3.7.1.min.js"></script>
+		</head><body></body></html>`,
+		"/next": `<html><head></head><body><div id="__next"></div>
+			<script id="__NEXT_DATA__" type="application/json">{"props":{}}</script>
+			<script src="/_next/static/chunks/main.js"></script>
+		</body></html>`,
+		// Only substrings of framework names, which must not match
+		"/lookalikes": `<html><head>
+			<script src="/js/revenue.js"></script>
+			<script src="/js/reactions.js"></script>
+			<script>var revue = "angular momentum"; preview = true;</script>
+		</head><body></body></html>`,
+		"/globals": `<html><head><script>window.Vue = {}; var Shopify = Shopify || {};</script></head><body></body></html>`,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wordpress":
+			w.Header().Set("Server", "nginx/1.25.3")
+			w.Header().Set("X-Powered-By", "PHP/8.2.7")
+		case "/next":
+			w.Header().Set("X-Powered-By", "Next.js")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Path]))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	a := getTestAnalyzer()
+	fetch := func(t *testing.T, path string) models.TechnologyAnalysis {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		return models.TechnologyAnalysis{
+			CMS:          page.Technology.CMS,
+			Frameworks:   page.Technology.Frameworks,
+			Technologies: page.Technology.Technologies,
+		}
+	}
+	find := func(tech models.TechnologyAnalysis, name string) *models.Technology {
+		i := slices.IndexFunc(tech.Technologies, func(t models.Technology) bool { return t.Name == name })
+		if i < 0 {
+			return nil
+		}
+		return &tech.Technologies[i]
+	}
+	type expectation struct {
+		version    string
+		confidence int
+		impliedBy  []string
+	}
+	check := func(t *testing.T, tech models.TechnologyAnalysis, want map[string]expectation) {
+		t.Helper()
+		for name, w := range want {
+			got := find(tech, name)
+			if got == nil {
+				t.Errorf("Expected %s to be detected, got %+v", name, tech.Technologies)
+				continue
+			}
+			if got.Version != w.version || got.Confidence != w.confidence || !slices.Equal(got.ImpliedBy, w.impliedBy) {
+				t.Errorf("%s: expected version %q, confidence %d, implied by %v, got %+v",
+					name, w.version, w.confidence, w.impliedBy, *got)
+			}
+		}
+	}
+
+	t.Run("WordPress", func(t *testing.T) {
+		tech := fetch(t, "/wordpress")
+		check(t, tech, map[string]expectation{
+			"WordPress": {version: "6.4.2", confidence: 100},
+			"jQuery":    {version: "3.7.1", confidence: 100},
+			"Nginx":     {version: "1.25.3", confidence: 100},
+			// Detected directly, so not listed as implied
+			"PHP":   {version: "8.2.7", confidence: 100},
+			"MySQL": {confidence: 100, impliedBy: []string{"WordPress"}},
+		})
+		if tech.CMS != "WordPress" || !slices.Equal(tech.Frameworks, []string{"jQuery"}) {
+			t.Errorf("Expected WordPress with jQuery, got %q with %v", tech.CMS, tech.Frameworks)
+		}
+	})
+
+	t.Run("NextJS", func(t *testing.T) {
+		tech := fetch(t, "/next")
+		check(t, tech, map[string]expectation{
+			"Next.js": {confidence: 100},
+			"React":   {confidence: 100, impliedBy: []string{"Next.js"}},
+			"Node.js": {confidence: 100, impliedBy: []string{"Next.js"}},
+		})
+		if tech.CMS != "Unknown" || !slices.Equal(tech.Frameworks, []string{"Next.js", "React"}) {
+			t.Errorf("Expected Next.js and React without a CMS, got %q with %v", tech.CMS, tech.Frameworks)
+		}
+	})
+
+	t.Run("NoFalsePositives", func(t *testing.T) {
+		tech := fetch(t, "/lookalikes")
+		if len(tech.Technologies) != 0 || len(tech.Frameworks) != 0 || tech.CMS != "Unknown" {
+			t.Errorf("Expected no technologies, got %+v", tech)
+		}
+	})
+
+	t.Run("GlobalVariables", func(t *testing.T) {
+		tech := fetch(t, "/globals")
+		check(t, tech, map[string]expectation{
+			"Vue.js":  {confidence: 100},
+			"Shopify": {confidence: 100},
+		})
+	})
+}
@@ -0,0 +1,151 @@
+package analyzer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"maps"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestTLSAnalysis tests the TLS connection and certificate analysis of deep analyses
+func TestTLSAnalysis(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Secure</p></body></html>`))
+	})
+	a := getTestAnalyzer()
+	ctx := context.Background()
+
+	checks := func(analysis models.TLSAnalysis) map[string]string {
+		verdicts := map[string]string{}
+		for _, check := range analysis.Checks {
+			verdicts[check.Name] = check.Verdict
+		}
+		return verdicts
+	}
+
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	t.Run("SelfSigned", func(t *testing.T) {
+		page, err := a.FetchPage(ctx, server.URL)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		tlsInfo := page.Security.TLS
+		if !tlsInfo.Enabled || tlsInfo.Version != "TLS 1.3" || tlsInfo.CipherSuite == "" || tlsInfo.ALPN == "" {
+			t.Errorf("Unexpected connection details: %+v", tlsInfo)
+		}
+		if !tlsInfo.SelfSigned || tlsInfo.Trusted || tlsInfo.VerificationError == "" || tlsInfo.HostnameMismatch || tlsInfo.Expired {
+			t.Errorf("Expected a trusted-nowhere self-signed certificate for the host, got %+v", tlsInfo)
+		}
+		if len(tlsInfo.Chain) != 1 {
+			t.Fatalf("Expected a chain of 1, got %d", len(tlsInfo.Chain))
+		}
+		leaf := tlsInfo.Chain[0]
+		if leaf.KeyType != "RSA" || leaf.KeySize < 1024 || leaf.Subject != leaf.Issuer || !leaf.IsCA {
+			t.Errorf("Unexpected certificate: %+v", leaf)
+		}
+		if !strings.Contains(strings.Join(leaf.SANs, ","), "127.0.0.1") || tlsInfo.DaysUntilExpiry < certDays(leaf.NotAfter)-1 {
+			t.Errorf("Unexpected SANs or expiry: %+v, %d days", leaf.SANs, tlsInfo.DaysUntilExpiry)
+		}
+		want := map[string]string{
+			"TLS protocol":           models.VerdictPass,
+			"TLS certificate":        models.VerdictFail,
+			"TLS hostname":           models.VerdictPass,
+			"TLS certificate expiry": models.VerdictPass,
+		}
+		if got := checks(tlsInfo); !maps.Equal(got, want) {
+			t.Errorf("Expected checks %v, got %v", want, got)
+		}
+	})
+
+	t.Run("HostnameMismatch", func(t *testing.T) {
+		_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+		page, err := a.FetchPage(ctx, "https://localhost:"+port)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		if !page.Security.TLS.HostnameMismatch || checks(page.Security.TLS)["TLS hostname"] != models.VerdictFail {
+			t.Errorf("Expected a hostname mismatch, got %+v", page.Security.TLS)
+		}
+	})
+
+	t.Run("ExpiringSoon", func(t *testing.T) {
+		expiring := httptest.NewUnstartedServer(handler)
+		expiring.TLS = &tls.Config{Certificates: []tls.Certificate{newTestCertificate(t, 10*24*time.Hour)}}
+		expiring.StartTLS()
+		defer expiring.Close()
+
+		page, err := a.FetchPage(ctx, expiring.URL)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		tlsInfo := page.Security.TLS
+		if tlsInfo.DaysUntilExpiry != 9 || tlsInfo.Expired {
+			t.Errorf("Expected 9 days until expiry, got %d", tlsInfo.DaysUntilExpiry)
+		}
+		if len(tlsInfo.Chain) != 1 || tlsInfo.Chain[0].KeyType != "ECDSA" || tlsInfo.Chain[0].KeySize != 256 {
+			t.Errorf("Expected an ECDSA P-256 certificate, got %+v", tlsInfo.Chain)
+		}
+		if checks(tlsInfo)["TLS certificate expiry"] != models.VerdictWarn {
+			t.Errorf("Expected an expiry warning, got %+v", tlsInfo.Checks)
+		}
+	})
+
+	t.Run("PlainHTTP", func(t *testing.T) {
+		plain := httptest.NewServer(handler)
+		defer plain.Close()
+
+		page, err := a.FetchPage(ctx, plain.URL)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		if page.Security.TLS.Enabled || len(page.Security.TLS.Checks) != 0 {
+			t.Errorf("Expected no TLS analysis for plain HTTP, got %+v", page.Security.TLS)
+		}
+	})
+}
+
+// newTestCertificate creates a self-signed ECDSA certificate for 127.0.0.1
+// that expires after validity
+func newTestCertificate(t *testing.T, validity time.Duration) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "expiring.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// certDays returns the whole days until a time
+func certDays(until time.Time) int {
+	return int(time.Until(until).Hours() / 24)
+}
@@ -0,0 +1,176 @@
+package analyzer_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"webPageAnalyzerGO/internal/analyzer"
+	"webPageAnalyzerGO/internal/config"
+	"webPageAnalyzerGO/internal/models"
+)
+
+// TestTrackerDetection tests matching trackers against the signature database
+func TestTrackerDetection(t *testing.T) {
+	pages := map[string]string{
+		"/tracked": `<html><head>
+			<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123"></script>
+			<script>window.dataLayer = []; gtag('config', 'G-ABC123');</script>
+			<script>!function(f,b,e,v){}(window, document,'script','https://connect.facebook.net/en_US/fbevents.js'); fbq('init', '123');</script>
+			<script src="https://static.hotjar.com/c/hotjar-1.js?sv=6"></script>
+			<script src="/js/internal.js"></script>
+		</head><body>
+			<noscript><img height="1" width="1" src="https://www.facebook.com/tr?id=123&ev=PageView&noscript=1"></noscript>
+			<iframe src="https://www.youtube.com/embed/xyz"></iframe>
+			<img src="/logo.png">
+		</body></html>`,
+		"/clean":  `<html><body><script src="/app.js"></script><img src="/logo.png"></body></html>`,
+		"/custom": `<html><head><script src="https://cdn.acme-metrics.test/m.js"></script></head><body></body></html>`,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tracked" {
+			http.SetCookie(w, &http.Cookie{Name: "_ga", Value: "GA1.1.1", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "_fbp", Value: "fb.1.1", Path: "/"})
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Path]))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	newAnalyzer := func(signatures string) (*analyzer.Analyzer, error) {
+		return analyzer.New(config.AnalyzerConfig{
+			RequestTimeout:    5 * time.Second,
+			UserAgent:         "WebPageAnalyzer-Test/1.0",
+			TrackerSignatures: signatures,
+		}, slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})))
+	}
+	fetch := func(t *testing.T, a *analyzer.Analyzer, url string) *analyzer.PageData {
+		t.Helper()
+		page, err := a.FetchPage(context.Background(), url)
+		if err != nil {
+			t.Fatalf("FetchPage failed: %v", err)
+		}
+		return page
+	}
+	evidence := func(match models.TrackerMatch) []string {
+		var kinds []string
+		for _, e := range match.Evidence {
+			kinds = append(kinds, e.Type)
+		}
+		return kinds
+	}
+
+	t.Run("BundledSignatures", func(t *testing.T) {
+		page := fetch(t, getTestAnalyzer(), server.URL+"/tracked")
+		want := map[string]struct {
+			category string
+			evidence []string
+		}{
+			"Google Analytics": {models.TrackerAnalytics, []string{models.EvidenceScript, models.EvidenceInline, models.EvidenceCookie}},
+			"Meta Pixel":       {models.TrackerAdvertising, []string{models.EvidenceInline, models.EvidencePixel, models.EvidenceCookie}},
+			"Hotjar":           {models.TrackerSessionReplay, []string{models.EvidenceScript}},
+			"YouTube":          {models.TrackerSocial, []string{models.EvidenceIframe}},
+		}
+
+		if len(page.Privacy.Trackers) != len(want) {
+			t.Fatalf("Expected %d trackers, got %+v", len(want), page.Privacy.Trackers)
+		}
+		for _, match := range page.Privacy.Trackers {
+			w, ok := want[match.Name]
+			if !ok {
+				t.Errorf("Unexpected tracker %q", match.Name)
+				continue
+			}
+			if match.Category != w.category || !slices.Equal(evidence(match), w.evidence) {
+				t.Errorf("%s: expected %s with %v, got %s with %v",
+					match.Name, w.category, w.evidence, match.Category, evidence(match))
+			}
+		}
+
+		categories := map[string]int{
+			models.TrackerAnalytics: 1, models.TrackerAdvertising: 1, models.TrackerSocial: 1, models.TrackerSessionReplay: 1,
+		}
+		for category, count := range categories {
+			if page.Privacy.Categories[category] != count {
+				t.Errorf("Expected %d %s trackers, got %d", count, category, page.Privacy.Categories[category])
+			}
+		}
+		if !slices.Equal(page.Technology.Advertising, []string{"Meta Pixel"}) {
+			t.Errorf("Expected Meta Pixel advertising, got %v", page.Technology.Advertising)
+		}
+	})
+
+	t.Run("NoTrackers", func(t *testing.T) {
+		page := fetch(t, getTestAnalyzer(), server.URL+"/clean")
+		if len(page.Privacy.Trackers) != 0 || page.Privacy.Categories[models.TrackerAnalytics] != 0 {
+			t.Errorf("Expected no trackers, got %+v", page.Privacy)
+		}
+	})
+
+	t.Run("UserSignatures", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trackers.json")
+		signatures := `{"trackers": [
+			{"name": "Acme Metrics", "vendor": "Acme", "category": "analytics", "domains": ["acme-metrics.test"]},
+			{"name": "Hotjar", "vendor": "Hotjar", "category": "session_replay", "domains": ["hotjar.invalid"]}
+		]}`
+		if err := os.WriteFile(path, []byte(signatures), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		a, err := newAnalyzer(path)
+		if err != nil {
+			t.Fatalf("Failed to load the user's signatures: %v", err)
+		}
+
+		custom := fetch(t, a, server.URL+"/custom")
+		if len(custom.Privacy.Trackers) != 1 || custom.Privacy.Trackers[0].Vendor != "Acme" {
+			t.Errorf("Expected the user's tracker, got %+v", custom.Privacy.Trackers)
+		}
+
+		// The user's Hotjar signature replaces the bundled one
+		tracked := fetch(t, a, server.URL+"/tracked")
+		if slices.ContainsFunc(tracked.Privacy.Trackers, func(m models.TrackerMatch) bool { return m.Name == "Hotjar" }) {
+			t.Error("Expected the bundled Hotjar signature to be replaced")
+		}
+		if !slices.ContainsFunc(tracked.Privacy.Trackers, func(m models.TrackerMatch) bool { return m.Name == "Google Analytics" }) {
+			t.Error("Expected the bundled signatures to remain")
+		}
+
+		// The rules version tells the user's signatures apart
+		if got := getTestAnalyzer().RulesVersion(); got != analyzer.RulesVersion {
+			t.Errorf("Expected rules version %q without user signatures, got %q", analyzer.RulesVersion, got)
+		}
+		version := a.RulesVersion()
+		if !strings.HasPrefix(version, analyzer.RulesVersion+"+") {
+			t.Errorf("Expected the rules version to include the user's signatures, got %q", version)
+		}
+		if err := os.WriteFile(path, []byte(`{"trackers": []}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if changed, err := newAnalyzer(path); err != nil || changed.RulesVersion() == version {
+			t.Errorf("Expected changed signatures to change the rules version, got %v", err)
+		}
+	})
+
+	t.Run("InvalidUserSignatures", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trackers.json")
+		if err := os.WriteFile(path, []byte(`{"trackers": [{"name": "Bad", "category": "spying"}]}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		// A broken file is a configuration error, not a silent fallback
+		if _, err := newAnalyzer(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("Expected an error naming %s, got %v", path, err)
+		}
+		if _, err := newAnalyzer(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+	})
+}